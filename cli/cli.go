@@ -3,25 +3,27 @@ package cli
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"raco/cli/cmd"
 	"raco/cli/version"
+	"raco/workspace"
 )
 
 func Run(args []string) int {
+	explicit, args := workspace.TakeFlag(args)
 	if len(args) == 0 {
 		printUsage()
 		return 1
 	}
 
-	homeDir, err := os.UserHomeDir()
+	ws, err := workspace.Discover(explicit)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
 	}
 
 	ctx := &cmd.Context{
-		StoragePath: filepath.Join(homeDir, ".raco"),
+		StoragePath: ws.Path,
+		Workspace:   ws,
 	}
 
 	command := args[0]
@@ -36,16 +38,30 @@ func Run(args []string) int {
 		return cmd.RunGRPC(ctx, subArgs)
 	case "collection", "col":
 		return cmd.RunCollection(ctx, subArgs)
+	case "req-saved":
+		return cmd.RunSavedRequest(ctx, subArgs)
 	case "env", "environment":
 		return cmd.RunEnvironment(ctx, subArgs)
+	case "workspace":
+		return cmd.RunWorkspace(ctx, subArgs)
+	case "secrets":
+		return cmd.RunSecrets(ctx, subArgs)
 	case "import":
 		return cmd.RunImport(ctx, subArgs)
 	case "curl":
 		return cmd.RunCurl(ctx, subArgs)
 	case "run":
 		return cmd.RunRunner(ctx, subArgs)
+	case "lint":
+		return cmd.RunLint(ctx, subArgs)
+	case "search":
+		return cmd.RunSearch(ctx, subArgs)
 	case "stats":
 		return cmd.RunStats(ctx, subArgs)
+	case "history":
+		return cmd.RunHistory(ctx, subArgs)
+	case "doctor":
+		return cmd.RunDoctor(ctx, subArgs)
 	case "update":
 		return cmd.RunUpdate()
 	case "help", "-h", "--help":
@@ -65,18 +81,25 @@ func Run(args []string) int {
 }
 
 func printUsage() {
-	fmt.Println(`Usage: raco <command> [options]
+	fmt.Println(`Usage: raco [--workspace <path>] <command> [options]
 
 Commands:
   request, req     Make HTTP request
   ws, websocket    Connect to WebSocket server
   grpc             Connect to gRPC server
   collection, col  Manage collections
+  req-saved        Show, edit, move, copy and remove saved requests
   env, environment Manage environments
+  secrets          Manage the key that encrypts environment secrets
+  workspace        Show or initialise the workspace (project .raco or ~/.raco)
   import           Import Postman collection
   curl             Parse/convert cURL commands
   run              Run collection with assertions
+  lint             Check collections and environments without running them
+  search           Search requests, environments and history
   stats            Show request statistics
+  history          List, search and replay past requests
+  doctor           Check stored files and upgrade old schema versions
   update           Update raco to latest release
   help             Show this help
   version          Show version
@@ -91,9 +114,14 @@ Examples:
   raco grpc -r localhost:50051
   raco grpc -r localhost:50051 -insecure
   raco col list
+  raco req-saved update my-collection "Get Users" -r https://api.example.org/v2/users
   raco env list
   raco import postman collection.json
   raco curl parse 'curl -X GET https://api.example.org'
   raco run my-collection -e production
-  raco stats`)
+  raco lint my-collection -e production
+  raco search orders method:POST host:api.example.org
+  raco stats
+  raco history search users --since 24h
+  raco doctor --migrate`)
 }
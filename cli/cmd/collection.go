@@ -2,12 +2,11 @@ package cmd
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
 	"raco/model"
 	"raco/storage"
-	"raco/util"
 	"strings"
 	"time"
 
@@ -32,7 +31,11 @@ func RunCollection(ctx *Context, args []string) int {
 	case "create", "new":
 		return collectionCreate(store, subArgs)
 	case "delete", "rm":
-		return collectionDelete(ctx.StoragePath, subArgs)
+		return collectionDelete(store, subArgs)
+	case "rename":
+		return collectionRename(store, subArgs)
+	case "migrate":
+		return collectionMigrate(store, subArgs)
 	case "add-request", "add":
 		return collectionAddRequest(ctx, store, subArgs)
 	default:
@@ -48,15 +51,23 @@ func printCollectionUsage() {
 Actions:
   list, ls              List all collections
   show, get <id>        Show collection details
-  create, new <name>    Create new collection
+  create, new <name>    Create new collection (--layout file|dir, --format yaml|json)
   delete, rm <id>       Delete collection
+  rename <id> <name>    Rename collection (--id <new-id> also changes its ID)
   add, add-request      Add request to collection
+  migrate <id>|--all    Convert between layouts (--layout file|dir, --format yaml|json)
 
 Examples:
   raco col list
   raco col create "My API Tests"
+  raco col create "Shared API" --layout dir
+  raco col migrate my-api-tests --layout dir --format yaml
   raco col show my-api-tests
-  raco col add my-api-tests -n "Get Users" -m GET -r https://api.example.org/users`)
+  raco col rename my-api-tests "API Smoke Tests" --id api-smoke-tests
+  raco col add my-api-tests -n "Get Users" -m GET -r https://api.example.org/users
+  raco col add my-api-tests/admin -n "List Admins" -m GET -r https://api.example.org/admins
+
+Edit, move, copy and remove single requests with raco req-saved.`)
 }
 
 func collectionList(store *storage.Storage) int {
@@ -72,7 +83,7 @@ func collectionList(store *storage.Storage) int {
 	}
 
 	for _, col := range collections {
-		fmt.Printf("%s  %s  (%d requests)\n", col.ID, col.Name, len(col.Requests))
+		fmt.Printf("%s  %s  (%d requests)\n", col.ID, col.Name, len(col.AllRequests()))
 	}
 
 	return 0
@@ -96,7 +107,7 @@ func collectionShow(store *storage.Storage, args []string) int {
 }
 
 func collectionCreate(store *storage.Storage, args []string) int {
-	if len(args) == 0 {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
 		fmt.Fprintln(os.Stderr, "Error: collection name is required")
 		return 1
 	}
@@ -104,13 +115,33 @@ func collectionCreate(store *storage.Storage, args []string) int {
 	name := args[0]
 	id := generateSlug(name)
 
+	fs := flag.NewFlagSet("col create", flag.ContinueOnError)
+	layoutName := fs.String("layout", "file", "Storage layout: file (one JSON file) or dir (one file per request)")
+	format := fs.String("format", "yaml", "File format of a dir layout: yaml or json")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	layout, err := storage.ParseCollectionLayout(*layoutName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if _, _, err := store.CollectionLayoutOf(id); err == nil {
+		fmt.Fprintf(os.Stderr, "Error: collection %s already exists\n", id)
+		return 1
+	}
+
 	col := &model.Collection{
 		ID:       id,
 		Name:     name,
 		Requests: []*model.Request{},
 	}
 
-	if err := store.SaveCollection(col); err != nil {
+	if err := store.CreateCollection(col, layout, *format); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
 	}
@@ -119,7 +150,7 @@ func collectionCreate(store *storage.Storage, args []string) int {
 	return 0
 }
 
-func collectionDelete(storagePath string, args []string) int {
+func collectionDelete(store *storage.Storage, args []string) int {
 	if len(args) == 0 {
 		fmt.Fprintln(os.Stderr, "Error: collection ID is required")
 		return 1
@@ -131,25 +162,99 @@ func collectionDelete(storagePath string, args []string) int {
 		return 1
 	}
 
-	path := filepath.Join(storagePath, "collections", id+".json")
+	if err := store.DeleteCollection(id); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
 
-	resolvedPath, err := filepath.EvalSymlinks(path)
-	if err != nil {
-		resolvedPath = path
+	fmt.Printf("Deleted collection: %s\n", id)
+	return 0
+}
+
+func collectionRename(store *storage.Storage, args []string) int {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		fmt.Fprintln(os.Stderr, "Usage: raco col rename <id> <new-name> [--id <new-id>]")
+		return 1
 	}
 
-	expectedDir := filepath.Join(storagePath, "collections")
-	if !util.IsPathContained(resolvedPath, expectedDir) {
-		fmt.Fprintln(os.Stderr, "Error: invalid path")
+	fs := flag.NewFlagSet("col rename", flag.ContinueOnError)
+	newID := fs.String("id", "", "New collection ID")
+	if err := fs.Parse(args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	id, name := args[0], args[1]
+	if *newID != "" && !isValidCollectionID(*newID) {
+		fmt.Fprintln(os.Stderr, "Error: invalid collection ID format")
 		return 1
 	}
 
-	if err := os.Remove(resolvedPath); err != nil {
+	if err := store.RenameCollection(id, name, *newID); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
 	}
 
-	fmt.Printf("Deleted collection: %s\n", id)
+	if *newID != "" && *newID != id {
+		fmt.Printf("Renamed collection: %s (%s -> %s)\n", name, id, *newID)
+		return 0
+	}
+	fmt.Printf("Renamed collection: %s (%s)\n", name, id)
+	return 0
+}
+
+// collectionMigrate converts collections between the single-file and the directory layout.
+func collectionMigrate(store *storage.Storage, args []string) int {
+	fs := flag.NewFlagSet("col migrate", flag.ContinueOnError)
+	layoutName := fs.String("layout", "", "Target layout: file or dir")
+	format := fs.String("format", "yaml", "File format of a dir layout: yaml or json")
+	all := fs.Bool("all", false, "Migrate every collection")
+
+	if err := fs.Parse(reorderArgs(args)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	layout, err := storage.ParseCollectionLayout(*layoutName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --layout: %v\n", err)
+		return 1
+	}
+	if _, err := storage.ParseCollectionFormat(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --format: %v\n", err)
+		return 1
+	}
+
+	ids := fs.Args()
+	if *all {
+		collections, err := store.ListCollections()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		ids = ids[:0]
+		for _, col := range collections {
+			ids = append(ids, col.ID)
+		}
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: raco col migrate <id>...|--all --layout file|dir [--format yaml|json]")
+		return 1
+	}
+
+	failed := 0
+	for _, id := range ids {
+		if err := store.MigrateCollection(id, layout, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", id, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Migrated collection: %s (%s)\n", id, layout)
+	}
+
+	if failed > 0 {
+		return 1
+	}
 	return 0
 }
 
@@ -178,17 +283,26 @@ func isValidCollectionID(id string) bool {
 
 func collectionAddRequest(ctx *Context, store *storage.Storage, args []string) int {
 	if len(args) < 5 {
-		fmt.Fprintln(os.Stderr, "Usage: raco col add <collection-id> -n <name> -m <method> -r <url> [-d body] [-H headers]")
+		fmt.Fprintln(os.Stderr, "Usage: raco col add <collection-id>[/<folder>...] -n <name> -m <method> -r <url> [-d body] [-H headers]")
 		return 1
 	}
 
-	colID := args[0]
+	colID, folderPath, _ := strings.Cut(args[0], "/")
 	col, err := store.LoadCollection(colID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading collection: %v\n", err)
 		return 1
 	}
 
+	var folder *model.Folder
+	if folderPath != "" {
+		folder, err = col.FindFolder(folderPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
 	var name string
 	requestArgs := make([]string, 0, len(args)-1)
 	for i := 1; i < len(args); i++ {
@@ -223,7 +337,12 @@ func collectionAddRequest(ctx *Context, store *storage.Storage, args []string) i
 		CollectionID:   colID,
 	}
 
-	col.Requests = append(col.Requests, req)
+	if err := storage.ValidateRequest(req); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	col.InsertRequest(req, folder, 0)
 
 	if err := store.SaveCollection(col); err != nil {
 		fmt.Fprintf(os.Stderr, "Error saving collection: %v\n", err)
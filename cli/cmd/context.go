@@ -1,9 +1,14 @@
 package cmd
 
-import "raco/storage"
+import (
+	"raco/storage"
+	"raco/workspace"
+)
 
 type Context struct {
 	StoragePath string
+	// Workspace describes where StoragePath came from.
+	Workspace workspace.Workspace
 }
 
 func (c *Context) Storage() *storage.Storage {
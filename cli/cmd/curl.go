@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"raco/model"
 	"raco/util"
 	"strings"
 )
@@ -68,12 +69,14 @@ func curlConvert(ctx *Context, args []string) int {
 		return 1
 	}
 
-	if reqIdx < 0 || reqIdx >= len(col.Requests) {
+	requests := col.AllRequests()
+	if reqIdx < 0 || reqIdx >= len(requests) {
 		fmt.Fprintf(os.Stderr, "Invalid request index: %d\n", reqIdx)
 		return 1
 	}
 
-	curlCmd := util.ToCurl(col.Requests[reqIdx])
+	req := requests[reqIdx]
+	curlCmd := util.ToCurl(col.ApplyDefaults(model.ApplyFolders(col.FolderPath(req), req)))
 	fmt.Println(curlCmd)
 	return 0
 }
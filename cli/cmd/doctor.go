@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"raco/storage"
+	"sort"
+)
+
+// Statuses of a file in raco doctor's report.
+const (
+	doctorOK       = "ok"
+	doctorOutdated = "outdated"
+	doctorUpgraded = "upgraded"
+	doctorTooNew   = "too new"
+	doctorError    = "error"
+)
+
+type doctorFile struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Version *int   `json:"schema_version,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+type doctorReport struct {
+	Workspace     string       `json:"workspace"`
+	SchemaVersion int          `json:"schema_version"`
+	Files         []doctorFile `json:"files"`
+	BackupDirs    []string     `json:"backup_dirs,omitempty"`
+}
+
+func RunDoctor(ctx *Context, args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	migrate := fs.Bool("migrate", false, "Upgrade every file stored in an older schema version")
+	outputFmt := fs.String("o", "text", "Output format: text, json")
+	fs.Usage = printDoctorUsage
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	store := ctx.Storage()
+	var files []storage.SchemaFile
+	var err error
+	if *migrate {
+		files, err = store.UpgradeSchema()
+	}
+	if !*migrate {
+		files, err = store.CheckSchema()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	report := doctorReport{Workspace: ctx.StoragePath, SchemaVersion: storage.SchemaVersion, Files: make([]doctorFile, 0, len(files))}
+	problems := 0
+	outdated := make(map[int]bool)
+	upgraded := make(map[int]bool)
+	for _, f := range files {
+		file := doctorFile{Kind: f.Kind, Name: f.Name, Status: doctorStatus(f)}
+		if f.Err != nil {
+			file.Error = f.Err.Error()
+		}
+		if f.Err == nil {
+			version := f.Version
+			file.Version = &version
+		}
+		switch file.Status {
+		case doctorOutdated, doctorTooNew, doctorError:
+			problems++
+		}
+		if file.Status == doctorOutdated || file.Status == doctorUpgraded {
+			outdated[f.Version] = true
+		}
+		if file.Status == doctorUpgraded {
+			upgraded[f.Version] = true
+		}
+		report.Files = append(report.Files, file)
+	}
+	versions := sortedVersions(outdated)
+	for _, v := range sortedVersions(upgraded) {
+		report.BackupDirs = append(report.BackupDirs, store.SchemaBackupDir(v))
+	}
+
+	if *outputFmt == "json" {
+		output, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(output))
+		if problems > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("Workspace:      %s\n", report.Workspace)
+	fmt.Printf("Schema version: %d\n", report.SchemaVersion)
+	if len(report.Files) == 0 {
+		fmt.Println("\nNo collections or environments found")
+		return 0
+	}
+
+	fmt.Println()
+	for _, f := range report.Files {
+		fmt.Printf("  %-11s  %-32s  %s\n", f.Kind, f.Name, doctorDetail(f))
+	}
+
+	for _, v := range versions {
+		fmt.Printf("\nChanges from version %d:\n", v)
+		for _, change := range storage.SchemaChanges(v) {
+			fmt.Printf("  - %s\n", change)
+		}
+	}
+
+	fmt.Println()
+	for _, dir := range report.BackupDirs {
+		fmt.Printf("Originals of upgraded files were copied to %s\n", dir)
+	}
+	if problems == 0 {
+		fmt.Println("No problems found")
+		return 0
+	}
+	fmt.Printf("%d file(s) need attention", problems)
+	if !*migrate && len(versions) > 0 {
+		fmt.Print("; run raco doctor --migrate to upgrade outdated files")
+	}
+	fmt.Println()
+	return 1
+}
+
+func sortedVersions(set map[int]bool) []int {
+	versions := make([]int, 0, len(set))
+	for v := range set {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+func doctorStatus(f storage.SchemaFile) string {
+	if errors.Is(f.Err, storage.ErrSchemaTooNew) {
+		return doctorTooNew
+	}
+	if f.Err != nil {
+		return doctorError
+	}
+	if f.Upgraded {
+		return doctorUpgraded
+	}
+	if f.Version < storage.SchemaVersion {
+		return doctorOutdated
+	}
+	return doctorOK
+}
+
+func doctorDetail(f doctorFile) string {
+	switch f.Status {
+	case doctorOK:
+		return fmt.Sprintf("v%d  ok", *f.Version)
+	case doctorOutdated:
+		return fmt.Sprintf("v%d  outdated", *f.Version)
+	case doctorUpgraded:
+		return fmt.Sprintf("v%d  upgraded to v%d", *f.Version, storage.SchemaVersion)
+	case doctorTooNew:
+		return "too new: " + f.Error + "; update raco"
+	}
+	return "error: " + f.Error
+}
+
+func printDoctorUsage() {
+	fmt.Println(`Usage: raco doctor [options]
+
+Checks that every collection and environment of the workspace can be read and reports files
+stored in an older schema version. raco upgrades such files when it loads them; --migrate
+upgrades all of them at once. The originals are copied to backups/schema-v<N> in the workspace.
+
+Options:
+  --migrate    Upgrade every file stored in an older schema version
+  -o <format>  Output format: text, json
+
+Exits with status 1 when a file is outdated (without --migrate), too new or cannot be read.
+
+Examples:
+  raco doctor
+  raco doctor --migrate`)
+}
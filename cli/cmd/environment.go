@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"raco/model"
+	"raco/secret"
 	"raco/storage"
 	"raco/util"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -32,9 +37,13 @@ func RunEnvironment(ctx *Context, args []string) int {
 	case "delete", "rm":
 		return environmentDelete(ctx.StoragePath, subArgs)
 	case "set":
-		return environmentSet(store, subArgs)
+		return environmentSet(store, ctx.StoragePath, subArgs)
 	case "unset":
 		return environmentUnset(store, subArgs)
+	case "import":
+		return environmentImport(store, ctx.StoragePath, subArgs)
+	case "export":
+		return environmentExport(store, subArgs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", action)
 		printEnvironmentUsage()
@@ -47,19 +56,28 @@ func printEnvironmentUsage() {
 
 Actions:
   list, ls              List all environments
-  show, get <name>      Show environment details
-  create, new <name>    Create new environment
+  show, get <name>      Show environment details (--resolved: merged view with sources)
+  create, new <name>    Create new environment (--extends <base> to inherit variables)
   delete, rm <name>     Delete environment
-  set <name> <key=val>  Set variable in environment
-  unset <name> <key>    Remove variable from environment
+  set <name> <key=val>  Set variable in environment (--secret before a pair: store it encrypted;
+                        --local: write the personal override file <name>.local.yaml)
+  unset <name> <key>    Remove variable or secret from environment
+  import <name>         Import variables (--dotenv <file|->, --os-env <prefix>, --secret <key>)
+  export <name>         Print merged variables (--format dotenv|json|yaml|shell); secrets are masked
 
 Examples:
   raco env list
   raco env create production
+  raco env create staging --extends base
   raco env set production API_URL=https://api.prod.example.org
-  raco env set production API_KEY=secret123
+  raco env set production --secret API_KEY=secret123
+  raco env set staging --local USER_TOKEN=abc
   raco env show production
-  raco env unset production API_KEY`)
+  raco env show staging --resolved
+  raco env unset production API_KEY
+  raco env import staging --dotenv .env --secret DB_PASSWORD
+  raco env import ci --os-env CI_
+  raco env export staging --format shell`)
 }
 
 func environmentList(storagePath string) int {
@@ -82,6 +100,9 @@ func environmentList(storagePath string) int {
 	for _, entry := range entries {
 		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".yaml") {
 			name := strings.TrimSuffix(entry.Name(), ".yaml")
+			if strings.HasSuffix(name, storage.LocalEnvironmentSuffix) {
+				continue
+			}
 			fmt.Println(name)
 		}
 	}
@@ -90,12 +111,27 @@ func environmentList(storagePath string) int {
 }
 
 func environmentShow(store *storage.Storage, args []string) int {
-	if len(args) == 0 {
+	name := ""
+	resolved := false
+	for _, arg := range args {
+		if arg == "--resolved" {
+			resolved = true
+			continue
+		}
+		if name == "" {
+			name = arg
+		}
+	}
+	if name == "" {
 		fmt.Fprintln(os.Stderr, "Error: environment name is required")
 		return 1
 	}
 
-	env, err := store.LoadEnvironment(args[0])
+	if resolved {
+		return environmentShowResolved(store, name)
+	}
+
+	env, err := store.LoadEnvironment(name)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
@@ -106,6 +142,40 @@ func environmentShow(store *storage.Storage, args []string) int {
 	return 0
 }
 
+// environmentShowResolved prints the merged variables of name with the environment each value
+// came from. Secrets and sensitive values are redacted.
+func environmentShowResolved(store *storage.Storage, name string) int {
+	env, sources, err := store.MergeEnvironment(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	keys := make([]string, 0, len(env.Variables))
+	width := 0
+	for k := range env.Variables {
+		keys = append(keys, k)
+		if len(k) > width {
+			width = len(k)
+		}
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("name: %s\n", env.Name)
+	if env.Extends != "" {
+		fmt.Printf("extends: %s\n", env.Extends)
+	}
+	fmt.Println("variables:")
+	for _, k := range keys {
+		value := env.Variables[k]
+		if env.IsSecret(k) || util.IsSensitiveKey(k) {
+			value = "[REDACTED]"
+		}
+		fmt.Printf("  %-*s = %s  (from %s)\n", width, k, value, sources[k])
+	}
+	return 0
+}
+
 func environmentCreate(store *storage.Storage, args []string) int {
 	if len(args) == 0 {
 		fmt.Fprintln(os.Stderr, "Error: environment name is required")
@@ -117,6 +187,9 @@ func environmentCreate(store *storage.Storage, args []string) int {
 		Name:      name,
 		Variables: make(map[string]string),
 	}
+	if len(args) >= 3 && args[1] == "--extends" {
+		env.Extends = args[2]
+	}
 
 	if err := store.SaveEnvironment(env); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -184,34 +257,123 @@ func isValidEnvName(name string) bool {
 	return true
 }
 
-func environmentSet(store *storage.Storage, args []string) int {
-	if len(args) < 2 {
-		fmt.Fprintln(os.Stderr, "Usage: raco env set <name> <key=value>")
+func environmentSet(store *storage.Storage, storagePath string, args []string) int {
+	// --secret applies to the key=value pair that follows it.
+	type assignment struct {
+		pair   string
+		secret bool
+	}
+	name := ""
+	assignments := make([]assignment, 0, len(args))
+	nextSecret := false
+	local := false
+	for _, arg := range args {
+		if arg == "--secret" {
+			nextSecret = true
+			continue
+		}
+		if arg == "--local" {
+			local = true
+			continue
+		}
+		if name == "" {
+			name = arg
+			continue
+		}
+		assignments = append(assignments, assignment{pair: arg, secret: nextSecret})
+		nextSecret = false
+	}
+	if name == "" || len(assignments) == 0 || nextSecret {
+		fmt.Fprintln(os.Stderr, "Usage: raco env set <name> [--local] [--secret] <key=value>...")
 		return 1
 	}
 
-	name := args[0]
 	env, err := store.LoadEnvironment(name)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if os.IsNotExist(err) && !local {
 			env = &model.Environment{
 				Name:      name,
 				Variables: make(map[string]string),
 			}
 		}
+		if os.IsNotExist(err) && local {
+			fmt.Fprintf(os.Stderr, "Error: environment %s does not exist; local overrides need it\n", name)
+			return 1
+		}
+		if env == nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if local {
+		env, err = store.LoadLocalEnvironment(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
 		if env == nil {
+			env = &model.Environment{}
+		}
+		env.Name = name
+	}
+	if env.Variables == nil {
+		env.Variables = make(map[string]string)
+	}
+
+	hasSecret := false
+	for _, a := range assignments {
+		hasSecret = hasSecret || a.secret
+	}
+
+	var box *secret.Box
+	if hasSecret {
+		var keySource string
+		var created bool
+		box, keySource, created, err = secret.OpenOrCreate(storagePath)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			return 1
 		}
+		if created {
+			fmt.Printf("Generated secret key: %s (keep it safe; without it secrets cannot be decrypted)\n", keySource)
+		}
+		if env.Secrets == nil {
+			env.Secrets = make(map[string]string)
+		}
 	}
 
-	for _, pair := range args[1:] {
-		parts := strings.SplitN(pair, "=", 2)
+	for _, a := range assignments {
+		parts := strings.SplitN(a.pair, "=", 2)
 		if len(parts) != 2 {
-			fmt.Fprintf(os.Stderr, "Invalid format: %s (use key=value)\n", pair)
+			fmt.Fprintf(os.Stderr, "Invalid format: %s (use key=value)\n", a.pair)
 			return 1
 		}
-		env.Variables[parts[0]] = parts[1]
+		if a.secret {
+			sealed, err := box.Seal(parts[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			env.Secrets[parts[0]] = sealed
+			delete(env.Variables, parts[0])
+		}
+		if !a.secret {
+			env.Variables[parts[0]] = parts[1]
+			delete(env.Secrets, parts[0])
+		}
+	}
+
+	if local {
+		ignored, err := store.SaveLocalEnvironment(env)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if ignored {
+			fmt.Printf("Added *%s.yaml to environments/.gitignore so local overrides stay untracked\n", storage.LocalEnvironmentSuffix)
+		}
+		fmt.Printf("Updated local overrides: %s%s\n", name, storage.LocalEnvironmentSuffix)
+		return 0
 	}
 
 	if err := store.SaveEnvironment(env); err != nil {
@@ -238,6 +400,7 @@ func environmentUnset(store *storage.Storage, args []string) int {
 
 	for _, key := range args[1:] {
 		delete(env.Variables, key)
+		delete(env.Secrets, key)
 	}
 
 	if err := store.SaveEnvironment(env); err != nil {
@@ -248,3 +411,204 @@ func environmentUnset(store *storage.Storage, args []string) int {
 	fmt.Printf("Updated environment: %s\n", name)
 	return 0
 }
+
+// environmentImport adds the variables of a .env file, or references to process variables with a
+// prefix, to name. Keys passed with --secret are stored encrypted.
+func environmentImport(store *storage.Storage, storagePath string, args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "Usage: raco env import <name> [--dotenv <file|->] [--os-env <prefix>] [--secret <key>]...")
+		return 1
+	}
+	name := args[0]
+
+	fs := flag.NewFlagSet("env import", flag.ContinueOnError)
+	dotenvPath := fs.String("dotenv", "", ".env file to import, - for stdin")
+	osPrefix := fs.String("os-env", "", "Reference process variables starting with this prefix as ${env:NAME}")
+	var secretKeys stringList
+	fs.Var(&secretKeys, "secret", "Store this imported key encrypted (repeatable)")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *dotenvPath == "" && *osPrefix == "" {
+		fmt.Fprintln(os.Stderr, "Error: --dotenv or --os-env is required")
+		return 1
+	}
+
+	var imported []storage.DotenvVariable
+	if *dotenvPath != "" {
+		var data []byte
+		var err error
+		if *dotenvPath == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		}
+		if *dotenvPath != "-" {
+			data, err = os.ReadFile(*dotenvPath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		vars, err := storage.ParseDotenv(string(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", *dotenvPath, err)
+			return 1
+		}
+		imported = append(imported, vars...)
+	}
+	if *osPrefix != "" {
+		// Only a reference is stored; the value is read from the process when a request uses it.
+		names := make([]string, 0)
+		for _, entry := range os.Environ() {
+			key, _, _ := strings.Cut(entry, "=")
+			if strings.HasPrefix(key, *osPrefix) {
+				names = append(names, key)
+			}
+		}
+		sort.Strings(names)
+		for _, key := range names {
+			imported = append(imported, storage.DotenvVariable{Key: key, Value: storage.EnvironmentReference(key)})
+		}
+	}
+
+	importedKeys := make(map[string]bool, len(imported))
+	for _, v := range imported {
+		importedKeys[v.Key] = true
+	}
+	isSecret := make(map[string]bool, len(secretKeys))
+	for _, key := range secretKeys {
+		if !importedKeys[key] {
+			fmt.Fprintf(os.Stderr, "Error: --secret %s: no such variable was imported\n", key)
+			return 1
+		}
+		isSecret[key] = true
+	}
+
+	env, err := store.LoadEnvironment(name)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		env = &model.Environment{Name: name}
+	}
+	if env.Variables == nil {
+		env.Variables = make(map[string]string)
+	}
+
+	var box *secret.Box
+	if len(secretKeys) > 0 {
+		var keySource string
+		var created bool
+		box, keySource, created, err = secret.OpenOrCreate(storagePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if created {
+			fmt.Printf("Generated secret key: %s (keep it safe; without it secrets cannot be decrypted)\n", keySource)
+		}
+		if env.Secrets == nil {
+			env.Secrets = make(map[string]string)
+		}
+	}
+
+	for _, v := range imported {
+		if isSecret[v.Key] {
+			sealed, err := box.Seal(v.Value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			env.Secrets[v.Key] = sealed
+			delete(env.Variables, v.Key)
+		}
+		if !isSecret[v.Key] {
+			env.Variables[v.Key] = v.Value
+			delete(env.Secrets, v.Key)
+		}
+	}
+
+	if err := store.SaveEnvironment(env); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Imported %d variable(s) into environment: %s\n", len(imported), name)
+	return 0
+}
+
+// environmentExport prints the merged variables of name. Secrets are never decrypted for export;
+// their values are masked.
+func environmentExport(store *storage.Storage, args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "Usage: raco env export <name> [--format dotenv|json|yaml|shell]")
+		return 1
+	}
+	name := args[0]
+
+	fs := flag.NewFlagSet("env export", flag.ContinueOnError)
+	format := fs.String("format", "dotenv", "Output format: dotenv, json, yaml, shell")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	env, _, err := store.MergeEnvironment(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	keys := make([]string, 0, len(env.Variables))
+	for k := range env.Variables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	masked := 0
+	values := make(map[string]string, len(keys))
+	vars := make([]storage.DotenvVariable, 0, len(keys))
+	for _, k := range keys {
+		value := env.Variables[k]
+		if env.IsSecret(k) {
+			value = "[REDACTED]"
+			masked++
+		}
+		values[k] = value
+		vars = append(vars, storage.DotenvVariable{Key: k, Value: value})
+	}
+
+	switch *format {
+	case "dotenv", "env":
+		err = storage.WriteDotenv(os.Stdout, vars)
+	case "shell", "sh":
+		err = storage.WriteShellExports(os.Stdout, vars)
+	case "json":
+		var data []byte
+		data, err = json.MarshalIndent(values, "", "  ")
+		if err == nil {
+			fmt.Println(string(data))
+		}
+	case "yaml", "yml":
+		var data []byte
+		data, err = yaml.Marshal(values)
+		if err == nil {
+			fmt.Print(string(data))
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (use dotenv, json, yaml or shell)\n", *format)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if masked > 0 {
+		fmt.Fprintf(os.Stderr, "%d secret(s) masked\n", masked)
+	}
+	return 0
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"raco/http"
+	"raco/model"
 	"raco/protocol"
 	"syscall"
 	"time"
@@ -16,6 +18,7 @@ func RunGRPC(ctx *Context, args []string) int {
 	fs := flag.NewFlagSet("grpc", flag.ContinueOnError)
 	address := fs.String("r", "", "gRPC server address (host:port)")
 	insecure := fs.Bool("insecure", false, "Use insecure connection (no TLS)")
+	envName := fs.String("e", "", "Environment name")
 
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -28,20 +31,33 @@ func RunGRPC(ctx *Context, args []string) int {
 		return 1
 	}
 
-	client := protocol.NewGRPCClient(*address)
+	env, err := loadStreamEnvironment(ctx, *envName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	target := http.ReplaceEnvVars(*address, env)
+	client := protocol.NewGRPCClient(target)
 	if setInsecure, ok := client.(interface{ SetInsecure(bool) }); ok {
 		setInsecure.SetInsecure(*insecure)
 	}
 	connCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	entry := model.NewHistoryEntry("GRPC", target, nil, "", "GRPC")
+	entry.Source = "cli"
+	entry.Environment = *envName
 	if err := client.Connect(connCtx); err != nil {
+		entry.Error = err.Error()
+		recordHistory(ctx, entry)
 		fmt.Fprintf(os.Stderr, "Connection failed: %v\n", err)
 		return 1
 	}
 	defer client.Close()
+	recordHistory(ctx, entry)
 
-	fmt.Printf("Connected to gRPC server at %s\n", *address)
+	fmt.Printf("Connected to gRPC server at %s\n", target)
 	fmt.Println("Send JSON envelope: {\"service\":\"pkg.Service\",\"method\":\"Method\",\"payload\":{...},\"metadata\":{...}}")
 	fmt.Println("Ctrl+C to exit.")
 
@@ -91,7 +107,7 @@ func RunGRPC(ctx *Context, args []string) int {
 		case <-connCtx.Done():
 			return 1
 		case text := <-inputCh:
-			if err := client.Send(text); err != nil {
+			if err := client.Send(http.ReplaceEnvVars(text, env)); err != nil {
 				fmt.Fprintf(os.Stderr, "Send error: %v\n", err)
 			}
 		}
@@ -104,6 +120,7 @@ func printGRPCUsage() {
 Options:
   -r <address>   gRPC server address (host:port) (required)
   -insecure     Use insecure connection (no TLS, for localhost)
+  -e <name>      Environment name ({{var}} and {{$fn}} placeholders are resolved)
 
 Send (stdin) JSON envelope per line, e.g.:
   {"service":"grpc.health.v1.Health","method":"Check","payload":{},"metadata":{}}
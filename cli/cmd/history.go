@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"raco/http"
+	"raco/model"
+	"raco/storage"
+	"strings"
+	"time"
+)
+
+func RunHistory(ctx *Context, args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help" {
+		return historyList(ctx, args, "")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return historyList(ctx, args[1:], "")
+	case "search":
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			fmt.Fprintln(os.Stderr, "Usage: raco history search <text> [filters]")
+			return 1
+		}
+		return historyList(ctx, args[2:], args[1])
+	case "show":
+		return historyShow(ctx, args[1:])
+	case "replay":
+		return historyReplay(ctx, args[1:])
+	case "clear":
+		return historyClear(ctx)
+	case "help", "-h", "--help":
+		printHistoryUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", args[0])
+		printHistoryUsage()
+		return 1
+	}
+}
+
+func printHistoryUsage() {
+	fmt.Println(`Usage: raco history <action> [options]
+
+Actions:
+  list, ls [filters]        List recent requests, newest first (default)
+  search <text> [filters]   List requests whose URL, headers, body or error contain text
+  show <id>                 Show one entry (an ID prefix is enough)
+  replay <id> [-o format]   Send a recorded HTTP request again
+  clear                     Delete the history
+
+Filters:
+  -m <method>       HTTP method, or WS / GRPC
+  --host <text>     Part of the host name
+  --status <code>   Status code (200), class (4xx) or "error" for failed requests
+  --since <time>    Duration ago (24h, 7d), date (2026-10-01) or RFC 3339 timestamp
+  --until <time>    Same formats as --since
+  -n <count>        Number of entries to show (default 20, 0 for all)
+  -o <format>       Output format: text, json
+
+Requests from the TUI and from raco req, ws and grpc are recorded in the workspace's history
+directory. Sensitive headers, query parameters and JSON fields and environment secrets are
+redacted before they are written.
+
+Examples:
+  raco history
+  raco history list -m POST --status 5xx --since 24h
+  raco history search users --host example.org
+  raco history replay 3f2a`)
+}
+
+func historyList(ctx *Context, args []string, text string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	method := fs.String("m", "", "HTTP method, or WS / GRPC")
+	host := fs.String("host", "", "Part of the host name")
+	status := fs.String("status", "", "Status code, class (4xx) or error")
+	since := fs.String("since", "", "Only entries after this time")
+	until := fs.String("until", "", "Only entries before this time")
+	limit := fs.Int("n", 20, "Number of entries (0 for all)")
+	outputFmt := fs.String("o", "text", "Output format: text, json")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	filter := storage.HistoryFilter{Host: *host, Status: strings.ToLower(*status), Text: text}
+	switch upper := strings.ToUpper(*method); upper {
+	case "WS", "GRPC":
+		filter.Protocol = upper
+	default:
+		filter.Method = upper
+	}
+	if err := filter.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	now := time.Now()
+	var err error
+	if *since != "" {
+		if filter.Since, err = storage.ParseHistoryTime(*since, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --since: %v\n", err)
+			return 1
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = storage.ParseHistoryTime(*until, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --until: %v\n", err)
+			return 1
+		}
+	}
+
+	entries, err := ctx.Storage().LoadHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	matched := make([]*model.HistoryEntry, 0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !filter.Match(entries[i]) {
+			continue
+		}
+		matched = append(matched, entries[i])
+		if *limit > 0 && len(matched) == *limit {
+			break
+		}
+	}
+
+	if *outputFmt == "json" {
+		data, _ := json.MarshalIndent(matched, "", "  ")
+		fmt.Println(string(data))
+		return 0
+	}
+
+	if len(matched) == 0 {
+		fmt.Println("No history entries found")
+		return 0
+	}
+	for _, e := range matched {
+		fmt.Println(historyLine(e))
+	}
+	return 0
+}
+
+// historyLine is the one-line summary of e used by list and search.
+func historyLine(e *model.HistoryEntry) string {
+	id := e.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	method := e.Method
+	if e.Protocol == "WS" || e.Protocol == "GRPC" {
+		method = e.Protocol
+	}
+
+	status := "  -"
+	if e.StatusCode > 0 {
+		status = fmt.Sprintf("%3d", e.StatusCode)
+	}
+	if e.StatusCode == 0 && e.Error != "" {
+		status = "ERR"
+	}
+
+	duration := ""
+	if e.DurationMs > 0 {
+		duration = fmt.Sprintf("%dms", e.DurationMs)
+	}
+
+	return fmt.Sprintf("%s  %s  %-6s %s %7s  %s", id, e.Timestamp.Local().Format("2006-01-02 15:04:05"), method, status, duration, e.URL)
+}
+
+func historyShow(ctx *Context, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: raco history show <id>")
+		return 1
+	}
+
+	entry, err := findHistoryEntry(ctx, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	data, _ := json.MarshalIndent(entry, "", "  ")
+	fmt.Println(string(data))
+	return 0
+}
+
+// historyReplay sends a recorded HTTP request again. Values that were redacted when it was
+// recorded cannot be restored; those headers and query parameters are left out and reported,
+// and a URL redacted anywhere else is not sent at all. Placeholders left in the entry, as typed in
+// the TUI, are resolved with the environment it was sent with.
+func historyReplay(ctx *Context, args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "Usage: raco history replay <id> [-o body|json|full]")
+		return 1
+	}
+
+	fs := flag.NewFlagSet("history replay", flag.ContinueOnError)
+	outputFmt := fs.String("o", "body", "Output format: body, json, full")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	entry, err := findHistoryEntry(ctx, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if entry.Protocol != "" && entry.Protocol != "HTTP" {
+		fmt.Fprintf(os.Stderr, "Error: only HTTP requests can be replayed (this is %s; reconnect with raco %s)\n", entry.Protocol, strings.ToLower(entry.Protocol))
+		return 1
+	}
+
+	url, dropped := replayURL(entry.URL)
+	if strings.Contains(url, "[REDACTED]") || strings.Contains(url, "%5BREDACTED%5D") {
+		fmt.Fprintln(os.Stderr, "Error: the recorded URL was redacted outside its query parameters and cannot be replayed")
+		return 1
+	}
+	for _, name := range dropped {
+		fmt.Fprintf(os.Stderr, "Warning: query parameter %s was redacted when recorded and is not sent\n", name)
+	}
+
+	req := &model.Request{
+		Method:  entry.Method,
+		URL:     url,
+		Headers: make(map[string]string),
+		Body:    entry.Body,
+		Files:   entry.Files,
+	}
+	for k, v := range entry.Headers {
+		if strings.Contains(v, "[REDACTED]") {
+			fmt.Fprintf(os.Stderr, "Warning: header %s was redacted when recorded and is not sent\n", k)
+			continue
+		}
+		req.Headers[k] = v
+	}
+	if strings.Contains(entry.Body, "[REDACTED") || strings.Contains(entry.Body, "... [truncated, ") {
+		fmt.Fprintln(os.Stderr, "Warning: the recorded body was redacted or truncated and may differ from the original")
+	}
+
+	envName := entry.Environment
+	if envName != "" {
+		if _, err := ctx.Storage().LoadEnvironment(envName); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: environment %s cannot be loaded (%v); replaying without it\n", envName, err)
+			envName = ""
+		}
+	}
+	return sendRequest(ctx, req, envName, true, *outputFmt)
+}
+
+// replayURL removes the query parameters of a recorded URL whose values were redacted. It
+// returns the URL to send and the names of the parameters it removed.
+func replayURL(rawURL string) (string, []string) {
+	base, query, ok := strings.Cut(rawURL, "?")
+	if !ok {
+		return rawURL, nil
+	}
+	fragment := ""
+	if i := strings.Index(query, "#"); i >= 0 {
+		query, fragment = query[:i], query[i:]
+	}
+
+	var dropped []string
+	kept := make([]string, 0)
+	for _, part := range strings.Split(query, "&") {
+		key, value, _ := strings.Cut(part, "=")
+		if strings.Contains(value, "[REDACTED]") || strings.Contains(value, "%5BREDACTED%5D") {
+			dropped = append(dropped, key)
+			continue
+		}
+		kept = append(kept, part)
+	}
+	if len(kept) == 0 {
+		return base + fragment, dropped
+	}
+	return base + "?" + strings.Join(kept, "&") + fragment, dropped
+}
+
+func findHistoryEntry(ctx *Context, id string) (*model.HistoryEntry, error) {
+	entries, err := ctx.Storage().LoadHistory()
+	if err != nil {
+		return nil, err
+	}
+	return storage.FindHistory(entries, id)
+}
+
+func historyClear(ctx *Context) int {
+	if err := ctx.Storage().ClearHistory(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println("History cleared")
+	return 0
+}
+
+// historyEntry describes req, as sent, for the history.
+func historyEntry(req *model.Request, protocol string, envName string) *model.HistoryEntry {
+	url, err := http.FullURL(req)
+	if err != nil {
+		url = req.URL
+	}
+	entry := model.NewHistoryEntry(req.Method, url, req.Headers, req.Body, protocol, req.Files)
+	entry.Source = "cli"
+	entry.Environment = envName
+	return entry
+}
+
+// recordHistory appends entry to the workspace history. Failing to record never fails the
+// request, so errors are only reported.
+func recordHistory(ctx *Context, entry *model.HistoryEntry) {
+	if err := ctx.Storage().AppendHistory(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record history: %v\n", err)
+	}
+}
@@ -62,6 +62,6 @@ func importPostman(ctx *Context, filePath string) int {
 		return 1
 	}
 
-	fmt.Printf("Imported collection: %s (%d requests)\n", collection.Name, len(collection.Requests))
+	fmt.Printf("Imported collection: %s (%d requests)\n", collection.Name, len(collection.AllRequests()))
 	return 0
 }
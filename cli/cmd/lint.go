@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"raco/cli/runner"
+	"raco/cli/version"
+	"raco/lint"
+	"sort"
+	"strings"
+)
+
+func RunLint(ctx *Context, args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	env := fs.String("e", "", "Environment the collections run with (default: every environment)")
+	outputFmt := fs.String("o", "text", "Output format: text, json, sarif")
+	dataFile := fs.String("data", "", "CSV or JSON data file whose columns are defined variables")
+	strict := fs.Bool("strict", false, "Exit with status 1 on warnings too")
+	fs.Usage = printLintUsage
+
+	if err := fs.Parse(reorderArgs(args)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *outputFmt != "text" && *outputFmt != "json" && *outputFmt != "sarif" {
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q (use text, json or sarif)\n", *outputFmt)
+		return 1
+	}
+	if fs.NArg() > 1 {
+		printLintUsage()
+		return 1
+	}
+
+	opts := lint.Options{Collection: fs.Arg(0), Environment: *env}
+	if *dataFile != "" {
+		rows, err := runner.LoadData(*dataFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading data file: %v\n", err)
+			return 1
+		}
+		opts.DataColumns = dataColumns(rows)
+	}
+
+	report, err := lint.Workspace(ctx.Storage(), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	status := 0
+	if report.Failed(*strict) {
+		status = 1
+	}
+
+	switch *outputFmt {
+	case "json":
+		output, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(output))
+		return status
+	case "sarif":
+		root, err := os.Getwd()
+		if err != nil {
+			root = ctx.StoragePath
+		}
+		if err := report.WriteSARIF(os.Stdout, ctx.StoragePath, root, version.Version); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return status
+	}
+
+	printLintText(report)
+	return status
+}
+
+// dataColumns returns every column that appears in a row of a data file.
+func dataColumns(rows []map[string]string) []string {
+	seen := make(map[string]bool)
+	columns := make([]string, 0)
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// printLintText groups findings by file, errors before warnings within each file.
+func printLintText(report *lint.Report) {
+	files := make([]string, 0)
+	byFile := make(map[string][]lint.Finding)
+	for _, f := range report.Findings {
+		if _, ok := byFile[f.File]; !ok {
+			files = append(files, f.File)
+		}
+		byFile[f.File] = append(byFile[f.File], f)
+	}
+
+	for _, file := range files {
+		findings := byFile[file]
+		sort.SliceStable(findings, func(i, j int) bool {
+			return findings[i].Severity == lint.SeverityError && findings[j].Severity != lint.SeverityError
+		})
+		fmt.Println(filepath.ToSlash(file))
+		for _, f := range findings {
+			location := f.Request
+			if f.Field != "" {
+				location = strings.TrimPrefix(location+" "+f.Field, " ")
+			}
+			if location != "" {
+				location += ": "
+			}
+			fmt.Printf("  %-7s  %s%s [%s]\n", f.Severity, location, f.Message, f.Rule)
+		}
+		fmt.Println()
+	}
+
+	summary := fmt.Sprintf("%d collection(s), %d environment(s): ", len(report.Collections), len(report.Environments))
+	if report.Errors == 0 && report.Warnings == 0 {
+		fmt.Println(summary + "no problems found")
+		return
+	}
+	fmt.Printf("%s%d error(s), %d warning(s)\n", summary, report.Errors, report.Warnings)
+}
+
+func printLintUsage() {
+	fmt.Println(`Usage: raco lint [collection] [options]
+
+Checks collections and environments without sending requests: HTTP methods, URLs, assertions,
+extractors, regular expressions, XPath and CSS selectors, scripts, run_if/skip_if, depends_on,
+file uploads, duplicate requests, unencrypted secrets and {{variables}} no scope defines.
+Without a collection every collection of the workspace is checked.
+
+Options:
+  -e <env>        Environment the collections run with (default: every environment)
+  -o <format>     Output format: text, json, sarif
+  --data <file>   CSV or JSON data file whose columns are defined variables
+  --strict        Exit with status 1 on warnings too
+
+Exits with status 1 when an error is found, and with --strict also on warnings.
+
+Examples:
+  raco lint
+  raco lint my-api -e staging
+  raco lint -o sarif > raco.sarif`)
+}
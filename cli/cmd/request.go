@@ -6,6 +6,7 @@ import (
 	"os"
 	"raco/cli/output"
 	"raco/http"
+	"raco/metrics"
 	"raco/model"
 	"raco/util/osnotify"
 	"strings"
@@ -21,6 +22,7 @@ type requestConfig struct {
 	TimeoutSeconds int
 	Output         string
 	Environment    string
+	Lenient        bool
 }
 
 func RunRequest(ctx *Context, args []string) int {
@@ -43,19 +45,44 @@ func RunRequest(ctx *Context, args []string) int {
 		TimeoutSeconds: cfg.TimeoutSeconds,
 	}
 
-	if cfg.Environment != "" {
-		env, err := ctx.Storage().LoadEnvironment(cfg.Environment)
-		if err == nil {
-			req.URL = http.ReplaceEnvVars(req.URL, env)
-			req.Body = http.ReplaceEnvVars(req.Body, env)
-			for k, v := range req.Headers {
-				req.Headers[k] = http.ReplaceEnvVars(v, env)
-			}
+	return sendRequest(ctx, req, cfg.Environment, cfg.Lenient, cfg.Output)
+}
+
+// sendRequest resolves req against the environment envName, executes it, records it in the
+// history and metrics and prints the response in the given output format.
+func sendRequest(ctx *Context, req *model.Request, envName string, lenient bool, outputFmt string) int {
+	var env *model.Environment
+	if envName != "" {
+		loaded, _, err := ctx.Storage().ResolveEnvironment(envName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading environment: %v\n", err)
+			return 1
+		}
+		env = loaded
+	}
+	req, err := http.ResolveRequest(req, env)
+	if err != nil {
+		if !lenient {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
 		}
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
 
 	client := http.NewClient()
 	resp, err := client.Execute(req)
+
+	entry := historyEntry(req, "HTTP", envName)
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	entry.SetResponse(resp)
+	recordHistory(ctx, entry)
+
+	metric := metrics.NewMetric("HTTP", req.Method, entry.URL, resp, entry.Error)
+	metric.Source = "req"
+	recordMetric(ctx, metric)
+
 	if err != nil {
 		osnotify.Send("Raco", "Request failed: "+err.Error())
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -63,7 +90,7 @@ func RunRequest(ctx *Context, args []string) int {
 	}
 
 	osnotify.Send("Raco", fmt.Sprintf("Request completed: %d", resp.StatusCode))
-	return output.PrintResponse(resp, cfg.Output)
+	return output.PrintResponse(resp, outputFmt)
 }
 
 func parseRequestArgs(args []string) (*requestConfig, error) {
@@ -78,6 +105,7 @@ func parseRequestArgs(args []string) (*requestConfig, error) {
 	file := fs.String("f", "", "File upload (format: field_name:file_path)")
 	outputFmt := fs.String("o", "body", "Output format: body, json, full")
 	env := fs.String("e", "", "Environment name")
+	lenient := fs.Bool("lenient", false, "Warn about unresolved variables instead of failing")
 
 	if err := fs.Parse(args); err != nil {
 		return nil, err
@@ -97,27 +125,11 @@ func parseRequestArgs(args []string) (*requestConfig, error) {
 		TimeoutSeconds: *timeout,
 		Output:         *outputFmt,
 		Environment:    *env,
+		Lenient:        *lenient,
 	}
 
-	if *query != "" {
-		pairs := strings.Split(*query, ";")
-		for _, pair := range pairs {
-			parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
-			if len(parts) == 2 {
-				cfg.Query[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-			}
-		}
-	}
-
-	if *headers != "" {
-		pairs := strings.Split(*headers, ";")
-		for _, pair := range pairs {
-			parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
-			if len(parts) == 2 {
-				cfg.Headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-			}
-		}
-	}
+	parsePairs(cfg.Query, *query, "=")
+	parsePairs(cfg.Headers, *headers, ":")
 
 	if *file != "" {
 		fileParts := strings.SplitN(*file, ":", 2)
@@ -136,6 +148,19 @@ func parseRequestArgs(args []string) (*requestConfig, error) {
 	return cfg, nil
 }
 
+// parsePairs adds the ";"-separated key<sep>value pairs of s to dst, as -q and -H take them.
+func parsePairs(dst map[string]string, s string, sep string) {
+	if s == "" {
+		return
+	}
+	for _, pair := range strings.Split(s, ";") {
+		parts := strings.SplitN(strings.TrimSpace(pair), sep, 2)
+		if len(parts) == 2 {
+			dst[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+}
+
 func ParseRequestArgsPublic(args []string) (method, url, body string, headers, query map[string]string, timeoutSeconds int, err error) {
 	cfg, err := parseRequestArgs(args)
 	if err != nil {
@@ -157,6 +182,7 @@ Options:
   -f <file>     File upload (field_name:path)
   -o <format>   Output: body, json, full
   -e <name>     Environment name
+  --lenient     Warn about unresolved {{variables}} instead of failing
 
 Examples:
   raco req -m GET -r https://api.example.org
@@ -5,14 +5,27 @@ import (
 	"fmt"
 	"os"
 	"raco/cli/runner"
+	"raco/metrics"
+	"raco/model"
+	"raco/secret"
 	"raco/util/osnotify"
+	"strings"
 )
 
+const maxParallel = 64
+
 func RunRunner(ctx *Context, args []string) int {
 	fs := flag.NewFlagSet("run", flag.ContinueOnError)
 	env := fs.String("e", "", "Environment name")
-	outputFmt := fs.String("o", "text", "Output format: text, json")
+	outputFmt := fs.String("o", "text", "Output format: "+strings.Join(runner.ReporterNames(), ", "))
 	stopOnFail := fs.Bool("stop-on-fail", false, "Stop on first failure")
+	lenient := fs.Bool("lenient", false, "Warn about unresolved variables instead of failing")
+	persist := fs.Bool("persist", false, "Save run-scoped variables back to the environment")
+	dataFile := fs.String("data", "", "CSV or JSON data file, one iteration per row")
+	iterations := fs.Int("iterations", 0, "Number of iterations")
+	parallel := fs.Int("parallel", 1, "Maximum number of requests in flight")
+	var reporterSpecs stringList
+	fs.Var(&reporterSpecs, "reporter", "Reporter as name or name:file (repeatable)")
 
 	reorderedArgs := reorderArgs(args)
 
@@ -27,7 +40,8 @@ func RunRunner(ctx *Context, args []string) int {
 		return 1
 	}
 
-	colID := remaining[0]
+	// "<collection>/<folder>/..." runs only the requests below that folder.
+	colID, folderPath, _ := strings.Cut(remaining[0], "/")
 
 	store := ctx.Storage()
 	col, err := store.LoadCollection(colID)
@@ -36,14 +50,62 @@ func RunRunner(ctx *Context, args []string) int {
 		return 1
 	}
 
+	if *persist && *env == "" {
+		fmt.Fprintln(os.Stderr, "Error: --persist requires an environment (-e)")
+		return 1
+	}
+
+	if *iterations < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --iterations cannot be negative")
+		return 1
+	}
+
+	if *parallel < 1 || *parallel > maxParallel {
+		fmt.Fprintf(os.Stderr, "Error: --parallel must be between 1 and %d\n", maxParallel)
+		return 1
+	}
+
+	targets := make([]runner.ReportTarget, 0, len(reporterSpecs)+1)
+	for _, spec := range reporterSpecs {
+		target, err := runner.ParseReportTarget(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		target, err := runner.ParseReportTarget(*outputFmt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		targets = append(targets, target)
+	}
+
+	var data []map[string]string
+	if *dataFile != "" {
+		data, err = runner.LoadData(*dataFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading data file: %v\n", err)
+			return 1
+		}
+	}
+
+	globals, err := store.LoadGlobals()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading globals: %v\n", err)
+		return 1
+	}
+
 	var environment runner.EnvironmentProvider
 	if *env != "" {
-		loadedEnv, err := store.LoadEnvironment(*env)
+		resolvedEnv, _, err := store.ResolveEnvironment(*env)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading environment: %v\n", err)
 			return 1
 		}
-		environment = &envWrapper{env: loadedEnv}
+		environment = &envWrapper{env: resolvedEnv}
 	}
 
 	cfg := &runner.Config{
@@ -51,12 +113,64 @@ func RunRunner(ctx *Context, args []string) int {
 		Environment: environment,
 		StopOnFail:  *stopOnFail,
 		OutputFormat: *outputFmt,
+		Lenient:     *lenient,
+		Globals:     globals.Variables,
+		Data:        data,
+		Iterations:  *iterations,
+		Parallel:    *parallel,
+		Folder:      folderPath,
 	}
 
 	result := runner.Execute(cfg)
-	runner.PrintResult(result, *outputFmt)
+	recordRunMetrics(ctx, col.ID, result)
+	recordRunHistory(ctx, *env, result)
+	if err := runner.WriteReports(result, targets); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		return 1
+	}
+
+	if *persist && len(result.Variables) > 0 {
+		// Persist into the environment's own file only, never into the ones it extends.
+		loadedEnv, err := store.LoadEnvironment(*env)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading environment: %v\n", err)
+			return 1
+		}
+		if loadedEnv.Variables == nil {
+			loadedEnv.Variables = make(map[string]string)
+		}
+		var box *secret.Box
+		for k, v := range result.Variables {
+			if loadedEnv.IsSecret(k) {
+				// A secret stays a secret: store the new value sealed, never in plaintext.
+				if box == nil {
+					if box, _, err = secret.Open(ctx.StoragePath); err != nil {
+						fmt.Fprintf(os.Stderr, "Error saving secret %s: %v\n", k, err)
+						return 1
+					}
+				}
+				sealed, err := box.Seal(v)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error saving secret %s: %v\n", k, err)
+					return 1
+				}
+				loadedEnv.Secrets[k] = sealed
+			}
+			if !loadedEnv.IsSecret(k) {
+				loadedEnv.Variables[k] = v
+			}
+		}
+		if err := store.SaveEnvironment(loadedEnv); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving environment: %v\n", err)
+			return 1
+		}
+	}
 
 	msg := fmt.Sprintf("%s: %d passed, %d failed", result.CollectionName, result.PassedCount, result.FailedCount)
+	if result.ErrorMessage != "" {
+		osnotify.Send("Raco", result.CollectionName+": "+result.ErrorMessage)
+		return 1
+	}
 	if result.FailedCount > 0 {
 		osnotify.Send("Raco", msg)
 		return 1
@@ -65,19 +179,87 @@ func RunRunner(ctx *Context, args []string) int {
 	return 0
 }
 
+// recordRunMetrics adds a metric for every request of result that was sent.
+func recordRunMetrics(ctx *Context, collectionID string, result *runner.Result) {
+	for _, r := range result.RequestResults {
+		if r.Request == nil {
+			continue
+		}
+		var resp *model.Response
+		errMsg := r.ErrorMessage
+		if r.StatusCode > 0 {
+			resp = &model.Response{StatusCode: r.StatusCode, Duration: r.Duration}
+			errMsg = ""
+		}
+		metric := metrics.NewMetric("HTTP", r.Method, r.Request.URL, resp, errMsg)
+		metric.RequestID = r.RequestID
+		metric.RequestName = r.Name
+		metric.Collection = collectionID
+		metric.Source = "run"
+		for _, a := range r.Assertions {
+			if a.Passed {
+				metric.AssertionsPassed++
+			}
+			if !a.Passed {
+				metric.AssertionsFailed++
+			}
+		}
+		if err := ctx.Storage().RecordMetric(metric); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not record metrics: %v\n", err)
+			return
+		}
+	}
+}
+
+// recordRunHistory adds a history entry for every request of result that was sent, as raco req
+// does for a single request.
+func recordRunHistory(ctx *Context, envName string, result *runner.Result) {
+	for _, r := range result.RequestResults {
+		if r.Request == nil {
+			continue
+		}
+		entry := model.NewHistoryEntry(r.Request.Method, r.Request.URL, r.Request.Headers, r.Request.Body, "HTTP")
+		entry.Source = "cli"
+		entry.Environment = envName
+		entry.StatusCode = r.StatusCode
+		entry.DurationMs = r.Duration.Milliseconds()
+		if r.Response != nil && !r.Response.Truncated {
+			entry.ResponseSize = len(r.Response.Body)
+		}
+		if r.StatusCode == 0 {
+			entry.Error = r.ErrorMessage
+		}
+		if err := ctx.Storage().AppendHistory(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not record history: %v\n", err)
+			return
+		}
+	}
+}
+
 func printRunnerUsage() {
-	fmt.Println(`Usage: raco run <collection-id> [options]
+	fmt.Println(`Usage: raco run <collection-id>[/<folder>...] [options]
 
 Options:
   -e <env>         Environment name
-  -o <format>      Output format: text, json
+  -o <format>      Output format: text, json, junit, tap, html
+  --reporter <r>   Write a report as name or name:file; repeat for several reports
   --stop-on-fail   Stop on first failure
+  --lenient        Warn about unresolved {{variables}} instead of failing
+  --persist        Save extracted and script-set values back to the environment (-e)
+  --data <file>    Run once per row of a CSV or JSON data file; columns become variables
+  --iterations <n> Run the collection n times (with --data, rows are reused in order)
+  --parallel <n>   Run up to n requests at once; requests marked sequential wait for the rest
 
 Examples:
   raco run my-api-tests
   raco run my-api-tests -e production
+  raco run my-api-tests/users/admin
   raco run my-api-tests -e staging -o json
-  raco run my-api-tests --stop-on-fail`)
+  raco run my-api-tests --stop-on-fail
+  raco run my-api-tests -e staging --data accounts.csv
+  raco run my-api-tests --iterations 5
+  raco run my-api-tests --parallel 8
+  raco run my-api-tests --reporter text --reporter junit:results.xml --reporter html:report.html`)
 }
 
 type envWrapper struct {
@@ -108,7 +290,7 @@ func reorderArgs(args []string) []string {
 
 		if len(arg) > 0 && arg[0] == '-' {
 			flags = append(flags, arg)
-			if arg == "-e" || arg == "-o" {
+			if takesValue(arg) {
 				if i+1 < len(args) {
 					flags = append(flags, args[i+1])
 					skipNext = true
@@ -122,3 +304,23 @@ func reorderArgs(args []string) []string {
 
 	return append(flags, positional...)
 }
+
+func takesValue(arg string) bool {
+	switch strings.TrimLeft(arg, "-") {
+	case "e", "o", "n", "data", "iterations", "parallel", "reporter", "layout", "format":
+		return true
+	}
+	return false
+}
+
+// stringList collects every value of a repeatable flag.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
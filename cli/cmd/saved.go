@@ -0,0 +1,578 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"raco/model"
+	"raco/storage"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// assertionOperators are the words --assert recognises as the operator of a spec.
+var assertionOperators = map[string]bool{
+	model.OpEquals:      true,
+	model.OpNotEquals:   true,
+	model.OpContains:    true,
+	model.OpNotContains: true,
+	model.OpMatches:     true,
+	model.OpGreater:     true,
+	model.OpGreaterEq:   true,
+	model.OpLess:        true,
+	model.OpLessEq:      true,
+	model.OpExists:      true,
+	model.OpNotExists:   true,
+}
+
+// RunSavedRequest manages the requests stored in collections. Requests are addressed by ID or,
+// when it is unique in the collection, by name.
+func RunSavedRequest(ctx *Context, args []string) int {
+	if len(args) == 0 {
+		printSavedRequestUsage()
+		return 1
+	}
+
+	store := ctx.Storage()
+	action := args[0]
+	subArgs := args[1:]
+
+	switch action {
+	case "list", "ls":
+		return savedList(store, subArgs)
+	case "show", "get":
+		return savedShow(store, subArgs)
+	case "update", "edit":
+		return savedUpdate(store, subArgs)
+	case "rm", "delete":
+		return savedDelete(store, subArgs)
+	case "mv", "move":
+		return savedTransfer(store, subArgs, true)
+	case "cp", "copy":
+		return savedTransfer(store, subArgs, false)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", action)
+		printSavedRequestUsage()
+		return 1
+	}
+}
+
+func printSavedRequestUsage() {
+	fmt.Println(`Usage: raco req-saved <action> <collection-id> [<request>] [options]
+
+<request> is a request ID or a name that is unique in the collection.
+
+Actions:
+  list, ls <col>                 List the requests with their IDs
+  show, get <col> <request>      Show a request (-o text|json)
+  update, edit <col> <request>   Change a request (options below)
+  rm, delete <col> <request>     Remove a request (--force even if others depend on it)
+  mv, move <col> <request>       Move or reorder (--to <col>[/<folder>...], --position <n>,
+                                 --name <name>, --force)
+  cp, copy <col> <request>       Copy under a new ID (--to, --position, --name)
+
+Update options:
+  -n <name>             Rename the request
+  -m <method>           HTTP method
+  -r <url>              URL
+  -d <body>             Body ("" clears it)
+  -H <hdr>              Set headers (Key:Value, multiple separated by ;)
+  -q <query>            Set query params (key=value, multiple separated by ;)
+  -t <sec>              Timeout in seconds (0 = default 30)
+  --rm-header <key>     Remove a header (repeatable)
+  --rm-query <key>      Remove a query param (repeatable)
+  --assert <spec>       Add an assertion: "<type> [field] <operator> [value]" (repeatable)
+  --rm-assert <n>       Remove assertion n as numbered by show (repeatable)
+  --clear-assertions    Remove every assertion
+  --extract <spec>      Add an extractor: "<variable>=<type> <source or pattern>" (repeatable)
+  --rm-extract <n>      Remove extractor n as numbered by show (repeatable)
+  --clear-extractors    Remove every extractor
+
+Examples:
+  raco req-saved ls my-api
+  raco req-saved show my-api "Get Users"
+  raco req-saved update my-api "Get Users" -r "{{base_url}}/v2/users" -H "Accept:application/json"
+  raco req-saved update my-api "Get Users" --assert "status_code equals 200" --assert "jsonpath data.id exists"
+  raco req-saved update my-api Login --extract 'token=jsonpath data.token' --rm-assert 2
+  raco req-saved mv my-api "Get Users" --position 1
+  raco req-saved mv my-api "Get Users" --to other-api/admin
+  raco req-saved cp my-api "Get Users" --name "Get Users v2"
+  raco req-saved rm my-api "Get Users"`)
+}
+
+// savedTarget splits the leading <collection> <request> arguments off args.
+func savedTarget(args []string, action string) (string, string, []string, bool) {
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: raco req-saved %s <collection-id> <request> [options]\n", action)
+		return "", "", nil, false
+	}
+	return args[0], args[1], args[2:], true
+}
+
+func savedList(store *storage.Storage, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: collection ID is required")
+		return 1
+	}
+
+	col, err := store.LoadCollection(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	requests := col.AllRequests()
+	if len(requests) == 0 {
+		fmt.Println("No requests found")
+		return 0
+	}
+	for i, req := range requests {
+		fmt.Printf("%3d  %-36s  %-7s  %s\n", i+1, req.ID, req.Method, savedPath(col, req))
+	}
+	return 0
+}
+
+func savedShow(store *storage.Storage, args []string) int {
+	colID, ref, rest, ok := savedTarget(args, "show")
+	if !ok {
+		return 1
+	}
+	fs := flag.NewFlagSet("req-saved show", flag.ContinueOnError)
+	outputFmt := fs.String("o", "text", "Output format: text, json")
+	if err := fs.Parse(rest); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	col, err := store.LoadCollection(colID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	req, err := col.FindRequest(ref)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *outputFmt == "json" {
+		data, _ := json.MarshalIndent(req, "", "  ")
+		fmt.Println(string(data))
+		return 0
+	}
+	printSavedRequest(col, req)
+	return 0
+}
+
+func printSavedRequest(col *model.Collection, req *model.Request) {
+	fmt.Printf("ID:         %s\n", req.ID)
+	fmt.Printf("Name:       %s\n", req.Name)
+	fmt.Printf("Collection: %s\n", col.ID)
+	if folders := col.FolderPath(req); len(folders) > 0 {
+		names := make([]string, 0, len(folders))
+		for _, f := range folders {
+			names = append(names, f.Name)
+		}
+		fmt.Printf("Folder:     %s\n", strings.Join(names, "/"))
+	}
+	fmt.Printf("Request:    %s %s\n", req.Method, req.URL)
+	if req.TimeoutSeconds > 0 {
+		fmt.Printf("Timeout:    %ds\n", req.TimeoutSeconds)
+	}
+	if req.Stage != "" {
+		fmt.Printf("Stage:      %s\n", req.Stage)
+	}
+	if len(req.DependsOn) > 0 {
+		fmt.Printf("Depends on: %s\n", strings.Join(req.DependsOn, ", "))
+	}
+
+	printSavedMap("Headers", req.Headers, ": ")
+	printSavedMap("Query", req.Query, "=")
+	if req.Body != "" {
+		fmt.Println("Body:")
+		for _, line := range strings.Split(req.Body, "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	if len(req.Assertions) > 0 {
+		fmt.Println("Assertions:")
+		for i, a := range req.Assertions {
+			fmt.Printf("  %d. %s\n", i+1, formatAssertion(a))
+		}
+	}
+	if len(req.Extractors) > 0 {
+		fmt.Println("Extractors:")
+		for i, e := range req.Extractors {
+			fmt.Printf("  %d. %s\n", i+1, formatExtractor(e))
+		}
+	}
+}
+
+func printSavedMap(title string, values map[string]string, sep string) {
+	if len(values) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Printf("%s:\n", title)
+	for _, k := range keys {
+		fmt.Printf("  %s%s%s\n", k, sep, values[k])
+	}
+}
+
+func savedUpdate(store *storage.Storage, args []string) int {
+	colID, ref, rest, ok := savedTarget(args, "update")
+	if !ok {
+		return 1
+	}
+
+	fs := flag.NewFlagSet("req-saved update", flag.ContinueOnError)
+	name := fs.String("n", "", "Request name")
+	method := fs.String("m", "", "HTTP method")
+	url := fs.String("r", "", "Request URL")
+	body := fs.String("d", "", "Request body")
+	headers := fs.String("H", "", "Headers to set (Key:Value, multiple separated by ;)")
+	query := fs.String("q", "", "Query params to set (key=value, multiple separated by ;)")
+	timeout := fs.Int("t", 0, "Request timeout in seconds (0 = default 30)")
+	clearAssertions := fs.Bool("clear-assertions", false, "Remove every assertion")
+	clearExtractors := fs.Bool("clear-extractors", false, "Remove every extractor")
+	var rmHeaders, rmQuery, asserts, rmAsserts, extracts, rmExtracts stringList
+	fs.Var(&rmHeaders, "rm-header", "Header to remove (repeatable)")
+	fs.Var(&rmQuery, "rm-query", "Query param to remove (repeatable)")
+	fs.Var(&asserts, "assert", "Assertion to add: \"<type> [field] <operator> [value]\" (repeatable)")
+	fs.Var(&rmAsserts, "rm-assert", "Number of an assertion to remove (repeatable)")
+	fs.Var(&extracts, "extract", "Extractor to add: \"<variable>=<type> <source>\" (repeatable)")
+	fs.Var(&rmExtracts, "rm-extract", "Number of an extractor to remove (repeatable)")
+
+	if err := fs.Parse(rest); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected argument %q\n", fs.Arg(0))
+		return 1
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if len(set) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: nothing to update; see raco req-saved help")
+		return 1
+	}
+
+	newAssertions := make([]model.Assertion, 0, len(asserts))
+	for _, spec := range asserts {
+		a, err := parseAssertion(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --assert %q: %v\n", spec, err)
+			return 1
+		}
+		newAssertions = append(newAssertions, a)
+	}
+	newExtractors := make([]model.Extractor, 0, len(extracts))
+	for _, spec := range extracts {
+		e, err := parseExtractor(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --extract %q: %v\n", spec, err)
+			return 1
+		}
+		newExtractors = append(newExtractors, e)
+	}
+
+	req, err := store.UpdateRequest(colID, ref, func(req *model.Request) error {
+		if set["n"] {
+			req.Name = *name
+		}
+		if set["m"] {
+			req.Method = strings.ToUpper(*method)
+		}
+		if set["r"] {
+			req.URL = *url
+		}
+		if set["d"] {
+			req.Body = *body
+		}
+		if set["t"] {
+			req.TimeoutSeconds = *timeout
+		}
+
+		if req.Headers == nil {
+			req.Headers = make(map[string]string)
+		}
+		for _, key := range rmHeaders {
+			if !deleteHeader(req.Headers, key) {
+				return fmt.Errorf("header %q not found", key)
+			}
+		}
+		parsePairs(req.Headers, *headers, ":")
+
+		for _, key := range rmQuery {
+			if _, ok := req.Query[key]; !ok {
+				return fmt.Errorf("query param %q not found", key)
+			}
+			delete(req.Query, key)
+		}
+		if *query != "" && req.Query == nil {
+			req.Query = make(map[string]string)
+		}
+		parsePairs(req.Query, *query, "=")
+
+		if *clearAssertions {
+			req.Assertions = nil
+		}
+		assertions, err := removeNumbered(req.Assertions, rmAsserts, "assertion")
+		if err != nil {
+			return err
+		}
+		req.Assertions = append(assertions, newAssertions...)
+
+		if *clearExtractors {
+			req.Extractors = nil
+		}
+		extractors, err := removeNumbered(req.Extractors, rmExtracts, "extractor")
+		if err != nil {
+			return err
+		}
+		req.Extractors = append(extractors, newExtractors...)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Updated request '%s' (%s)\n", req.Name, req.ID)
+	return 0
+}
+
+// deleteHeader removes key from headers, ignoring case like HTTP does.
+func deleteHeader(headers map[string]string, key string) bool {
+	for existing := range headers {
+		if strings.EqualFold(existing, key) {
+			delete(headers, existing)
+			return true
+		}
+	}
+	return false
+}
+
+// removeNumbered drops the 1-based positions in numbers from items.
+func removeNumbered[T any](items []T, numbers []string, what string) ([]T, error) {
+	drop := make(map[int]bool, len(numbers))
+	for _, n := range numbers {
+		i, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil {
+			return nil, fmt.Errorf("%s number %q is not a number", what, n)
+		}
+		if i < 1 || i > len(items) {
+			return nil, fmt.Errorf("no %s %d (the request has %d)", what, i, len(items))
+		}
+		drop[i] = true
+	}
+
+	kept := make([]T, 0, len(items))
+	for i, item := range items {
+		if !drop[i+1] {
+			kept = append(kept, item)
+		}
+	}
+	return kept, nil
+}
+
+// parseAssertion reads "<type> [field] <operator> [value]". The first known operator after the
+// type ends the field, so fields may contain spaces (CSS selectors); the value is the rest.
+func parseAssertion(spec string) (model.Assertion, error) {
+	tokens := tokenOffsets(spec)
+	if len(tokens) < 2 {
+		return model.Assertion{}, fmt.Errorf("expected \"<type> [field] <operator> [value]\"")
+	}
+
+	op := -1
+	for i := 1; i < len(tokens); i++ {
+		if assertionOperators[spec[tokens[i][0]:tokens[i][1]]] {
+			op = i
+			break
+		}
+	}
+	if op < 0 {
+		return model.Assertion{}, fmt.Errorf("no operator found (use one of %s)", strings.Join(sortedOperators(), ", "))
+	}
+
+	a := model.Assertion{
+		Type:     model.AssertionType(spec[tokens[0][0]:tokens[0][1]]),
+		Operator: spec[tokens[op][0]:tokens[op][1]],
+	}
+	if op > 1 {
+		a.Field = spec[tokens[1][0]:tokens[op-1][1]]
+	}
+	a.Value = strings.TrimSpace(spec[tokens[op][1]:])
+	return a, storage.ValidateAssertion(a)
+}
+
+// parseExtractor reads "<variable>=<type> <source>"; for regex extractors the source is the
+// pattern.
+func parseExtractor(spec string) (model.Extractor, error) {
+	target, rest, ok := strings.Cut(spec, "=")
+	if !ok {
+		return model.Extractor{}, fmt.Errorf("expected \"<variable>=<type> <source>\"")
+	}
+	kind, source, _ := strings.Cut(strings.TrimSpace(rest), " ")
+
+	e := model.Extractor{Type: model.ExtractionType(kind), Target: strings.TrimSpace(target)}
+	source = strings.TrimSpace(source)
+	if e.Type == model.ExtractRegex {
+		e.Pattern = source
+	}
+	if e.Type != model.ExtractRegex {
+		e.Source = source
+	}
+	return e, storage.ValidateExtractor(e)
+}
+
+func formatAssertion(a model.Assertion) string {
+	parts := []string{string(a.Type)}
+	if a.Field != "" {
+		parts = append(parts, a.Field)
+	}
+	parts = append(parts, a.Operator)
+	if a.Value != "" {
+		parts = append(parts, a.Value)
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatExtractor(e model.Extractor) string {
+	source := e.Source
+	if e.Type == model.ExtractRegex {
+		source = e.Pattern
+	}
+	return fmt.Sprintf("%s=%s %s", e.Target, e.Type, source)
+}
+
+// tokenOffsets returns the start and end of every whitespace-separated token of s.
+func tokenOffsets(s string) [][2]int {
+	tokens := make([][2]int, 0)
+	start := -1
+	for i, r := range s {
+		space := r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		if !space && start < 0 {
+			start = i
+		}
+		if space && start >= 0 {
+			tokens = append(tokens, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, [2]int{start, len(s)})
+	}
+	return tokens
+}
+
+func sortedOperators() []string {
+	ops := make([]string, 0, len(assertionOperators))
+	for op := range assertionOperators {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+func savedDelete(store *storage.Storage, args []string) int {
+	colID, ref, rest, ok := savedTarget(args, "rm")
+	if !ok {
+		return 1
+	}
+	fs := flag.NewFlagSet("req-saved rm", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Remove the request even if others depend on it")
+	if err := fs.Parse(rest); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	req, err := store.DeleteRequest(colID, ref, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, storage.ErrHasDependents) {
+			fmt.Fprintln(os.Stderr, "Use --force to remove it anyway; raco lint reports the dangling depends_on")
+		}
+		return 1
+	}
+
+	fmt.Printf("Removed request '%s' from collection '%s'\n", req.Name, colID)
+	return 0
+}
+
+// savedTransfer moves (mv) or copies (cp) a request within its collection or into another one.
+func savedTransfer(store *storage.Storage, args []string, move bool) int {
+	action := "cp"
+	if move {
+		action = "mv"
+	}
+	colID, ref, rest, ok := savedTarget(args, action)
+	if !ok {
+		return 1
+	}
+
+	fs := flag.NewFlagSet("req-saved "+action, flag.ContinueOnError)
+	to := fs.String("to", "", "Target collection and folder: <col>[/<folder>...]")
+	position := fs.Int("position", 0, "1-based position in the target list (default: last)")
+	name := fs.String("name", "", "New name for the request")
+	force := fs.Bool("force", false, "Move the request even if others in its collection depend on it")
+	if err := fs.Parse(rest); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *position < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --position cannot be negative")
+		return 1
+	}
+	if move && *to == "" && *position == 0 && *name == "" {
+		fmt.Fprintln(os.Stderr, "Error: nothing to do; pass --to, --position or --name")
+		return 1
+	}
+
+	dest := storage.RequestDestination{Position: *position, Name: *name}
+	dest.Collection, dest.Folder, _ = strings.Cut(*to, "/")
+
+	var req *model.Request
+	var err error
+	if move {
+		req, err = store.MoveRequest(colID, ref, dest, *force)
+	}
+	if !move {
+		req, err = store.CopyRequest(colID, ref, dest)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, storage.ErrHasDependents) {
+			fmt.Fprintln(os.Stderr, "Use --force to move it anyway; raco lint reports the dangling depends_on")
+		}
+		return 1
+	}
+
+	target := dest.Collection
+	if target == "" {
+		target = colID
+	}
+	verb := "Copied"
+	if move {
+		verb = "Moved"
+	}
+	fmt.Printf("%s request '%s' (%s) to collection '%s'\n", verb, req.Name, req.ID, target)
+	return 0
+}
+
+// savedPath names req by its folders and name, as list shows it.
+func savedPath(col *model.Collection, req *model.Request) string {
+	parts := make([]string, 0)
+	for _, f := range col.FolderPath(req) {
+		parts = append(parts, f.Name)
+	}
+	return strings.Join(append(parts, req.Name), "/")
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"raco/search"
+	"strings"
+)
+
+const (
+	highlightOn  = "\033[1;33m"
+	highlightOff = "\033[0m"
+)
+
+func RunSearch(ctx *Context, args []string) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	env := fs.String("e", "", "Environment that resolves templated hosts for host: filters")
+	limit := fs.Int("n", 20, "Number of results (0 for all)")
+	outputFmt := fs.String("o", "text", "Output format: text, json")
+	fs.Usage = printSearchUsage
+
+	if err := fs.Parse(reorderArgs(args)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *outputFmt != "text" && *outputFmt != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q (use text or json)\n", *outputFmt)
+		return 1
+	}
+	if fs.NArg() == 0 {
+		printSearchUsage()
+		return 1
+	}
+
+	q, err := search.Parse(searchQuery(fs.Args()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	results, err := search.Workspace(ctx.Storage(), q, *env)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	total := len(results)
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	if *outputFmt == "json" {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(data))
+		return 0
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No matches found")
+		return 0
+	}
+	color := colorOutput()
+	for _, r := range results {
+		fmt.Println(searchResultLine(r))
+		for _, hit := range r.Hits {
+			fmt.Printf("    %-8s  %s\n", hit.Field, highlight(hit.Snippet, hit.Spans, color))
+		}
+	}
+	if total > len(results) {
+		fmt.Printf("\n%d of %d results; use -n 0 to see all\n", len(results), total)
+	}
+	return 0
+}
+
+// searchQuery joins the arguments of raco search back into one query, quoting those the shell
+// kept together so "create order" stays a phrase.
+func searchQuery(args []string) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " \t") && !strings.Contains(arg, `"`) {
+			arg = `"` + arg + `"`
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// searchResultLine is the heading of a result: what it is and where to find it.
+func searchResultLine(r search.Result) string {
+	switch r.Kind {
+	case search.KindRequest:
+		return fmt.Sprintf("request      %s → %s  (%s %s)  [%s]", r.CollectionName, r.Title, r.Method, r.URL, r.Collection)
+	case search.KindEnvironment:
+		return fmt.Sprintf("environment  %s", r.Title)
+	}
+	return "history      " + historyLine(r.History)
+}
+
+// highlight marks the spans of s in bold yellow, or returns s unchanged without color.
+func highlight(s string, spans []search.Span, color bool) string {
+	if !color || len(spans) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(s[last:span.Start])
+		b.WriteString(highlightOn + s[span.Start:span.End] + highlightOff)
+		last = span.End
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// colorOutput reports whether stdout is a terminal and NO_COLOR is unset.
+func colorOutput() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func printSearchUsage() {
+	fmt.Println(`Usage: raco search <query> [options]
+
+Searches the workspace's saved requests by name, folder, method, URL, query parameters,
+headers and body, its environments by variable name and its history by method, URL, headers,
+body and error. Results are ranked: a match in a name counts more than one in a URL, and both
+more than one in a body. Every term must match; "quoted words" match as a phrase, and a term
+that appears nowhere may still match a request name whose letters it spells in order.
+
+Filters:
+  method:<method>   HTTP method, e.g. method:POST or method:PUT,PATCH
+  host:<text>       Part of the host; {{variables}} in URLs are resolved with the globals,
+                    the collection's variables and the -e environment
+  col:<collection>  Collection ID, or part of its name
+  in:<kind>         request, env or history
+
+Options:
+  -e <env>      Environment that resolves templated hosts for host: filters
+  -n <count>    Number of results to show (default 20, 0 for all)
+  -o <format>   Output format: text, json
+
+Values of environment variables and of sensitive headers such as Authorization are never
+searched or shown.
+
+Examples:
+  raco search /v2/orders
+  raco search orders method:POST host:api.example.org
+  raco search "create order" col:shop
+  raco search x-request-id in:history -n 5`)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"raco/secret"
+)
+
+// EnvNewSecretKey supplies the new passphrase for raco secrets rekey, e.g. in CI.
+const EnvNewSecretKey = "RACO_NEW_SECRET_KEY"
+
+func RunSecrets(ctx *Context, args []string) int {
+	if len(args) == 0 {
+		printSecretsUsage()
+		return 1
+	}
+
+	switch args[0] {
+	case "rekey":
+		return secretsRekey(ctx, args[1:])
+	case "status":
+		return secretsStatus(ctx)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", args[0])
+		printSecretsUsage()
+		return 1
+	}
+}
+
+func printSecretsUsage() {
+	fmt.Println(`Usage: raco secrets <action> [options]
+
+Actions:
+  status                      Show where the secret key is read from
+  rekey [--new-key-file path] Re-encrypt all environment secrets with a new key
+
+The key is read from $RACO_SECRET_KEY, the file named by $RACO_SECRET_KEY_FILE, or secret.key
+in the workspace (~/.raco by default). rekey takes the new key from --new-key-file, then
+$RACO_NEW_SECRET_KEY; without either it generates a new secret.key in the workspace.
+
+Examples:
+  raco env set production --secret API_KEY=secret123
+  raco secrets status
+  raco secrets rekey
+  RACO_SECRET_KEY=old RACO_NEW_SECRET_KEY=new raco secrets rekey`)
+}
+
+func secretsStatus(ctx *Context) int {
+	_, source, err := secret.Open(ctx.StoragePath)
+	if err != nil {
+		if errors.Is(err, secret.ErrNoKey) {
+			fmt.Println("No secret key configured")
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Secret key: %s\n", source)
+	return 0
+}
+
+func secretsRekey(ctx *Context, args []string) int {
+	fs := flag.NewFlagSet("secrets rekey", flag.ContinueOnError)
+	newKeyFile := fs.String("new-key-file", "", "File containing the new key")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	current, source, err := secret.Open(ctx.StoragePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// A generated key is staged and only installed once every secret has been re-encrypted, so a
+	// failed rekey never leaves files sealed with a key that was not saved.
+	generated := false
+	var next *secret.Box
+	switch {
+	case *newKeyFile != "":
+		next, err = secret.FromFile(*newKeyFile)
+	case os.Getenv(EnvNewSecretKey) != "":
+		next = secret.WithPassphrase(os.Getenv(EnvNewSecretKey))
+	default:
+		next, _, err = secret.NewKey(ctx.StoragePath)
+		generated = true
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: new key: %v\n", err)
+		return 1
+	}
+
+	result, err := ctx.Storage().RekeySecrets(current, next)
+	if err != nil {
+		if generated {
+			secret.DiscardNewKey(ctx.StoragePath)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if result.Files > 0 {
+			fmt.Fprintf(os.Stderr, "%d environment file(s) were already re-encrypted\n", result.Files)
+		}
+		return 1
+	}
+
+	fmt.Printf("Re-encrypted %d secret(s) in %d environment file(s) (old key: %s)\n", result.Secrets, result.Files, source)
+
+	if generated {
+		path, err := secret.InstallNewKey(ctx.StoragePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: installing new key: %v\n", err)
+			return 1
+		}
+		fmt.Printf("New key written to %s\n", path)
+		if source != path {
+			fmt.Printf("Replace the old key (%s) with the contents of %s\n", source, path)
+		}
+		return 0
+	}
+
+	fmt.Println("Configure the new key wherever the old one was used")
+	return 0
+}
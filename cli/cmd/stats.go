@@ -1,67 +1,255 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"raco/metrics"
+	"raco/storage"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 )
 
 type storedStats struct {
-	TotalRequests   int     `json:"total_requests"`
-	SuccessCount    int     `json:"success_count"`
-	FailureCount    int     `json:"failure_count"`
-	SuccessRate     float64 `json:"success_rate"`
-	AverageDuration int64   `json:"average_duration_ms"`
-	MinDuration     int64   `json:"min_duration_ms"`
-	MaxDuration     int64   `json:"max_duration_ms"`
+	TotalRequests   int            `json:"total_requests"`
+	SuccessCount    int            `json:"success_count"`
+	FailureCount    int            `json:"failure_count"`
+	SuccessRate     float64        `json:"success_rate"`
+	AverageDuration int64          `json:"average_duration_ms"`
+	MinDuration     int64          `json:"min_duration_ms"`
+	MaxDuration     int64          `json:"max_duration_ms"`
+	P50Duration     int64          `json:"p50_duration_ms"`
+	P90Duration     int64          `json:"p90_duration_ms"`
+	P95Duration     int64          `json:"p95_duration_ms"`
+	P99Duration     int64          `json:"p99_duration_ms"`
+	StatusCodes     map[string]int `json:"status_codes,omitempty"`
+	Errors          map[string]int `json:"errors,omitempty"`
+}
+
+type storedGroup struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	storedStats
+}
+
+type statsReport struct {
+	storedStats
+	By     string        `json:"by,omitempty"`
+	Groups []storedGroup `json:"groups,omitempty"`
 }
 
 func RunStats(ctx *Context, args []string) int {
 	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
-	outputFmt := fs.String("o", "text", "Output format: text, json")
+	outputFmt := fs.String("o", "text", "Output format: text, json, prometheus, openmetrics")
 	clear := fs.Bool("clear", false, "Clear statistics")
+	since := fs.String("since", "", "Only requests after this time")
+	until := fs.String("until", "", "Only requests before this time")
+	by := fs.String("by", "", "Group by: "+strings.Join(metrics.Dimensions, ", "))
+	collection := fs.String("collection", "", "Only requests of this collection ID")
+	limit := fs.Int("n", 20, "Number of groups to show with --by (0 for all)")
+	serve := fs.String("serve", "", "Serve /metrics on this address until interrupted")
+	fs.Usage = printStatsUsage
 
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
 	}
 
-	statsPath := filepath.Join(ctx.StoragePath, "stats.json")
-
+	store := ctx.Storage()
 	if *clear {
-		os.Remove(statsPath)
+		if err := store.ClearMetrics(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
 		fmt.Println("Statistics cleared")
 		return 0
 	}
 
-	data, err := os.ReadFile(statsPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println("No statistics available")
-			return 0
+	if *serve != "" {
+		if *since != "" || *until != "" || *by != "" {
+			fmt.Fprintln(os.Stderr, "Error: --serve exposes counters over all recorded requests and cannot be combined with --since, --until or --by")
+			return 1
+		}
+		return serveStats(ctx, *serve, *collection)
+	}
+
+	now := time.Now()
+	var from, to time.Time
+	var err error
+	if *since != "" {
+		if from, err = storage.ParseHistoryTime(*since, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --since: %v\n", err)
+			return 1
+		}
+	}
+	if *until != "" {
+		if to, err = storage.ParseHistoryTime(*until, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --until: %v\n", err)
+			return 1
 		}
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		return 1
 	}
 
-	var stats storedStats
-	if err := json.Unmarshal(data, &stats); err != nil {
+	all, err := store.LoadMetrics()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		return 1
 	}
 
+	selected := inCollection(metrics.Window(all, from, to), *collection)
+
+	if *outputFmt == metrics.FormatPrometheus || *outputFmt == metrics.FormatOpenMetrics {
+		if err := metrics.WriteExposition(os.Stdout, selected, *outputFmt); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	report := statsReport{storedStats: toStoredStats(metrics.CalculateStats(selected)), By: *by}
+	if *by != "" {
+		groups, err := metrics.GroupBy(selected, *by)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if *limit > 0 && len(groups) > *limit {
+			groups = groups[:*limit]
+		}
+		for _, g := range groups {
+			report.Groups = append(report.Groups, storedGroup{Key: g.Key, Label: g.Label, storedStats: toStoredStats(g.Stats)})
+		}
+	}
+
 	if *outputFmt == "json" {
-		output, _ := json.MarshalIndent(stats, "", "  ")
+		output, _ := json.MarshalIndent(report, "", "  ")
 		fmt.Println(string(output))
 		return 0
 	}
 
-	printStatsText(stats)
+	if len(selected) == 0 {
+		fmt.Println("No statistics available")
+		return 0
+	}
+	printStatsText(report.storedStats)
+	if *by != "" {
+		fmt.Println()
+		printStatsGroups(*by, report.Groups)
+	}
 	return 0
 }
 
+// inCollection returns the metrics of the collection with the given ID, or all of them when id
+// is empty.
+func inCollection(all []metrics.RequestMetric, id string) []metrics.RequestMetric {
+	if id == "" {
+		return all
+	}
+	filtered := make([]metrics.RequestMetric, 0, len(all))
+	for _, m := range all {
+		if m.Collection == id {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// serveStats serves the workspace metrics at addr/metrics, reading the store again for every
+// scrape so requests recorded by other raco processes show up.
+func serveStats(ctx *Context, addr string, collection string) int {
+	store := ctx.Storage()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(func() ([]metrics.RequestMetric, error) {
+		all, err := store.LoadMetrics()
+		if err != nil {
+			return nil, err
+		}
+		return inCollection(all, collection), nil
+	}))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Serving metrics on http://%s/metrics (Ctrl+C to stop)\n", listener.Addr())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func printStatsUsage() {
+	fmt.Println(`Usage: raco stats [options]
+
+Options:
+  -o <format>          Output format: text, json, prometheus, openmetrics
+  --since <time>       Only requests after this time (24h, 7d, 2026-10-01 or RFC 3339)
+  --until <time>       Only requests before this time
+  --by <dimension>     Break down by endpoint, host, protocol, collection, hour or day
+  -n <count>           Number of groups to show with --by (default 20, 0 for all)
+  --collection <id>    Only requests of this collection
+  --serve <addr>       Serve /metrics for Prometheus on addr until interrupted
+  --clear              Delete the recorded metrics
+
+Examples:
+  raco stats
+  raco stats --since 24h --by endpoint
+  raco stats -o prometheus > /var/lib/node_exporter/raco.prom.tmp && mv /var/lib/node_exporter/raco.prom.tmp /var/lib/node_exporter/raco.prom
+  raco stats --serve 127.0.0.1:9464`)
+}
+
+func toStoredStats(stats metrics.Stats) storedStats {
+	stored := storedStats{
+		TotalRequests:   stats.TotalRequests,
+		SuccessCount:    stats.SuccessCount,
+		FailureCount:    stats.FailureCount,
+		SuccessRate:     stats.SuccessRate,
+		AverageDuration: stats.AverageDuration.Milliseconds(),
+		MinDuration:     stats.MinDuration.Milliseconds(),
+		MaxDuration:     stats.MaxDuration.Milliseconds(),
+		P50Duration:     stats.P50Duration.Milliseconds(),
+		P90Duration:     stats.P90Duration.Milliseconds(),
+		P95Duration:     stats.P95Duration.Milliseconds(),
+		P99Duration:     stats.P99Duration.Milliseconds(),
+		Errors:          stats.Errors,
+	}
+	if len(stats.StatusCodes) > 0 {
+		stored.StatusCodes = make(map[string]int, len(stats.StatusCodes))
+		for code, count := range stats.StatusCodes {
+			stored.StatusCodes[fmt.Sprint(code)] = count
+		}
+	}
+	if len(stored.Errors) == 0 {
+		stored.Errors = nil
+	}
+	return stored
+}
+
 func printStatsText(stats storedStats) {
 	fmt.Println("Request Statistics")
 	fmt.Println("------------------")
@@ -72,4 +260,55 @@ func printStatsText(stats storedStats) {
 	fmt.Printf("Average Duration:  %dms\n", stats.AverageDuration)
 	fmt.Printf("Min Duration:      %dms\n", stats.MinDuration)
 	fmt.Printf("Max Duration:      %dms\n", stats.MaxDuration)
+	fmt.Printf("Percentiles:       p50 %dms  p90 %dms  p95 %dms  p99 %dms\n", stats.P50Duration, stats.P90Duration, stats.P95Duration, stats.P99Duration)
+	if len(stats.StatusCodes) > 0 {
+		fmt.Printf("Status Codes:      %s\n", formatCounts(stats.StatusCodes))
+	}
+	if len(stats.Errors) > 0 {
+		fmt.Printf("Errors:            %s\n", formatCounts(stats.Errors))
+	}
+}
+
+func printStatsGroups(by string, groups []storedGroup) {
+	width := len(by)
+	for _, g := range groups {
+		if len(g.Label) > width {
+			width = len(g.Label)
+		}
+	}
+	if width > 60 {
+		width = 60
+	}
+
+	fmt.Printf("%-*s  %6s  %7s  %7s  %7s  %7s  %7s\n", width, strings.ToUpper(by), "COUNT", "OK%", "AVG", "P50", "P95", "P99")
+	for _, g := range groups {
+		label := g.Label
+		if len(label) > width {
+			label = label[:width-1] + "…"
+		}
+		fmt.Printf("%-*s  %6d  %6.1f%%  %5dms  %5dms  %5dms  %5dms\n", width, label, g.TotalRequests, g.SuccessRate, g.AverageDuration, g.P50Duration, g.P95Duration, g.P99Duration)
+	}
+}
+
+// formatCounts renders counts as "200: 12  404: 1", sorted by key.
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %d", k, counts[k]))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// recordMetric adds metric to the workspace metrics. Like recordHistory, a failed write is only
+// reported.
+func recordMetric(ctx *Context, metric metrics.RequestMetric) {
+	if err := ctx.Storage().RecordMetric(metric); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record metrics: %v\n", err)
+	}
 }
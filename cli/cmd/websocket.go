@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"raco/http"
+	"raco/model"
 	"raco/protocol"
 	"strings"
 	"syscall"
@@ -17,6 +19,7 @@ func RunWebSocket(ctx *Context, args []string) int {
 	fs := flag.NewFlagSet("websocket", flag.ContinueOnError)
 	url := fs.String("r", "", "WebSocket URL (ws:// or wss://)")
 	headers := fs.String("H", "", "Headers (Key:Value, multiple separated by ;)")
+	envName := fs.String("e", "", "Environment name")
 
 	if err := fs.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -29,14 +32,21 @@ func RunWebSocket(ctx *Context, args []string) int {
 		return 1
 	}
 
-	if !strings.HasPrefix(*url, "ws://") && !strings.HasPrefix(*url, "wss://") {
+	env, err := loadStreamEnvironment(ctx, *envName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	target := http.ReplaceEnvVars(*url, env)
+	if !strings.HasPrefix(target, "ws://") && !strings.HasPrefix(target, "wss://") {
 		fmt.Fprintln(os.Stderr, "Error: URL must start with ws:// or wss://")
 		return 1
 	}
 
-	client := protocol.NewWebSocketClient(*url)
+	client := protocol.NewWebSocketClient(target)
 	if *headers != "" {
-		headerMap := parseHeaderFlag(*headers)
+		headerMap := http.ReplaceEnvVarsInMap(parseHeaderFlag(*headers), env)
 		if setHeaders, ok := client.(interface{ SetHeaders(map[string]string) }); ok {
 			setHeaders.SetHeaders(headerMap)
 		}
@@ -44,13 +54,19 @@ func RunWebSocket(ctx *Context, args []string) int {
 	connCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	entry := model.NewHistoryEntry("WS", target, nil, "", "WS")
+	entry.Source = "cli"
+	entry.Environment = *envName
 	if err := client.Connect(connCtx); err != nil {
+		entry.Error = err.Error()
+		recordHistory(ctx, entry)
 		fmt.Fprintf(os.Stderr, "Connection failed: %v\n", err)
 		return 1
 	}
 	defer client.Close()
+	recordHistory(ctx, entry)
 
-	fmt.Printf("Connected to %s\n", *url)
+	fmt.Printf("Connected to %s\n", target)
 	fmt.Println("Type messages and press Enter to send. Ctrl+C to exit.")
 
 	msgCh, err := client.Receive()
@@ -96,7 +112,7 @@ func RunWebSocket(ctx *Context, args []string) int {
 		case <-connCtx.Done():
 			return 1
 		case text := <-inputCh:
-			if err := client.Send(text); err != nil {
+			if err := client.Send(http.ReplaceEnvVars(text, env)); err != nil {
 				fmt.Fprintf(os.Stderr, "Send error: %v\n", err)
 			}
 		}
@@ -115,14 +131,28 @@ func parseHeaderFlag(s string) map[string]string {
 	return out
 }
 
+// loadStreamEnvironment loads the -e environment used to resolve placeholders in URLs, headers and messages.
+func loadStreamEnvironment(ctx *Context, name string) (*model.Environment, error) {
+	if name == "" {
+		return nil, nil
+	}
+	env, _, err := ctx.Storage().ResolveEnvironment(name)
+	if err != nil {
+		return nil, fmt.Errorf("environment %s: %w", name, err)
+	}
+	return env, nil
+}
+
 func printWebSocketUsage() {
 	fmt.Println(`Usage: raco ws [options]
 
 Options:
   -r <url>   WebSocket URL (ws:// or wss://) (required)
   -H <hdr>   Headers (Key:Value, multiple separated by ;)
+  -e <name>  Environment name ({{var}} and {{$fn}} placeholders are resolved)
 
 Examples:
   raco ws -r wss://echo.websocket.org
-  raco ws -r wss://api.example.org/ws -H "Authorization:Bearer token;X-Custom:value"`)
+  raco ws -r wss://api.example.org/ws -H "Authorization:Bearer token;X-Custom:value"
+  raco ws -r "{{ws_url}}" -e dev -H "Authorization:Bearer {{token}}"`)
 }
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"raco/workspace"
+)
+
+func RunWorkspace(ctx *Context, args []string) int {
+	if len(args) == 0 {
+		return workspaceShow(ctx)
+	}
+
+	switch args[0] {
+	case "show":
+		return workspaceShow(ctx)
+	case "init":
+		return workspaceInit(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", args[0])
+		printWorkspaceUsage()
+		return 1
+	}
+}
+
+func printWorkspaceUsage() {
+	fmt.Println(`Usage: raco workspace [show | init [dir]]
+
+raco uses the first of: --workspace <path>, $RACO_HOME, the nearest .raco directory in the
+working directory or one of its parents, ~/.raco.
+
+Actions:
+  show          Print the workspace in use and how it was found (default)
+  init [dir]    Create dir/.raco (default: current directory) with a .gitignore for secrets and history
+
+Examples:
+  raco workspace init
+  raco --workspace ../billing-service col list
+  RACO_HOME=/srv/raco raco run smoke -e production`)
+}
+
+func workspaceShow(ctx *Context) int {
+	ws := ctx.Workspace
+	fmt.Printf("Workspace: %s (%s)\n", ws.Path, ws.Source)
+	if ws.IsProject() {
+		fmt.Printf("Global:    %s\n", ws.Global)
+	}
+	return 0
+}
+
+func workspaceInit(args []string) int {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	path, created, err := workspace.Init(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if len(created) == 0 {
+		fmt.Printf("Workspace already initialised: %s\n", path)
+		return 0
+	}
+	for _, p := range created {
+		fmt.Printf("Created %s\n", p)
+	}
+	fmt.Println("Commit the collections and environments; the .gitignore keeps the secret key, local environments and history out of git")
+	return 0
+}
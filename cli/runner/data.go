@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const maxDataFileSize = 10 * 1024 * 1024
+
+// LoadData reads the rows of a data file for a data-driven run. CSV files use their first line as
+// column names; JSON files hold an array of objects. Every row becomes a map of variable values.
+func LoadData(path string) ([]map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	if info.Size() > maxDataFileSize {
+		return nil, fmt.Errorf("data file too large (max 10MB)")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var rows []map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = parseCSVData(data)
+	case ".json":
+		rows, err = parseJSONData(data)
+	default:
+		return nil, fmt.Errorf("unsupported data file %s (use .csv or .json)", filepath.Base(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("data file has no rows")
+	}
+
+	return rows, nil
+}
+
+func parseCSVData(data []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("data file has no rows")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		if header[i] == "" {
+			return nil, fmt.Errorf("invalid CSV: column %d has no name", i+1)
+		}
+	}
+
+	rows := make([]map[string]string, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseJSONData(data []byte) ([]map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var items []map[string]interface{}
+	if err := decoder.Decode(&items); err != nil {
+		return nil, fmt.Errorf("invalid JSON data (expected an array of objects): %w", err)
+	}
+
+	rows := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		row := make(map[string]string, len(item))
+		for name, value := range item {
+			row[name] = dataValue(value)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// dataValue turns a JSON value into a variable value; objects and arrays stay JSON-encoded.
+func dataValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
@@ -0,0 +1,464 @@
+package runner
+
+import (
+	"fmt"
+	"raco/http"
+	"raco/model"
+	"raco/script"
+	"strings"
+	"sync"
+)
+
+// maxFlowRuns caps how many requests one pass may execute, so setNextRequest loops terminate.
+const maxFlowRuns = 1000
+
+// plan is the execution order of one pass over a collection: setup requests, then the regular
+// requests, then teardown requests. Within each stage requests keep their collection order
+// unless a dependency has to run first.
+type plan struct {
+	setup    []*model.Request
+	main     []*model.Request
+	teardown []*model.Request
+	deps     map[*model.Request][]*model.Request
+}
+
+func (p *plan) size() int {
+	return len(p.setup) + len(p.main) + len(p.teardown)
+}
+
+// requests lists the requests of p in the order a pass runs them.
+func (p *plan) requests() []*model.Request {
+	all := make([]*model.Request, 0, p.size())
+	all = append(all, p.setup...)
+	all = append(all, p.main...)
+	return append(all, p.teardown...)
+}
+
+func stageRank(req *model.Request) int {
+	switch req.Stage {
+	case model.StageSetup:
+		return 0
+	case model.StageTeardown:
+		return 2
+	}
+	return 1
+}
+
+func requestLabel(req *model.Request) string {
+	if req.Name != "" {
+		return req.Name
+	}
+	return req.ID
+}
+
+// findRequest resolves a depends_on or setNextRequest reference, matching IDs before names.
+func findRequest(requests []*model.Request, ref string) (*model.Request, error) {
+	for _, req := range requests {
+		if req.ID != "" && req.ID == ref {
+			return req, nil
+		}
+	}
+
+	var found *model.Request
+	for _, req := range requests {
+		if req.Name != ref {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("request name %q is ambiguous", ref)
+		}
+		found = req
+	}
+	if found == nil {
+		return nil, fmt.Errorf("unknown request %q", ref)
+	}
+	return found, nil
+}
+
+// buildPlan resolves depends_on references among requests and orders every stage so that
+// dependencies run first.
+func buildPlan(requests []*model.Request) (*plan, error) {
+	p := &plan{deps: make(map[*model.Request][]*model.Request)}
+	stages := make([][]*model.Request, 3)
+
+	for _, req := range requests {
+		if req.Stage != "" && req.Stage != model.StageSetup && req.Stage != model.StageTeardown {
+			return nil, fmt.Errorf("request %q has unknown stage %q (use setup or teardown)", requestLabel(req), req.Stage)
+		}
+
+		for _, ref := range req.DependsOn {
+			dep, err := findRequest(requests, ref)
+			if err != nil {
+				return nil, fmt.Errorf("request %q depends on an %v", requestLabel(req), err)
+			}
+			if dep == req {
+				return nil, fmt.Errorf("request %q depends on itself", requestLabel(req))
+			}
+			if stageRank(dep) > stageRank(req) {
+				return nil, fmt.Errorf("request %q cannot depend on later-stage request %q", requestLabel(req), requestLabel(dep))
+			}
+			p.deps[req] = append(p.deps[req], dep)
+		}
+
+		rank := stageRank(req)
+		stages[rank] = append(stages[rank], req)
+	}
+
+	for i, stage := range stages {
+		ordered, err := p.order(stage)
+		if err != nil {
+			return nil, err
+		}
+		stages[i] = ordered
+	}
+
+	p.setup, p.main, p.teardown = stages[0], stages[1], stages[2]
+	return p, nil
+}
+
+// order sorts a stage topologically, always picking the earliest request whose dependencies
+// have been placed.
+func (p *plan) order(stage []*model.Request) ([]*model.Request, error) {
+	inStage := make(map[*model.Request]bool, len(stage))
+	for _, req := range stage {
+		inStage[req] = true
+	}
+
+	placed := make(map[*model.Request]bool, len(stage))
+	ordered := make([]*model.Request, 0, len(stage))
+	for len(ordered) < len(stage) {
+		progressed := false
+		for _, req := range stage {
+			if placed[req] || !p.ready(req, inStage, placed) {
+				continue
+			}
+			placed[req] = true
+			ordered = append(ordered, req)
+			progressed = true
+			break
+		}
+		if !progressed {
+			return nil, p.cycleError(stage, placed)
+		}
+	}
+	return ordered, nil
+}
+
+func (p *plan) ready(req *model.Request, inStage, placed map[*model.Request]bool) bool {
+	for _, dep := range p.deps[req] {
+		if inStage[dep] && !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// cycleError follows unplaced dependencies until a request repeats and reports that loop.
+func (p *plan) cycleError(stage []*model.Request, placed map[*model.Request]bool) error {
+	var current *model.Request
+	for _, req := range stage {
+		if !placed[req] {
+			current = req
+			break
+		}
+	}
+
+	seen := make(map[*model.Request]int)
+	path := make([]*model.Request, 0)
+	for current != nil {
+		if idx, ok := seen[current]; ok {
+			names := make([]string, 0, len(path)-idx+1)
+			for _, req := range path[idx:] {
+				names = append(names, requestLabel(req))
+			}
+			names = append(names, requestLabel(current))
+			return fmt.Errorf("dependency cycle: %s", strings.Join(names, " -> "))
+		}
+		seen[current] = len(path)
+		path = append(path, current)
+
+		var next *model.Request
+		for _, dep := range p.deps[current] {
+			if !placed[dep] {
+				next = dep
+				break
+			}
+		}
+		current = next
+	}
+	return fmt.Errorf("dependency cycle")
+}
+
+// run executes the whole plan and returns the results in plan order. It reports whether
+// StopOnFail ended the pass, so the caller can skip the remaining iterations.
+func (f *flow) run() ([]RequestResult, bool) {
+	results := make([]RequestResult, 0, f.plan.size())
+	if f.cfg.Parallel < 2 {
+		results = append(results, f.runStage(f.plan.setup)...)
+		results = append(results, f.runMain()...)
+		results = append(results, f.runStage(f.plan.teardown)...)
+	}
+	if f.cfg.Parallel >= 2 {
+		results = append(results, f.runParallel(f.plan.setup)...)
+		results = append(results, f.runParallel(f.plan.main)...)
+		results = append(results, f.runParallel(f.plan.teardown)...)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return results, f.cfg.StopOnFail && f.failed
+}
+
+func (f *flow) runStage(stage []*model.Request) []RequestResult {
+	results := make([]RequestResult, 0, len(stage))
+	for _, req := range stage {
+		result := f.step(req)
+		if result.next != nil {
+			result.Warnings = append(result.Warnings, "setNextRequest is ignored in setup and teardown requests")
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// runMain runs the regular requests in order, following setNextRequest jumps. Requests that a
+// jump passed over are reported as skipped at the end.
+func (f *flow) runMain() []RequestResult {
+	results := make([]RequestResult, 0, len(f.plan.main))
+	reached := make(map[*model.Request]bool, len(f.plan.main))
+
+	for i, runs := 0, 0; i < len(f.plan.main); runs++ {
+		req := f.plan.main[i]
+		if runs >= maxFlowRuns {
+			f.stop(fmt.Sprintf("stopped after %d requests (setNextRequest loop?)", maxFlowRuns))
+			break
+		}
+
+		result := f.step(req)
+		reached[req] = true
+		i++
+
+		if result.next != nil && *result.next == "" {
+			f.stop(fmt.Sprintf("stopped by setNextRequest(null) in %s", requestLabel(req)))
+		}
+		if result.next != nil && *result.next != "" {
+			target, err := findRequest(f.plan.main, *result.next)
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("setNextRequest: %v", err))
+			}
+			if err == nil {
+				for idx, candidate := range f.plan.main {
+					if candidate == target {
+						i = idx
+					}
+				}
+			}
+		}
+		results = append(results, result)
+	}
+
+	for _, req := range f.plan.main {
+		if !reached[req] {
+			result := skippedResult(req, "not reached after setNextRequest")
+			f.record(req, result)
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// runParallel runs a stage with up to cfg.Parallel requests in flight. A request starts once its
+// dependencies have finished; Sequential requests wait for everything before them and hold back
+// everything after them.
+func (f *flow) runParallel(stage []*model.Request) []RequestResult {
+	slots := make([]RequestResult, len(stage))
+	done := make(map[*model.Request]chan struct{}, len(stage))
+	for _, req := range stage {
+		done[req] = make(chan struct{})
+	}
+
+	sem := make(chan struct{}, f.cfg.Parallel)
+	var wg sync.WaitGroup
+
+	for i, req := range stage {
+		if req.Sequential {
+			wg.Wait()
+		}
+
+		wg.Add(1)
+		go func(i int, req *model.Request) {
+			defer wg.Done()
+			defer close(done[req])
+
+			for _, dep := range f.plan.deps[req] {
+				if ch, ok := done[dep]; ok {
+					<-ch
+				}
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := f.step(req)
+			if result.next != nil {
+				result.Warnings = append(result.Warnings, "setNextRequest is ignored in parallel runs")
+			}
+			slots[i] = result
+		}(i, req)
+
+		if req.Sequential {
+			wg.Wait()
+		}
+	}
+	wg.Wait()
+
+	return slots
+}
+
+type outcome int
+
+const (
+	outcomePassed outcome = iota + 1
+	outcomeFailed
+	outcomeSkipped
+)
+
+// flow carries the state of one pass: what each request ended with and whether the pass was stopped.
+type flow struct {
+	cfg     *Config
+	client  *http.Client
+	scope   *model.Scope
+	plan    *plan
+	lenient bool
+
+	mu          sync.Mutex
+	outcomes    map[*model.Request]outcome
+	setupFailed string
+	stopped     string
+	failed      bool
+}
+
+func newFlow(cfg *Config, client *http.Client, scope *model.Scope, p *plan, lenient bool) *flow {
+	return &flow{
+		cfg:      cfg,
+		client:   client,
+		scope:    scope,
+		plan:     p,
+		lenient:  lenient,
+		outcomes: make(map[*model.Request]outcome),
+	}
+}
+
+// step runs req unless its stage, dependencies or conditions say otherwise, and records the outcome.
+func (f *flow) step(req *model.Request) RequestResult {
+	reason, err := f.skipReason(req)
+
+	var result RequestResult
+	switch {
+	case err != nil:
+		result = RequestResult{RequestID: req.ID, Name: req.Name, Method: req.Method, URL: req.URL, ErrorMessage: err.Error()}
+	case reason != "":
+		result = skippedResult(req, reason)
+	default:
+		result = executeRequest(f.client, f.cfg.Collection, req, f.scope, f.lenient)
+	}
+
+	f.record(req, result)
+	return result
+}
+
+func skippedResult(req *model.Request, reason string) RequestResult {
+	return RequestResult{
+		RequestID:  req.ID,
+		Name:       req.Name,
+		Method:     req.Method,
+		URL:        req.URL,
+		Skipped:    true,
+		SkipReason: reason,
+	}
+}
+
+func (f *flow) skipReason(req *model.Request) (string, error) {
+	f.mu.Lock()
+	stopped, setupFailed := f.stopped, f.setupFailed
+	outcomes := make(map[*model.Request]outcome, len(f.outcomes))
+	for k, v := range f.outcomes {
+		outcomes[k] = v
+	}
+	f.mu.Unlock()
+
+	if req.Stage != model.StageTeardown && stopped != "" {
+		return stopped, nil
+	}
+	if req.Stage == "" && setupFailed != "" {
+		return fmt.Sprintf("setup request %s failed", setupFailed), nil
+	}
+
+	for _, dep := range f.plan.deps[req] {
+		switch outcomes[dep] {
+		case outcomePassed:
+			continue
+		case outcomeFailed:
+			return fmt.Sprintf("dependency %s failed", requestLabel(dep)), nil
+		case outcomeSkipped:
+			return fmt.Sprintf("dependency %s was skipped", requestLabel(dep)), nil
+		}
+		return fmt.Sprintf("dependency %s did not run", requestLabel(dep)), nil
+	}
+
+	if req.RunIf == "" && req.SkipIf == "" {
+		return "", nil
+	}
+
+	vars := f.scope.Resolve(req.Variables)
+	if req.RunIf != "" {
+		ok, err := script.Condition(req.RunIf, vars)
+		if err != nil {
+			return "", fmt.Errorf("run_if: %w", err)
+		}
+		if !ok {
+			return fmt.Sprintf("run_if is false: %s", req.RunIf), nil
+		}
+	}
+	if req.SkipIf != "" {
+		skip, err := script.Condition(req.SkipIf, vars)
+		if err != nil {
+			return "", fmt.Errorf("skip_if: %w", err)
+		}
+		if skip {
+			return fmt.Sprintf("skip_if is true: %s", req.SkipIf), nil
+		}
+	}
+	return "", nil
+}
+
+func (f *flow) record(req *model.Request, result RequestResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if result.Skipped {
+		f.outcomes[req] = outcomeSkipped
+		return
+	}
+	if result.Passed {
+		f.outcomes[req] = outcomePassed
+		return
+	}
+
+	f.outcomes[req] = outcomeFailed
+	f.failed = true
+	if req.Stage == model.StageSetup && f.setupFailed == "" {
+		f.setupFailed = requestLabel(req)
+	}
+	if f.cfg.StopOnFail && f.stopped == "" {
+		f.stopped = fmt.Sprintf("stopped after %s failed", requestLabel(req))
+	}
+}
+
+func (f *flow) stop(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped == "" {
+		f.stopped = reason
+	}
+}
@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+type htmlReport struct {
+	Result    *Result
+	Generated string
+	Requests  []htmlRequest
+}
+
+type htmlRequest struct {
+	RequestResult
+	Status    string
+	Extracted map[string]string
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) int64 { return d.Milliseconds() },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Result.CollectionName}} – raco run report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2rem; color: #222; background: #fafafa; }
+h1 { margin-bottom: 0.2rem; }
+.meta { color: #666; margin-bottom: 1.5rem; }
+.summary { display: flex; gap: 1rem; margin-bottom: 1.5rem; }
+.summary div { background: #fff; border: 1px solid #ddd; border-radius: 6px; padding: 0.6rem 1rem; }
+.summary strong { display: block; font-size: 1.4rem; }
+.error { background: #fdecea; border: 1px solid #f5c2c0; padding: 0.8rem 1rem; border-radius: 6px; margin-bottom: 1rem; }
+details { background: #fff; border: 1px solid #ddd; border-left-width: 5px; border-radius: 6px; margin-bottom: 0.5rem; padding: 0.4rem 0.8rem; }
+details.passed { border-left-color: #2e9d4f; }
+details.failed { border-left-color: #d93025; }
+details.skipped { border-left-color: #999; }
+summary { cursor: pointer; font-weight: 600; }
+.badge { display: inline-block; min-width: 4.5rem; font-size: 0.8rem; text-transform: uppercase; }
+.passed .badge { color: #2e9d4f; }
+.failed .badge { color: #d93025; }
+.skipped .badge { color: #777; }
+.dim { color: #777; font-weight: normal; }
+table { border-collapse: collapse; margin: 0.4rem 0; }
+td, th { text-align: left; padding: 0.15rem 0.8rem 0.15rem 0; vertical-align: top; font-size: 0.9rem; }
+pre { background: #f4f4f4; padding: 0.6rem; overflow: auto; max-height: 24rem; font-size: 0.85rem; white-space: pre-wrap; word-break: break-all; }
+.ok { color: #2e9d4f; }
+.fail { color: #d93025; }
+h4 { margin: 0.8rem 0 0.2rem; }
+</style>
+</head>
+<body>
+<h1>{{.Result.CollectionName}}</h1>
+<div class="meta">Started {{.Result.StartedAt.Format "2006-01-02 15:04:05 MST"}} · {{ms .Result.Duration}}ms · generated {{.Generated}}</div>
+{{with .Result.ErrorMessage}}<div class="error">{{.}}</div>{{end}}
+<div class="summary">
+<div>Total<strong>{{.Result.TotalCount}}</strong></div>
+<div class="ok">Passed<strong>{{.Result.PassedCount}}</strong></div>
+<div class="fail">Failed<strong>{{.Result.FailedCount}}</strong></div>
+<div>Skipped<strong>{{.Result.SkippedCount}}</strong></div>
+</div>
+{{range .Requests}}
+<details class="{{.Status}}"{{if eq .Status "failed"}} open{{end}}>
+<summary><span class="badge">{{.Status}}</span> {{.Method}} {{.Name}} <span class="dim">{{if .Iteration}}iteration {{.Iteration}} · {{end}}{{if .StatusCode}}{{.StatusCode}} · {{end}}{{ms .Duration}}ms{{if .Attempts}} · {{.Attempts}} attempts{{end}}</span></summary>
+{{with .SkipReason}}<p>Skipped: {{.}}</p>{{end}}
+{{with .ErrorMessage}}<p class="fail">Error: {{.}}</p>{{end}}
+{{if .Assertions}}<h4>Assertions</h4>
+<table>{{range .Assertions}}<tr><td class="{{if .Passed}}ok{{else}}fail{{end}}">{{if .Passed}}✓{{else}}✗{{end}}</td><td>{{.Type}}</td><td>{{.Message}}</td></tr>{{end}}</table>{{end}}
+{{if .Extracted}}<h4>Extracted</h4>
+<table>{{range $k, $v := .Extracted}}<tr><th>{{$k}}</th><td>{{$v}}</td></tr>{{end}}</table>{{end}}
+{{if .Warnings}}<h4>Warnings</h4>
+<ul>{{range .Warnings}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Logs}}<h4>Logs</h4>
+<pre>{{range .Logs}}{{.}}
+{{end}}</pre>{{end}}
+{{with .Request}}<h4>Request</h4>
+<pre>{{.Method}} {{.URL}}
+{{range $k, $v := .Headers}}{{$k}}: {{$v}}
+{{end}}{{with .Body}}
+{{.}}{{end}}</pre>{{end}}
+{{with .Response}}<h4>Response</h4>
+<pre>{{.StatusCode}}
+{{range $k, $v := .Headers}}{{$k}}: {{$v}}
+{{end}}{{with .Body}}
+{{.}}{{end}}{{if .Truncated}}
+… (truncated){{end}}</pre>{{end}}
+</details>
+{{end}}
+</body>
+</html>
+`))
+
+// writeHTML writes a single self-contained page with no external assets.
+func writeHTML(w io.Writer, result *Result) error {
+	report := htmlReport{
+		Result:    result,
+		Generated: time.Now().Format("2006-01-02 15:04:05 MST"),
+		Requests:  make([]htmlRequest, 0, len(result.RequestResults)),
+	}
+	for _, req := range result.RequestResults {
+		report.Requests = append(report.Requests, htmlRequest{
+			RequestResult: req,
+			Status:        requestStatus(req),
+			Extracted:     redactValues(req.Extracted),
+		})
+	}
+	return htmlTemplate.Execute(w, report)
+}
@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"encoding/json"
+	"io"
+	"raco/util"
+	"time"
+)
+
+// JSONSchema identifies the layout written by the json reporter. It changes only when a field is
+// removed or changes meaning; new fields may be added within a version.
+const JSONSchema = "raco.run/v1"
+
+type jsonReport struct {
+	Schema     string            `json:"schema"`
+	Collection string            `json:"collection"`
+	StartedAt  time.Time         `json:"started_at"`
+	DurationMs int64             `json:"duration_ms"`
+	Summary    jsonSummary       `json:"summary"`
+	Error      string            `json:"error,omitempty"`
+	Iterations []jsonIteration   `json:"iterations,omitempty"`
+	Requests   []jsonRequest     `json:"requests"`
+	Variables  map[string]string `json:"variables,omitempty"`
+}
+
+type jsonSummary struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+type jsonIteration struct {
+	Index      int               `json:"index"`
+	Data       map[string]string `json:"data,omitempty"`
+	Summary    jsonSummary       `json:"summary"`
+	DurationMs int64             `json:"duration_ms"`
+}
+
+type jsonRequest struct {
+	Iteration  int               `json:"iteration,omitempty"`
+	Name       string            `json:"name"`
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	Status     string            `json:"status"`
+	StatusCode int               `json:"status_code,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	Attempts   int               `json:"attempts,omitempty"`
+	SkipReason string            `json:"skip_reason,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Assertions []jsonAssertion   `json:"assertions"`
+	Extracted  map[string]string `json:"extracted,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	Logs       []string          `json:"logs,omitempty"`
+	Request    *jsonHTTPRequest  `json:"request,omitempty"`
+	Response   *jsonHTTPResponse `json:"response,omitempty"`
+}
+
+type jsonAssertion struct {
+	Type    string `json:"type"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+type jsonHTTPRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+type jsonHTTPResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Truncated  bool              `json:"truncated,omitempty"`
+}
+
+func writeJSON(w io.Writer, result *Result) error {
+	report := jsonReport{
+		Schema:     JSONSchema,
+		Collection: result.CollectionName,
+		StartedAt:  result.StartedAt,
+		DurationMs: result.Duration.Milliseconds(),
+		Summary: jsonSummary{
+			Total:   result.TotalCount,
+			Passed:  result.PassedCount,
+			Failed:  result.FailedCount,
+			Skipped: result.SkippedCount,
+		},
+		Error:     result.ErrorMessage,
+		Requests:  make([]jsonRequest, 0, len(result.RequestResults)),
+		Variables: redactValues(result.Variables),
+	}
+
+	for _, iter := range result.Iterations {
+		report.Iterations = append(report.Iterations, jsonIteration{
+			Index: iter.Index,
+			Data:  redactValues(iter.Data),
+			Summary: jsonSummary{
+				Total:   iter.PassedCount + iter.FailedCount + iter.SkippedCount,
+				Passed:  iter.PassedCount,
+				Failed:  iter.FailedCount,
+				Skipped: iter.SkippedCount,
+			},
+			DurationMs: iter.Duration.Milliseconds(),
+		})
+	}
+
+	for _, req := range result.RequestResults {
+		report.Requests = append(report.Requests, jsonRequestFrom(req))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func jsonRequestFrom(req RequestResult) jsonRequest {
+	out := jsonRequest{
+		Iteration:  req.Iteration,
+		Name:       req.Name,
+		Method:     req.Method,
+		URL:        req.URL,
+		Status:     requestStatus(req),
+		StatusCode: req.StatusCode,
+		DurationMs: req.Duration.Milliseconds(),
+		Attempts:   req.Attempts,
+		SkipReason: req.SkipReason,
+		Error:      req.ErrorMessage,
+		Assertions: make([]jsonAssertion, 0, len(req.Assertions)),
+		Extracted:  redactValues(req.Extracted),
+		Warnings:   req.Warnings,
+		Logs:       req.Logs,
+	}
+
+	for _, a := range req.Assertions {
+		out.Assertions = append(out.Assertions, jsonAssertion{Type: a.Type, Passed: a.Passed, Message: a.Message})
+	}
+
+	if req.Request != nil {
+		out.Request = &jsonHTTPRequest{
+			Method:  req.Request.Method,
+			URL:     req.Request.URL,
+			Headers: req.Request.Headers,
+			Body:    req.Request.Body,
+		}
+	}
+	if req.Response != nil {
+		out.Response = &jsonHTTPResponse{
+			StatusCode: req.Response.StatusCode,
+			Headers:    req.Response.Headers,
+			Body:       req.Response.Body,
+			Truncated:  req.Response.Truncated,
+		}
+	}
+	return out
+}
+
+// requestStatus is "passed", "failed" or "skipped".
+func requestStatus(req RequestResult) string {
+	if req.Skipped {
+		return "skipped"
+	}
+	if req.Passed {
+		return "passed"
+	}
+	return "failed"
+}
+
+// redactValues copies vars with the values of sensitive names replaced.
+func redactValues(vars map[string]string) map[string]string {
+	if len(vars) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(vars))
+	for k, v := range vars {
+		if util.IsSensitiveKey(k) {
+			v = "[REDACTED]"
+		}
+		out[k] = v
+	}
+	return out
+}
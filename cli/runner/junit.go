@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Error     *junitMessage   `xml:"error,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Time      string         `xml:"time,attr"`
+	Failures  []junitMessage `xml:"failure"`
+	Error     *junitMessage  `xml:"error,omitempty"`
+	Skipped   *junitMessage  `xml:"skipped,omitempty"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes one testsuite per iteration and one testcase per request. Each failed
+// assertion becomes its own <failure>; transport and script errors become <error>.
+func writeJUnit(w io.Writer, result *Result) error {
+	doc := junitSuites{
+		Name: result.CollectionName,
+		Time: junitSeconds(result.Duration),
+	}
+
+	suites := make(map[int]*junitSuite)
+	order := make([]int, 0)
+	suiteFor := func(iteration int) *junitSuite {
+		if suite, ok := suites[iteration]; ok {
+			return suite
+		}
+		name := result.CollectionName
+		if iteration > 0 {
+			name = fmt.Sprintf("%s [iteration %d]", result.CollectionName, iteration)
+		}
+		suite := &junitSuite{Name: name}
+		if !result.StartedAt.IsZero() {
+			suite.Timestamp = result.StartedAt.UTC().Format("2006-01-02T15:04:05")
+		}
+		suites[iteration] = suite
+		order = append(order, iteration)
+		return suite
+	}
+
+	if result.ErrorMessage != "" {
+		suite := suiteFor(0)
+		suite.Errors++
+		suite.Error = &junitMessage{Message: result.ErrorMessage, Type: "run"}
+	}
+
+	for _, iter := range result.Iterations {
+		suiteFor(iter.Index).Time = junitSeconds(iter.Duration)
+	}
+
+	for _, req := range result.RequestResults {
+		suite := suiteFor(req.Iteration)
+		tc := junitTestCase{
+			Name:      strings.TrimSpace(req.Method + " " + req.Name),
+			ClassName: result.CollectionName,
+			Time:      junitSeconds(req.Duration),
+			SystemOut: strings.Join(req.Logs, "\n"),
+		}
+
+		switch {
+		case req.Skipped:
+			tc.Skipped = &junitMessage{Message: req.SkipReason}
+			suite.Skipped++
+		case req.ErrorMessage != "":
+			tc.Error = &junitMessage{Message: req.ErrorMessage, Type: "request"}
+			suite.Errors++
+		}
+
+		for _, a := range req.Assertions {
+			if a.Passed {
+				continue
+			}
+			tc.Failures = append(tc.Failures, junitMessage{Message: a.Message, Type: a.Type})
+		}
+		if len(tc.Failures) > 0 {
+			suite.Failures++
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	for _, iteration := range order {
+		suite := suites[iteration]
+		if suite.Time == "" {
+			suite.Time = junitSeconds(result.Duration)
+		}
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Errors += suite.Errors
+		doc.Skipped += suite.Skipped
+		doc.Suites = append(doc.Suites, *suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
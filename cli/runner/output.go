@@ -1,39 +1,50 @@
 package runner
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"raco/util"
+	"sort"
+	"strings"
 )
 
+// PrintResult writes result to stdout with the reporter named format, falling back to text.
 func PrintResult(result *Result, format string) {
-	if format == "json" {
-		printJSON(result)
-		return
+	if _, ok := lookupReporter(format); !ok {
+		format = "text"
+	}
+	if err := WriteReports(result, []ReportTarget{{Name: format}}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 	}
-
-	printText(result)
 }
 
-func printJSON(result *Result) {
-	data, _ := json.MarshalIndent(result, "", "  ")
-	fmt.Println(string(data))
-}
+func writeText(w io.Writer, result *Result) error {
+	fmt.Fprintf(w, "\nCollection: %s\n", result.CollectionName)
+	fmt.Fprintf(w, "Duration: %dms\n", result.Duration.Milliseconds())
+	fmt.Fprintln(w, "---")
 
-func printText(result *Result) {
-	fmt.Printf("\nCollection: %s\n", result.CollectionName)
-	fmt.Printf("Duration: %dms\n", result.Duration.Milliseconds())
-	fmt.Println("---")
+	if result.ErrorMessage != "" {
+		fmt.Fprintf(w, "Error: %s\n", result.ErrorMessage)
+	}
 
+	iteration := 0
 	for _, req := range result.RequestResults {
+		if req.Iteration != iteration {
+			iteration = req.Iteration
+			printIterationHeader(w, result, iteration)
+		}
+
 		status := "✓"
 		if !req.Passed {
 			status = "✗"
 		}
 		if req.Skipped {
-			status = "○"
+			fmt.Fprintf(w, "○ %s %s skipped: %s\n", req.Method, req.Name, req.SkipReason)
+			continue
 		}
 
-		fmt.Printf("%s %s %s [%d] %dms\n",
+		fmt.Fprintf(w, "%s %s %s [%d] %dms\n",
 			status,
 			req.Method,
 			req.Name,
@@ -41,8 +52,28 @@ func printText(result *Result) {
 			req.Duration.Milliseconds(),
 		)
 
+		if req.Attempts > 0 {
+			fmt.Fprintf(w, "  attempts: %d\n", req.Attempts)
+		}
+
 		if req.ErrorMessage != "" {
-			fmt.Printf("  Error: %s\n", req.ErrorMessage)
+			fmt.Fprintf(w, "  Error: %s\n", req.ErrorMessage)
+		}
+
+		for _, name := range sortedKeys(req.Extracted) {
+			value := req.Extracted[name]
+			if util.IsSensitiveKey(name) {
+				value = "[REDACTED]"
+			}
+			fmt.Fprintf(w, "  extracted: %s = %s\n", name, value)
+		}
+
+		for _, warning := range req.Warnings {
+			fmt.Fprintf(w, "  Warning: %s\n", warning)
+		}
+
+		for _, line := range req.Logs {
+			fmt.Fprintf(w, "  log: %s\n", line)
 		}
 
 		for _, assertion := range req.Assertions {
@@ -50,15 +81,53 @@ func printText(result *Result) {
 			if !assertion.Passed {
 				assertStatus = "  ✗"
 			}
-			fmt.Printf("%s [%s] %s\n", assertStatus, assertion.Type, assertion.Message)
+			fmt.Fprintf(w, "%s [%s] %s\n", assertStatus, assertion.Type, assertion.Message)
 		}
 	}
 
-	fmt.Println("---")
-	fmt.Printf("Total: %d | Passed: %d | Failed: %d | Skipped: %d\n",
+	fmt.Fprintln(w, "---")
+	for _, iter := range result.Iterations {
+		fmt.Fprintf(w, "Iteration %d: %d passed, %d failed, %d skipped (%dms)\n",
+			iter.Index,
+			iter.PassedCount,
+			iter.FailedCount,
+			iter.SkippedCount,
+			iter.Duration.Milliseconds(),
+		)
+	}
+	fmt.Fprintf(w, "Total: %d | Passed: %d | Failed: %d | Skipped: %d\n",
 		result.TotalCount,
 		result.PassedCount,
 		result.FailedCount,
 		result.SkippedCount,
 	)
+	return nil
+}
+
+func printIterationHeader(w io.Writer, result *Result, index int) {
+	header := fmt.Sprintf("Iteration %d/%d", index, result.IterationCount)
+	for _, iter := range result.Iterations {
+		if iter.Index != index || len(iter.Data) == 0 {
+			continue
+		}
+		pairs := make([]string, 0, len(iter.Data))
+		for _, name := range sortedKeys(iter.Data) {
+			value := iter.Data[name]
+			if util.IsSensitiveKey(name) {
+				value = "[REDACTED]"
+			}
+			pairs = append(pairs, name+"="+value)
+		}
+		header += " (" + strings.Join(pairs, ", ") + ")"
+	}
+	fmt.Fprintf(w, "[%s]\n", header)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
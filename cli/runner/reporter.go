@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"raco/util"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Reporter renders a finished run in one output format.
+type Reporter interface {
+	Report(w io.Writer, result *Result) error
+}
+
+// ReporterFunc adapts a function to the Reporter interface.
+type ReporterFunc func(w io.Writer, result *Result) error
+
+func (f ReporterFunc) Report(w io.Writer, result *Result) error {
+	return f(w, result)
+}
+
+var (
+	reportersMu sync.RWMutex
+	reporters   = map[string]Reporter{
+		"text":  ReporterFunc(writeText),
+		"json":  ReporterFunc(writeJSON),
+		"junit": ReporterFunc(writeJUnit),
+		"tap":   ReporterFunc(writeTAP),
+		"html":  ReporterFunc(writeHTML),
+	}
+)
+
+// RegisterReporter makes a reporter available to --reporter under name, replacing any existing one.
+func RegisterReporter(name string, r Reporter) {
+	reportersMu.Lock()
+	defer reportersMu.Unlock()
+	reporters[name] = r
+}
+
+// ReporterNames lists the registered reporters in alphabetical order.
+func ReporterNames() []string {
+	reportersMu.RLock()
+	defer reportersMu.RUnlock()
+
+	names := make([]string, 0, len(reporters))
+	for name := range reporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupReporter(name string) (Reporter, bool) {
+	reportersMu.RLock()
+	defer reportersMu.RUnlock()
+	r, ok := reporters[name]
+	return r, ok
+}
+
+// ReportTarget is one --reporter value: a reporter name and the file it writes to, or stdout
+// when Path is empty.
+type ReportTarget struct {
+	Name string
+	Path string
+}
+
+// ParseReportTarget parses "name" or "name:path".
+func ParseReportTarget(spec string) (ReportTarget, error) {
+	name, path, _ := strings.Cut(spec, ":")
+	target := ReportTarget{Name: strings.ToLower(strings.TrimSpace(name)), Path: strings.TrimSpace(path)}
+
+	if _, ok := lookupReporter(target.Name); !ok {
+		return target, fmt.Errorf("unknown reporter %q (available: %s)", target.Name, strings.Join(ReporterNames(), ", "))
+	}
+	return target, nil
+}
+
+// WriteReports renders result with every target. Each report is rendered in full before its
+// file is written, so a failing reporter never leaves a half-written file behind.
+func WriteReports(result *Result, targets []ReportTarget) error {
+	for _, target := range targets {
+		reporter, ok := lookupReporter(target.Name)
+		if !ok {
+			return fmt.Errorf("unknown reporter %q", target.Name)
+		}
+
+		var buf bytes.Buffer
+		if err := reporter.Report(&buf, result); err != nil {
+			return fmt.Errorf("%s report: %w", target.Name, err)
+		}
+
+		// Decrypted environment secrets never reach a report in plaintext.
+		output := []byte(util.RedactSecrets(buf.String()))
+
+		if target.Path == "" {
+			if _, err := os.Stdout.Write(output); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.WriteFile(target.Path, output, 0644); err != nil {
+			return fmt.Errorf("%s report: %w", target.Name, err)
+		}
+	}
+	return nil
+}
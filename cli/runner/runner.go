@@ -1,8 +1,12 @@
 package runner
 
 import (
+	"fmt"
 	"raco/http"
 	"raco/model"
+	"raco/script"
+	"raco/util"
+	"strings"
 	"time"
 )
 
@@ -16,6 +20,21 @@ type Config struct {
 	Environment  EnvironmentProvider
 	StopOnFail   bool
 	OutputFormat string
+	// Globals are the lowest-precedence variables, below collection and environment values.
+	Globals map[string]string
+	// Lenient sends requests with unresolved {{placeholders}} and reports them as warnings
+	// instead of failing the request.
+	Lenient bool
+	// Data holds one variable map per iteration of a data-driven run.
+	Data []map[string]string
+	// Iterations is how often the collection runs. Zero means once, or once per data row;
+	// with more iterations than rows the rows are reused in order.
+	Iterations int
+	// Parallel is the number of requests that may be in flight at once; values below 2 run
+	// requests one after another. Requests marked Sequential act as barriers.
+	Parallel int
+	// Folder limits the run to the requests below this slash-separated folder path.
+	Folder string
 }
 
 type Result struct {
@@ -24,11 +43,31 @@ type Result struct {
 	PassedCount    int
 	FailedCount    int
 	SkippedCount   int
+	StartedAt      time.Time
 	Duration       time.Duration
 	RequestResults []RequestResult
+	// IterationCount is the number of planned passes over the collection.
+	IterationCount int
+	// Iterations summarises each pass over the collection when it ran more than once or with data.
+	Iterations []IterationResult `json:",omitempty"`
+	// Variables holds the run-scoped values extracted or set by scripts during the run.
+	Variables map[string]string
+	// ErrorMessage is set when the run could not start, e.g. because of a dependency cycle.
+	ErrorMessage string `json:",omitempty"`
+}
+
+type IterationResult struct {
+	Index        int
+	Data         map[string]string `json:",omitempty"`
+	PassedCount  int
+	FailedCount  int
+	SkippedCount int
+	Duration     time.Duration
 }
 
 type RequestResult struct {
+	Iteration    int    `json:",omitempty"`
+	RequestID    string `json:",omitempty"`
 	Name         string
 	Method       string
 	URL          string
@@ -36,10 +75,40 @@ type RequestResult struct {
 	Duration     time.Duration
 	Passed       bool
 	Skipped      bool
+	SkipReason   string
 	Assertions   []AssertionResult
+	Logs         []string
+	Warnings     []string
+	Extracted    map[string]string
 	ErrorMessage string
+	// Attempts is how often a polling request was sent; zero for requests without polling.
+	Attempts int `json:",omitempty"`
+	// Request and Response are what was sent and received, with sensitive values redacted.
+	Request  *RequestDetail  `json:",omitempty"`
+	Response *ResponseDetail `json:",omitempty"`
+
+	// next is the target of setNextRequest from the request's scripts.
+	next *string
 }
 
+type RequestDetail struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+	Body    string
+}
+
+type ResponseDetail struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       string
+	// Truncated is set when Body was cut to maxReportedBody bytes.
+	Truncated bool
+}
+
+// maxReportedBody bounds how much of each response body is kept for reports.
+const maxReportedBody = 64 * 1024
+
 type AssertionResult struct {
 	Type    string
 	Passed  bool
@@ -49,74 +118,192 @@ type AssertionResult struct {
 func Execute(cfg *Config) *Result {
 	startTime := time.Now()
 
+	iterations := iterationCount(cfg)
+
 	result := &Result{
 		CollectionName: cfg.Collection.Name,
-		TotalCount:     len(cfg.Collection.Requests),
-		RequestResults: make([]RequestResult, 0, len(cfg.Collection.Requests)),
+		StartedAt:      startTime,
+		IterationCount: iterations,
+		Variables:      make(map[string]string),
 	}
 
-	var env *model.Environment
-	if cfg.Environment != nil {
-		env = &model.Environment{
-			Variables: cfg.Environment.GetVariables(),
+	requests := cfg.Collection.AllRequests()
+	if cfg.Folder != "" {
+		folder, err := cfg.Collection.FindFolder(cfg.Folder)
+		if err != nil {
+			result.ErrorMessage = err.Error()
+			result.Duration = time.Since(startTime)
+			return result
 		}
+		result.CollectionName = cfg.Collection.Name + "/" + strings.Trim(cfg.Folder, "/")
+		requests = folder.AllRequests()
+	}
+	result.RequestResults = make([]RequestResult, 0, len(requests)*iterations)
+
+	p, err := buildPlan(requests)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	var envVars map[string]string
+	if cfg.Environment != nil {
+		envVars = cfg.Environment.GetVariables()
 	}
 
 	client := http.NewClient()
+	lenient := cfg.Lenient || cfg.Collection.LenientVariables
+	multi := iterations > 1 || len(cfg.Data) > 0
 
-	for _, req := range cfg.Collection.Requests {
-		reqResult := executeRequest(client, req, env)
-		result.RequestResults = append(result.RequestResults, reqResult)
+	for i := 0; i < iterations; i++ {
+		iterStart := time.Now()
+		iter := IterationResult{Index: i + 1}
 
-		if reqResult.Passed {
-			result.PassedCount++
+		// Every iteration starts from a fresh runtime layer; extractors and scripts write to it,
+		// so chaining works without an environment.
+		scope := model.NewScope(cfg.Globals, cfg.Collection.Variables, envVars)
+		if len(cfg.Data) > 0 {
+			iter.Data = cfg.Data[i%len(cfg.Data)]
+			scope.Data = iter.Data
 		}
-		if !reqResult.Passed && !reqResult.Skipped {
-			result.FailedCount++
-			if cfg.StopOnFail {
-				result.SkippedCount = result.TotalCount - len(result.RequestResults)
-				break
+
+		results, stopped := newFlow(cfg, client, scope, p, lenient).run()
+		for _, reqResult := range results {
+			if multi {
+				reqResult.Iteration = iter.Index
+			}
+			result.RequestResults = append(result.RequestResults, reqResult)
+
+			if reqResult.Passed {
+				iter.PassedCount++
+			}
+			if !reqResult.Passed && !reqResult.Skipped {
+				iter.FailedCount++
 			}
+			if reqResult.Skipped {
+				iter.SkippedCount++
+			}
+		}
+
+		iter.Duration = time.Since(iterStart)
+
+		result.PassedCount += iter.PassedCount
+		result.FailedCount += iter.FailedCount
+		result.SkippedCount += iter.SkippedCount
+		for k, v := range scope.Snapshot() {
+			result.Variables[k] = v
+		}
+		if multi {
+			result.Iterations = append(result.Iterations, iter)
+		}
+
+		if stopped {
+			// The iterations that were not run are reported as skipped, so every count has the
+			// results behind it that the reporters write.
+			reason := fmt.Sprintf("not run: iteration %d was stopped", iter.Index)
+			for j := i + 1; j < iterations; j++ {
+				skipped := IterationResult{Index: j + 1}
+				if len(cfg.Data) > 0 {
+					skipped.Data = cfg.Data[j%len(cfg.Data)]
+				}
+				for _, req := range p.requests() {
+					reqResult := skippedResult(req, reason)
+					if multi {
+						reqResult.Iteration = skipped.Index
+					}
+					result.RequestResults = append(result.RequestResults, reqResult)
+					skipped.SkippedCount++
+				}
+				result.SkippedCount += skipped.SkippedCount
+				if multi {
+					result.Iterations = append(result.Iterations, skipped)
+				}
+			}
+			break
 		}
 	}
 
+	result.TotalCount = result.PassedCount + result.FailedCount + result.SkippedCount
 	result.Duration = time.Since(startTime)
 	return result
 }
 
-func executeRequest(client *http.Client, req *model.Request, env *model.Environment) RequestResult {
+func iterationCount(cfg *Config) int {
+	if cfg.Iterations > 0 {
+		return cfg.Iterations
+	}
+	if len(cfg.Data) > 0 {
+		return len(cfg.Data)
+	}
+	return 1
+}
+
+func executeRequest(client *http.Client, col *model.Collection, req *model.Request, scope *model.Scope, lenient bool) RequestResult {
 	result := RequestResult{
+		RequestID:  req.ID,
 		Name:       req.Name,
 		Method:     req.Method,
-		URL:        req.URL,
 		Assertions: make([]AssertionResult, 0, len(req.Assertions)),
 	}
 
-	processedReq := &model.Request{
-		ID:             req.ID,
-		Name:           req.Name,
-		Method:         req.Method,
-		URL:            http.ReplaceEnvVars(req.URL, env),
-		Headers:        make(map[string]string, len(req.Headers)),
-		Body:           http.ReplaceEnvVars(req.Body, env),
-		Query:          http.ReplaceEnvVarsInMap(req.Query, env),
-		Files:          req.Files,
-		TimeoutSeconds: req.TimeoutSeconds,
-		Assertions:     req.Assertions,
-		Extractors:     req.Extractors,
+	folders := col.FolderPath(req)
+	prepared := col.ApplyDefaults(model.ApplyFolders(folders, req))
+	result.URL = prepared.URL
+	vars := scope.Resolve(prepared.Variables)
+	before := model.CloneVariables(vars)
+
+	pre, err := script.RunPreRequest(col, folders, prepared, vars)
+	appendScriptResult(&result, pre)
+	scope.Commit(before, vars)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		result.Passed = false
+		return result
+	}
+
+	processedReq, err := http.ResolveRequest(prepared, &model.Environment{Variables: vars})
+	if err != nil {
+		if !lenient {
+			result.ErrorMessage = err.Error()
+			result.Passed = false
+			return result
+		}
+		result.Warnings = append(result.Warnings, err.Error())
 	}
 
-	for k, v := range req.Headers {
-		processedReq.Headers[k] = http.ReplaceEnvVars(v, env)
+	result.Request = &RequestDetail{
+		Method:  processedReq.Method,
+		URL:     processedReq.URL,
+		Headers: util.RedactHeaders(processedReq.Headers),
+		Body:    util.RedactJSON(processedReq.Body),
 	}
 
-	resp, err := client.Execute(processedReq)
+	var resp *model.Response
+	if req.Poll == nil {
+		resp, err = client.Execute(processedReq)
+	}
+	if req.Poll != nil {
+		var polled *http.PollResult
+		polled, err = client.Poll(processedReq, req.Poll, req.Assertions)
+		result.Attempts = polled.Attempts
+		resp = polled.Response
+		if err == nil && !polled.Met {
+			failure := model.PollFailure(polled.Attempts, polled.TimedOut, polled.Failed)
+			result.Assertions = append(result.Assertions, AssertionResult{
+				Type:    string(failure.Assertion.Type),
+				Passed:  false,
+				Message: failure.Message,
+			})
+		}
+	}
 	if err != nil {
 		result.ErrorMessage = err.Error()
 		result.Passed = false
 		return result
 	}
 
+	result.Response = responseDetail(resp)
 	result.StatusCode = resp.StatusCode
 	result.Duration = resp.Duration
 	result.Passed = true
@@ -133,11 +320,68 @@ func executeRequest(client *http.Client, req *model.Request, env *model.Environm
 		}
 	}
 
-	if env != nil {
+	if len(req.Extractors) > 0 {
+		extracted := &model.Environment{Variables: make(map[string]string)}
 		for _, extractor := range req.Extractors {
-			model.ExtractValue(extractor, resp, env)
+			if err := model.ExtractValue(extractor, resp, extracted); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("extract %s: %v", extractor.Target, err))
+			}
+		}
+		for k, v := range extracted.Variables {
+			scope.Set(k, v)
+			vars[k] = v
+		}
+		result.Extracted = extracted.Variables
+	}
+
+	before = model.CloneVariables(vars)
+	post, err := script.RunPostResponse(col, folders, processedReq, resp, vars)
+	appendScriptResult(&result, post)
+	scope.Commit(before, vars)
+	if err != nil {
+		result.Assertions = append(result.Assertions, AssertionResult{
+			Type:    string(model.AssertScript),
+			Passed:  false,
+			Message: err.Error(),
+		})
+	}
+
+	for _, assertion := range result.Assertions {
+		if !assertion.Passed {
+			result.Passed = false
 		}
 	}
 
 	return result
 }
+
+func responseDetail(resp *model.Response) *ResponseDetail {
+	detail := &ResponseDetail{
+		StatusCode: resp.StatusCode,
+		Headers:    util.RedactHeaders(resp.Headers),
+		Body:       resp.Body,
+	}
+	if len(detail.Body) > maxReportedBody {
+		detail.Body = strings.ToValidUTF8(detail.Body[:maxReportedBody], "")
+		detail.Truncated = true
+	}
+	detail.Body = util.RedactJSON(detail.Body)
+	return detail
+}
+
+func appendScriptResult(result *RequestResult, sr *script.Result) {
+	if sr == nil {
+		return
+	}
+	for _, t := range sr.Tests {
+		result.Assertions = append(result.Assertions, AssertionResult{
+			Type:    string(t.Assertion.Type),
+			Passed:  t.Passed,
+			Message: t.Message,
+		})
+	}
+	result.Logs = append(result.Logs, sr.Logs...)
+	if sr.Next != nil {
+		result.next = sr.Next
+	}
+}
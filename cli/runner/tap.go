@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeTAP writes TAP version 13: one test point per request, with failed assertions and errors
+// in a YAML diagnostic block and skipped requests marked with a SKIP directive.
+func writeTAP(w io.Writer, result *Result) error {
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+
+	if result.ErrorMessage != "" {
+		fmt.Fprintf(&b, "1..0 # SKIP %s\n", tapEscape(result.ErrorMessage))
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	fmt.Fprintf(&b, "1..%d\n", len(result.RequestResults))
+	for i, req := range result.RequestResults {
+		description := strings.TrimSpace(req.Method + " " + req.Name)
+		if req.Iteration > 0 {
+			description = fmt.Sprintf("%s [iteration %d]", description, req.Iteration)
+		}
+
+		if req.Skipped {
+			fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", i+1, tapEscape(description), tapEscape(req.SkipReason))
+			continue
+		}
+
+		if req.Passed {
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, tapEscape(description))
+			continue
+		}
+
+		fmt.Fprintf(&b, "not ok %d - %s\n", i+1, tapEscape(description))
+		b.WriteString("  ---\n")
+		if req.StatusCode > 0 {
+			fmt.Fprintf(&b, "  status_code: %d\n", req.StatusCode)
+		}
+		fmt.Fprintf(&b, "  duration_ms: %d\n", req.Duration.Milliseconds())
+		if req.Attempts > 0 {
+			fmt.Fprintf(&b, "  attempts: %d\n", req.Attempts)
+		}
+		if req.ErrorMessage != "" {
+			fmt.Fprintf(&b, "  error: %s\n", strconv.Quote(req.ErrorMessage))
+		}
+		failures := make([]AssertionResult, 0)
+		for _, a := range req.Assertions {
+			if !a.Passed {
+				failures = append(failures, a)
+			}
+		}
+		if len(failures) > 0 {
+			b.WriteString("  failures:\n")
+			for _, a := range failures {
+				fmt.Fprintf(&b, "    - type: %s\n", strconv.Quote(a.Type))
+				fmt.Fprintf(&b, "      message: %s\n", strconv.Quote(a.Message))
+			}
+		}
+		b.WriteString("  ...\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tapEscape keeps descriptions on one line and stops "#" from starting a directive.
+func tapEscape(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "#", "\\#")
+}
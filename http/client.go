@@ -8,7 +8,6 @@ import (
 	"mime/multipart"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"raco/model"
@@ -63,6 +62,7 @@ const (
 	defaultRequestTimeout = 30 * time.Second
 	maxRetries            = 3
 	retryBaseDelay        = 1 * time.Second
+	retryLimit            = 10
 )
 
 func requestTimeout(req *model.Request) time.Duration {
@@ -76,6 +76,28 @@ func requestTimeout(req *model.Request) time.Duration {
 	return defaultRequestTimeout
 }
 
+// retryPolicy returns how many retries req allows and the delay before the first one.
+func retryPolicy(req *model.Request) (int, time.Duration) {
+	if req == nil || req.Retry == nil {
+		return maxRetries, retryBaseDelay
+	}
+	retries := req.Retry.MaxRetries
+	if retries < 0 {
+		retries = 0
+	}
+	if retries > retryLimit {
+		retries = retryLimit
+	}
+	delay := retryBaseDelay
+	if req.Retry.DelayMs > 0 {
+		delay = time.Duration(req.Retry.DelayMs) * time.Millisecond
+	}
+	if delay > 30*time.Second {
+		delay = 30 * time.Second
+	}
+	return retries, delay
+}
+
 func isIdempotentMethod(method string) bool {
 	switch method {
 	case "GET", "HEAD", "PUT", "DELETE":
@@ -156,9 +178,10 @@ func (c *Client) Execute(req *model.Request) (*model.Response, error) {
 	var lastResp *model.Response
 	var lastErr error
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
+	retries, baseDelay := retryPolicy(req)
+	for attempt := 0; attempt <= retries; attempt++ {
 		if attempt > 0 {
-			delay := retryBaseDelay * (1 << (attempt - 1))
+			delay := baseDelay * (1 << (attempt - 1))
 			if delay > 30*time.Second {
 				delay = 30 * time.Second
 			}
@@ -215,7 +238,7 @@ func (c *Client) Execute(req *model.Request) (*model.Response, error) {
 		lastResp = resp
 		lastErr = nil
 
-		shouldRetry := isIdempotentMethod(req.Method) && isRetryableStatus(httpResp.StatusCode) && attempt < maxRetries
+		shouldRetry := isIdempotentMethod(req.Method) && isRetryableStatus(httpResp.StatusCode) && attempt < retries
 		if !shouldRetry {
 			return resp, nil
 		}
@@ -244,18 +267,9 @@ func (c *Client) buildRequest(req *model.Request) (*http.Request, error) {
 		bodyReader = strings.NewReader(req.Body)
 	}
 
-	requestURL := req.URL
-	if len(req.Query) > 0 {
-		parsed, err := url.Parse(req.URL)
-		if err != nil {
-			return nil, err
-		}
-		q := parsed.Query()
-		for k, v := range req.Query {
-			q.Set(k, v)
-		}
-		parsed.RawQuery = q.Encode()
-		requestURL = parsed.String()
+	requestURL, err := FullURL(req)
+	if err != nil {
+		return nil, err
 	}
 
 	httpReq, err := http.NewRequest(req.Method, requestURL, bodyReader)
@@ -371,21 +385,20 @@ func SaveDownloadedFile(resp *model.Response, downloadPath string) (*model.FileD
 }
 
 func ReplaceEnvVars(input string, env *model.Environment) string {
-	if env == nil || len(env.Variables) == 0 {
+	if !strings.Contains(input, "{{") {
 		return input
 	}
 
-	result := input
-	for key, value := range env.Variables {
-		placeholder := "{{" + key + "}}"
-		result = strings.ReplaceAll(result, placeholder, value)
+	var vars map[string]string
+	if env != nil {
+		vars = env.Variables
 	}
 
-	return result
+	return util.RenderTemplate(input, vars)
 }
 
 func ReplaceEnvVarsInMap(m map[string]string, env *model.Environment) map[string]string {
-	if env == nil || len(m) == 0 {
+	if len(m) == 0 {
 		return m
 	}
 	out := make(map[string]string, len(m))
@@ -394,3 +407,51 @@ func ReplaceEnvVarsInMap(m map[string]string, env *model.Environment) map[string
 	}
 	return out
 }
+
+// ApplyEnvVars returns a copy of req with variables and template functions resolved in the URL,
+// query, headers, body and file uploads.
+func ApplyEnvVars(req *model.Request, env *model.Environment) *model.Request {
+	processed := *req
+	processed.URL = ReplaceEnvVars(req.URL, env)
+	processed.Body = ReplaceEnvVars(req.Body, env)
+	processed.Query = ReplaceEnvVarsInMap(req.Query, env)
+	processed.Headers = ReplaceEnvVarsInMap(req.Headers, env)
+
+	if len(req.Files) > 0 {
+		processed.Files = make([]model.FileUpload, len(req.Files))
+		for i, f := range req.Files {
+			f.FieldName = ReplaceEnvVars(f.FieldName, env)
+			f.FilePath = ReplaceEnvVars(f.FilePath, env)
+			f.FileName = ReplaceEnvVars(f.FileName, env)
+			processed.Files[i] = f
+		}
+	}
+
+	return &processed
+}
+
+// ResolveRequest is ApplyEnvVars followed by a check for placeholders that could not be resolved.
+// The processed request is always returned; the error lists undefined or circular variables so
+// callers can fail (strict) or only warn (lenient).
+func ResolveRequest(req *model.Request, env *model.Environment) (*model.Request, error) {
+	processed := ApplyEnvVars(req, env)
+
+	texts := make([]string, 0, 2+len(processed.Headers)+len(processed.Query)+2*len(processed.Files))
+	texts = append(texts, processed.URL, processed.Body)
+	for _, v := range processed.Headers {
+		texts = append(texts, v)
+	}
+	for _, v := range processed.Query {
+		texts = append(texts, v)
+	}
+	for _, f := range processed.Files {
+		texts = append(texts, f.FilePath, f.FieldName)
+	}
+
+	var vars map[string]string
+	if env != nil {
+		vars = env.Variables
+	}
+
+	return processed, util.CheckTemplate(vars, texts...)
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"raco/model"
+	"time"
+)
+
+// PollResult is the outcome of Client.Poll. Response is the last attempt's response; Failed
+// holds the conditions that still failed on it.
+type PollResult struct {
+	Response *model.Response
+	Attempts int
+	Met      bool
+	TimedOut bool
+	Failed   []model.AssertionResult
+}
+
+// Poll sends req repeatedly as configured by poll until its conditions (or fallback when poll
+// has none) pass. Only the final attempt counts: a transport error on it is returned as err.
+func (c *Client) Poll(req *model.Request, poll *model.Polling, fallback []model.Assertion) (*PollResult, error) {
+	conditions := poll.Conditions(fallback)
+	maxAttempts := poll.Attempts()
+
+	var deadline time.Time
+	if timeout := poll.Timeout(); timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	result := &PollResult{}
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		result.Attempts = attempt
+
+		resp, err := c.Execute(req)
+		result.Response = resp
+		lastErr = err
+		if err == nil {
+			result.Failed = model.CheckConditions(conditions, resp)
+			if len(result.Failed) == 0 {
+				result.Met = true
+				return result, nil
+			}
+		}
+
+		if attempt >= maxAttempts {
+			break
+		}
+
+		delay := poll.Delay(attempt)
+		if !deadline.IsZero() && time.Now().Add(delay).After(deadline) {
+			result.TimedOut = true
+			break
+		}
+		time.Sleep(delay)
+	}
+
+	return result, lastErr
+}
@@ -0,0 +1,23 @@
+package http
+
+import (
+	"net/url"
+	"raco/model"
+)
+
+// FullURL returns the URL of req with its Query parameters merged into the query string.
+func FullURL(req *model.Request) (string, error) {
+	if len(req.Query) == 0 {
+		return req.URL, nil
+	}
+	parsed, err := url.Parse(req.URL)
+	if err != nil {
+		return "", err
+	}
+	q := parsed.Query()
+	for k, v := range req.Query {
+		q.Set(k, v)
+	}
+	parsed.RawQuery = q.Encode()
+	return parsed.String(), nil
+}
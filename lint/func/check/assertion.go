@@ -0,0 +1,174 @@
+package check
+
+import (
+	"fmt"
+	"raco/markup"
+	"raco/model"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxPattern matches the limit assertions and extractors apply to regular expressions.
+const maxPattern = 4096
+
+var valueOperators = map[string]bool{
+	model.OpEquals:      true,
+	model.OpNotEquals:   true,
+	model.OpContains:    true,
+	model.OpNotContains: true,
+	model.OpMatches:     true,
+	model.OpGreater:     true,
+	model.OpGreaterEq:   true,
+	model.OpLess:        true,
+	model.OpLessEq:      true,
+	model.OpExists:      true,
+	model.OpNotExists:   true,
+}
+
+func (r *reporter) assertion(label string, field string, a model.Assertion) {
+	switch a.Type {
+	case model.AssertStatusCode, model.AssertHeader, model.AssertJSONPath, model.AssertXPath, model.AssertCSS:
+		if !valueOperators[a.Operator] {
+			r.errorf(RuleAssertion, label, field, "unknown %s operator %q", a.Type, a.Operator)
+			return
+		}
+	case model.AssertRegex:
+		if a.Operator != model.OpMatches {
+			r.errorf(RuleAssertion, label, field, "regex assertions only support the %s operator, not %q", model.OpMatches, a.Operator)
+			return
+		}
+		r.pattern(label, field, a.Value)
+		return
+	case model.AssertScript, model.AssertPoll:
+		r.errorf(RuleAssertion, label, field, "%s is reported by raco and cannot be used as an assertion type", a.Type)
+		return
+	default:
+		r.errorf(RuleAssertion, label, field, "unknown assertion type %q", a.Type)
+		return
+	}
+
+	if a.Type != model.AssertStatusCode && strings.TrimSpace(a.Field) == "" {
+		r.errorf(RuleAssertion, label, field, "%s assertion has no field", a.Type)
+	}
+	if a.Type == model.AssertStatusCode && (a.Operator == model.OpEquals || a.Operator == model.OpNotEquals) && !strings.Contains(a.Value, "{{") {
+		if _, err := strconv.Atoi(strings.TrimSpace(a.Value)); err != nil {
+			r.errorf(RuleAssertion, label, field, "status code %q is not a number", a.Value)
+		}
+	}
+	if a.Type == model.AssertJSONPath {
+		r.jsonPath(label, field, a.Field)
+	}
+	if a.Type == model.AssertXPath || a.Type == model.AssertCSS {
+		r.selector(label, field, string(a.Type), a.Field)
+	}
+
+	switch a.Operator {
+	case model.OpMatches:
+		r.pattern(label, field, a.Value)
+	case model.OpGreater, model.OpGreaterEq, model.OpLess, model.OpLessEq:
+		if strings.Contains(a.Value, "{{") {
+			return
+		}
+		if _, err := strconv.ParseFloat(strings.TrimSpace(a.Value), 64); err != nil {
+			r.errorf(RuleAssertion, label, field, "%s compares numbers but %q is not one", a.Operator, a.Value)
+		}
+	}
+}
+
+// pattern checks a regular expression the way assertions and extractors compile it.
+func (r *reporter) pattern(label string, field string, pattern string) {
+	if len(pattern) > maxPattern {
+		r.errorf(RuleRegex, label, field, "regular expression is longer than %d bytes", maxPattern)
+		return
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		r.errorf(RuleRegex, label, field, "regular expression %q does not compile: %v", pattern, err)
+	}
+}
+
+// jsonPath warns about paths written for full JSONPath: raco walks object keys split on ".".
+func (r *reporter) jsonPath(label string, field string, path string) {
+	if strings.HasPrefix(path, "$") {
+		r.warnf(RuleAssertion, label, field, "JSON path %q starts with $; raco paths are plain keys such as data.id", path)
+		return
+	}
+	if strings.ContainsAny(path, "[]") {
+		r.warnf(RuleAssertion, label, field, "JSON path %q uses [ ]; raco paths only walk object keys", path)
+	}
+}
+
+func (r *reporter) selector(label string, field string, kind string, query string) {
+	if strings.TrimSpace(query) == "" {
+		return
+	}
+	var err error
+	if kind == string(model.AssertXPath) {
+		err = markup.CheckXPath(query)
+	}
+	if kind == string(model.AssertCSS) {
+		err = markup.CheckCSS(query)
+	}
+	if err != nil {
+		r.errorf(RuleSelector, label, field, "%s %q: %v", kind, query, err)
+	}
+}
+
+func (r *reporter) extractor(label string, field string, e model.Extractor) {
+	if strings.TrimSpace(e.Target) == "" {
+		r.errorf(RuleExtractor, label, field, "extractor has no target variable")
+	}
+
+	switch e.Type {
+	case model.ExtractRegex:
+		if e.Pattern == "" {
+			r.errorf(RuleExtractor, label, field, "regex extractor has no pattern")
+			return
+		}
+		if len(e.Pattern) > maxPattern {
+			r.errorf(RuleRegex, label, field, "regular expression is longer than %d bytes", maxPattern)
+			return
+		}
+		re, err := regexp.Compile(e.Pattern)
+		if err != nil {
+			r.errorf(RuleRegex, label, field, "regular expression %q does not compile: %v", e.Pattern, err)
+			return
+		}
+		if re.NumSubexp() == 0 {
+			r.errorf(RuleExtractor, label, field, "regex extractor pattern %q has no capture group; the first group is extracted", e.Pattern)
+		}
+	case model.ExtractJSONPath, model.ExtractHeader, model.ExtractXPath, model.ExtractCSS:
+		if strings.TrimSpace(e.Source) == "" {
+			r.errorf(RuleExtractor, label, field, "%s extractor has no source", e.Type)
+			return
+		}
+		if e.Type == model.ExtractJSONPath {
+			r.jsonPath(label, field, e.Source)
+		}
+		if e.Type == model.ExtractXPath || e.Type == model.ExtractCSS {
+			r.selector(label, field, string(e.Type), e.Source)
+		}
+	default:
+		r.errorf(RuleExtractor, label, field, "unknown extractor type %q", e.Type)
+	}
+}
+
+// poll checks the polling settings and the assertions that end polling.
+func (r *reporter) poll(label string, req *model.Request) {
+	p := req.Poll
+	if p == nil {
+		return
+	}
+	for i, a := range p.Until {
+		r.assertion(label, fmt.Sprintf("poll.until[%d]", i), a)
+	}
+	if len(p.Conditions(req.Assertions)) == 0 {
+		r.errorf(RulePoll, label, "poll", "polling has no until conditions and the request has no assertions, so it never stops early")
+	}
+	if p.IntervalMs < 0 || p.MaxIntervalMs < 0 || p.MaxAttempts < 0 || p.TimeoutSeconds < 0 {
+		r.errorf(RulePoll, label, "poll", "polling settings cannot be negative")
+	}
+	if p.Backoff != 0 && p.Backoff < 1 {
+		r.warnf(RulePoll, label, "poll.backoff", "backoff %v is below 1 and is ignored", p.Backoff)
+	}
+}
@@ -0,0 +1,135 @@
+// Package check holds the rules of raco lint. Each rule inspects a loaded collection or
+// environment without sending requests and reports what would fail, or likely misbehave, when
+// it runs.
+package check
+
+import (
+	"fmt"
+	"raco/model"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is one problem found by a rule. Collection, Environment, Request and Field locate it;
+// File is the stored file relative to the workspace.
+type Finding struct {
+	Severity    Severity `json:"severity"`
+	Rule        string   `json:"rule"`
+	Message     string   `json:"message"`
+	Collection  string   `json:"collection,omitempty"`
+	Environment string   `json:"environment,omitempty"`
+	Request     string   `json:"request,omitempty"`
+	Field       string   `json:"field,omitempty"`
+	File        string   `json:"file,omitempty"`
+}
+
+// Rule IDs.
+const (
+	RuleLoad             = "load"
+	RuleMethod           = "method"
+	RuleURL              = "url"
+	RuleDuplicate        = "duplicate"
+	RuleAssertion        = "assertion"
+	RuleRegex            = "regex"
+	RuleSelector         = "selector"
+	RuleExtractor        = "extractor"
+	RuleFile             = "file"
+	RuleScript           = "script"
+	RuleCondition        = "condition"
+	RuleDependency       = "depends-on"
+	RuleStage            = "stage"
+	RulePoll             = "poll"
+	RuleVariable         = "undefined-variable"
+	RuleTemplateFunction = "template-function"
+	RuleEnvironment      = "environment"
+	RuleEmpty            = "empty"
+)
+
+// Rule describes a rule for reports such as SARIF.
+type Rule struct {
+	ID          string
+	Description string
+}
+
+// Rules lists every rule in the order reports show them.
+var Rules = []Rule{
+	{RuleLoad, "The collection or environment cannot be read"},
+	{RuleMethod, "Unknown HTTP method"},
+	{RuleURL, "Missing or malformed request URL"},
+	{RuleDuplicate, "Requests or folders share a name or ID"},
+	{RuleAssertion, "Assertion with an unknown type or operator, or a missing field or value"},
+	{RuleRegex, "Regular expression that does not compile"},
+	{RuleSelector, "XPath expression or CSS selector that does not parse"},
+	{RuleExtractor, "Extractor with an unknown type or a missing source or target"},
+	{RuleFile, "File upload whose file does not exist"},
+	{RuleScript, "Pre-request or post-response script with a syntax error"},
+	{RuleCondition, "run_if or skip_if expression with a syntax error"},
+	{RuleDependency, "depends_on refers to an unknown, ambiguous or later request, or forms a cycle"},
+	{RuleStage, "Unknown request stage"},
+	{RulePoll, "Polling settings that cannot work"},
+	{RuleVariable, "{{variable}} defined in no scope the request can see"},
+	{RuleTemplateFunction, "Unknown {{$function}}"},
+	{RuleEnvironment, "Environment that extends an unknown environment or holds an unencrypted secret"},
+	{RuleEmpty, "Collection without requests"},
+}
+
+// reporter collects findings for one collection or environment.
+type reporter struct {
+	collection  string
+	environment string
+	file        string
+	findings    []Finding
+}
+
+func (r *reporter) add(severity Severity, rule string, request string, field string, format string, args ...interface{}) {
+	r.findings = append(r.findings, Finding{
+		Severity:    severity,
+		Rule:        rule,
+		Message:     fmt.Sprintf(format, args...),
+		Collection:  r.collection,
+		Environment: r.environment,
+		Request:     request,
+		Field:       field,
+		File:        r.file,
+	})
+}
+
+func (r *reporter) errorf(rule string, request string, field string, format string, args ...interface{}) {
+	r.add(SeverityError, rule, request, field, format, args...)
+}
+
+func (r *reporter) warnf(rule string, request string, field string, format string, args ...interface{}) {
+	r.add(SeverityWarning, rule, request, field, format, args...)
+}
+
+// requestLabel names req by its folders and name, falling back to its ID.
+func requestLabel(folders []*model.Folder, req *model.Request) string {
+	name := req.Name
+	if name == "" {
+		name = req.ID
+	}
+	if len(folders) == 0 {
+		return name
+	}
+	parts := make([]string, 0, len(folders)+1)
+	for _, f := range folders {
+		parts = append(parts, f.Name)
+	}
+	return strings.Join(append(parts, name), " / ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
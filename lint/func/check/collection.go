@@ -0,0 +1,393 @@
+package check
+
+import (
+	"fmt"
+	"net/url"
+	"raco/model"
+	"raco/script"
+	"raco/util"
+	"strings"
+)
+
+// Collection checks col against the model. file is its stored path relative to the workspace
+// and vars the scopes outside the collection that its requests can use.
+func Collection(col *model.Collection, file string, vars Variables) []Finding {
+	r := &reporter{collection: col.ID, file: file}
+
+	requests := col.AllRequests()
+	if len(requests) == 0 {
+		r.warnf(RuleEmpty, "", "", "collection %q has no requests", col.Name)
+	}
+
+	labels := requestLabels(col, requests)
+	runtime := r.scripts(col, labels)
+	for _, req := range requests {
+		for _, e := range req.Extractors {
+			if e.Target != "" {
+				runtime[e.Target] = true
+			}
+		}
+	}
+
+	r.duplicates(col)
+
+	for _, req := range requests {
+		folders := col.FolderPath(req)
+		label := labels[req]
+		prepared := col.ApplyDefaults(model.ApplyFolders(folders, req))
+
+		r.method(label, prepared.Method)
+		r.url(label, prepared.URL)
+		r.files(label, prepared.Files)
+		for i, a := range req.Assertions {
+			r.assertion(label, fmt.Sprintf("assertions[%d]", i), a)
+		}
+		for i, e := range req.Extractors {
+			r.extractor(label, fmt.Sprintf("extractors[%d]", i), e)
+		}
+		r.poll(label, req)
+		r.condition(label, "run_if", req.RunIf)
+		r.condition(label, "skip_if", req.SkipIf)
+		if req.Stage != "" && req.Stage != model.StageSetup && req.Stage != model.StageTeardown {
+			r.errorf(RuleStage, label, "stage", "unknown stage %q (use %s or %s)", req.Stage, model.StageSetup, model.StageTeardown)
+		}
+		r.variables(col, label, prepared, runtime, vars)
+	}
+
+	r.dependencies(requests, labels)
+	return r.findings
+}
+
+// requestLabels names every request by requestLabel, adding the ID to labels that repeat.
+func requestLabels(col *model.Collection, requests []*model.Request) map[*model.Request]string {
+	labels := make(map[*model.Request]string, len(requests))
+	count := make(map[string]int)
+	for _, req := range requests {
+		labels[req] = requestLabel(col.FolderPath(req), req)
+		count[labels[req]]++
+	}
+	for _, req := range requests {
+		if count[labels[req]] > 1 && req.ID != "" {
+			labels[req] += " (" + req.ID + ")"
+		}
+	}
+	return labels
+}
+
+func (r *reporter) method(label string, method string) {
+	if method == "" {
+		return
+	}
+	if !util.ValidateMethod(method) {
+		r.errorf(RuleMethod, label, "method", "unknown HTTP method %q", method)
+	}
+}
+
+// url checks the request URL after the collection's base URL is applied. Placeholders stand in
+// for any text; a URL that starts with one is only checked for characters a URL cannot hold.
+func (r *reporter) url(label string, raw string) {
+	if strings.TrimSpace(raw) == "" {
+		r.errorf(RuleURL, label, "url", "URL is empty")
+		return
+	}
+
+	stripped := stripPlaceholders(raw)
+	if strings.ContainsAny(stripped, " \t\r\n") {
+		r.errorf(RuleURL, label, "url", "URL %q contains whitespace", raw)
+		return
+	}
+	parsed, err := url.Parse(stripped)
+	if err != nil {
+		r.errorf(RuleURL, label, "url", "URL %q is malformed: %v", raw, err)
+		return
+	}
+	if strings.HasPrefix(strings.TrimSpace(raw), "{{") {
+		return
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		r.errorf(RuleURL, label, "url", "URL %q has no http:// or https:// scheme; set one or a base URL in the collection defaults", raw)
+		return
+	}
+	if parsed.Host == "" {
+		r.errorf(RuleURL, label, "url", "URL %q has no host", raw)
+	}
+}
+
+// stripPlaceholders replaces every {{...}}, nested ones included, with "x".
+func stripPlaceholders(s string) string {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], "{{") {
+			if depth == 0 {
+				b.WriteByte('x')
+			}
+			depth++
+			i++
+			continue
+		}
+		if depth > 0 && strings.HasPrefix(s[i:], "}}") {
+			depth--
+			i++
+			continue
+		}
+		if depth == 0 {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// files checks that every upload names a field and an existing file. Paths are resolved like a
+// run does, relative to the working directory; paths built from variables are not checked.
+func (r *reporter) files(label string, files []model.FileUpload) {
+	for i, f := range files {
+		field := fmt.Sprintf("files[%d]", i)
+		if f.FieldName == "" {
+			r.errorf(RuleFile, label, field, "file upload has no field name")
+		}
+		if f.FilePath == "" {
+			r.errorf(RuleFile, label, field, "file upload has no file path")
+			continue
+		}
+		if strings.Contains(f.FilePath, "{{") {
+			continue
+		}
+		upload := f
+		upload.FieldName = "file"
+		if err := upload.Validate(); err != nil {
+			r.errorf(RuleFile, label, field, "file %s: %v", f.FilePath, err)
+		}
+	}
+}
+
+// scripts checks the syntax of every hook and returns the variables the hooks assign, which
+// later requests may use.
+func (r *reporter) scripts(col *model.Collection, labels map[*model.Request]string) map[string]bool {
+	assigned := make(map[string]bool)
+	hook := func(label string, field string, code string) {
+		if strings.TrimSpace(code) == "" {
+			return
+		}
+		names, err := script.Check(code)
+		if err != nil {
+			r.errorf(RuleScript, label, field, "%v", err)
+			return
+		}
+		for _, name := range names {
+			assigned[name] = true
+		}
+	}
+
+	hook("", "pre_request_script", col.PreRequestScript)
+	hook("", "post_response_script", col.PostResponseScript)
+	walkFolders(col.Folders, nil, func(path []*model.Folder) {
+		folder := path[len(path)-1]
+		label := folderLabel(path)
+		hook(label, "pre_request_script", folder.PreRequestScript)
+		hook(label, "post_response_script", folder.PostResponseScript)
+	})
+	for _, req := range col.AllRequests() {
+		label := labels[req]
+		hook(label, "pre_request_script", req.PreRequestScript)
+		hook(label, "post_response_script", req.PostResponseScript)
+	}
+	return assigned
+}
+
+func (r *reporter) condition(label string, field string, expr string) {
+	if strings.TrimSpace(expr) == "" {
+		return
+	}
+	if err := script.CheckCondition(expr); err != nil {
+		r.errorf(RuleCondition, label, field, "%v", err)
+	}
+}
+
+// duplicates reports requests and folders that share an ID anywhere in the collection, and
+// requests that share a name. Same-named requests in one folder cannot be told apart; elsewhere
+// they only make name references ambiguous.
+func (r *reporter) duplicates(col *model.Collection) {
+	requestIDs := make(map[string]int)
+	names := make(map[string]int)
+	for _, req := range col.AllRequests() {
+		if req.ID != "" {
+			requestIDs[req.ID]++
+		}
+		if req.Name != "" {
+			names[req.Name]++
+		}
+	}
+	for _, id := range sortedKeys(requestIDs) {
+		if requestIDs[id] > 1 {
+			r.errorf(RuleDuplicate, "", "id", "%d requests have the ID %q", requestIDs[id], id)
+		}
+	}
+
+	siblings := func(label string, requests []*model.Request) {
+		seen := make(map[string]int)
+		for _, req := range requests {
+			if req != nil && req.Name != "" {
+				seen[req.Name]++
+			}
+		}
+		for _, name := range sortedKeys(seen) {
+			if seen[name] > 1 {
+				names[name] = 0
+				where := "at the top level"
+				if label != "" {
+					where = "in folder " + label
+				}
+				r.errorf(RuleDuplicate, "", "name", "%d requests %s are named %q", seen[name], where, name)
+			}
+		}
+	}
+	siblings("", col.Requests)
+
+	folderIDs := make(map[string]int)
+	walkFolders(col.Folders, nil, func(path []*model.Folder) {
+		folder := path[len(path)-1]
+		if folder.ID != "" {
+			folderIDs[folder.ID]++
+		}
+		siblings(folderLabel(path), folder.Requests)
+	})
+	for _, id := range sortedKeys(folderIDs) {
+		if folderIDs[id] > 1 {
+			r.errorf(RuleDuplicate, "", "id", "%d folders have the ID %q", folderIDs[id], id)
+		}
+	}
+
+	for _, name := range sortedKeys(names) {
+		if names[name] > 1 {
+			r.warnf(RuleDuplicate, "", "name", "%d requests in different folders are named %q; refer to them by ID", names[name], name)
+		}
+	}
+}
+
+// dependencies mirrors how a run resolves depends_on: IDs first, then unique names, never a
+// later stage, and without cycles.
+func (r *reporter) dependencies(requests []*model.Request, labels map[*model.Request]string) {
+	deps := make(map[*model.Request][]*model.Request)
+	for _, req := range requests {
+		for i, ref := range req.DependsOn {
+			field := fmt.Sprintf("depends_on[%d]", i)
+			dep, err := findRequest(requests, ref)
+			if err != nil {
+				r.errorf(RuleDependency, labels[req], field, "%v", err)
+				continue
+			}
+			if dep == req {
+				r.errorf(RuleDependency, labels[req], field, "request depends on itself")
+				continue
+			}
+			if stageRank(dep) > stageRank(req) {
+				r.errorf(RuleDependency, labels[req], field, "cannot depend on later-stage request %q", labels[dep])
+				continue
+			}
+			deps[req] = append(deps[req], dep)
+		}
+	}
+
+	// Depth-first search; a request met again while still on the path closes a cycle.
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[*model.Request]int)
+	var path []*model.Request
+	var visit func(req *model.Request)
+	visit = func(req *model.Request) {
+		state[req] = onPath
+		path = append(path, req)
+		for _, dep := range deps[req] {
+			if state[dep] == onPath {
+				names := make([]string, 0)
+				start := 0
+				for i, p := range path {
+					if p == dep {
+						start = i
+					}
+				}
+				for _, p := range path[start:] {
+					names = append(names, labels[p])
+				}
+				names = append(names, labels[dep])
+				r.errorf(RuleDependency, labels[req], "depends_on", "dependency cycle: %s", strings.Join(names, " -> "))
+				continue
+			}
+			if state[dep] == unvisited {
+				visit(dep)
+			}
+		}
+		path = path[:len(path)-1]
+		state[req] = done
+	}
+	for _, req := range requests {
+		if state[req] == unvisited {
+			visit(req)
+		}
+	}
+}
+
+func findRequest(requests []*model.Request, ref string) (*model.Request, error) {
+	for _, req := range requests {
+		if req.ID != "" && req.ID == ref {
+			return req, nil
+		}
+	}
+
+	var found *model.Request
+	for _, req := range requests {
+		if req.Name != ref {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("request name %q is ambiguous", ref)
+		}
+		found = req
+	}
+	if found == nil {
+		return nil, fmt.Errorf("unknown request %q", ref)
+	}
+	return found, nil
+}
+
+func stageRank(req *model.Request) int {
+	switch req.Stage {
+	case model.StageSetup:
+		return 0
+	case model.StageTeardown:
+		return 2
+	}
+	return 1
+}
+
+// walkFolders calls visit with the path to every folder, outermost first.
+func walkFolders(folders []*model.Folder, trail []*model.Folder, visit func(path []*model.Folder)) {
+	if len(trail) >= maxFolderDepth {
+		return
+	}
+	for _, folder := range folders {
+		if folder == nil {
+			continue
+		}
+		path := append(append([]*model.Folder(nil), trail...), folder)
+		visit(path)
+		walkFolders(folder.Folders, path, visit)
+	}
+}
+
+// maxFolderDepth matches the depth the model walks.
+const maxFolderDepth = 32
+
+func folderLabel(path []*model.Folder) string {
+	names := make([]string, 0, len(path))
+	for _, f := range path {
+		names = append(names, f.Name)
+	}
+	return strings.Join(names, " / ")
+}
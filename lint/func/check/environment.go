@@ -0,0 +1,48 @@
+package check
+
+import (
+	"raco/model"
+	"raco/secret"
+	"raco/util"
+	"strings"
+)
+
+// Environment checks a stored environment as written, before it is merged with the
+// environments it extends. file is its path relative to the workspace.
+func Environment(env *model.Environment, file string) []Finding {
+	r := &reporter{environment: env.Name, file: file}
+
+	for _, key := range sortedKeys(env.Secrets) {
+		if !secret.IsEncrypted(env.Secrets[key]) {
+			r.errorf(RuleEnvironment, "", "secrets."+key, "secret %s is stored unencrypted; set it again with raco env set %s --secret %s=<value>", key, env.Name, key)
+		}
+	}
+
+	for _, key := range sortedKeys(env.Variables) {
+		for _, name := range util.Placeholders(env.Variables[key]) {
+			if !strings.HasPrefix(name, "$") {
+				continue
+			}
+			fields := strings.Fields(strings.TrimPrefix(name, "$"))
+			if len(fields) == 0 || !util.IsTemplateFunction(fields[0]) {
+				r.errorf(RuleTemplateFunction, "", "variables."+key, "unknown template function {{%s}}", name)
+			}
+		}
+	}
+	return r.findings
+}
+
+// EnvironmentError reports an environment that cannot be merged with the environments it
+// extends.
+func EnvironmentError(name string, file string, err error) Finding {
+	r := &reporter{environment: name, file: file}
+	r.errorf(RuleEnvironment, "", "", "%v", err)
+	return r.findings[0]
+}
+
+// LoadError reports a collection or environment that cannot be read.
+func LoadError(collection string, environment string, file string, err error) Finding {
+	r := &reporter{collection: collection, environment: environment, file: file}
+	r.errorf(RuleLoad, "", "", "%v", err)
+	return r.findings[0]
+}
@@ -0,0 +1,112 @@
+package check
+
+import (
+	"raco/model"
+	"raco/util"
+	"strings"
+)
+
+// NoEnvironment names the scope used when the workspace has no environments.
+const NoEnvironment = "(none)"
+
+// Variables are the scopes outside a collection that a run can draw on.
+type Variables struct {
+	Globals map[string]string
+	// Environments maps each environment a run may select to its merged variables.
+	Environments map[string]map[string]string
+	// Data lists the columns of the data file the run will use.
+	Data []string
+}
+
+// variables reports placeholders in the rendered fields of req that no scope defines, for each
+// environment the collection may run with. Values of variables are followed, since they are
+// rendered too. runtime holds the names extractors and scripts may set during the run.
+func (r *reporter) variables(col *model.Collection, label string, prepared *model.Request, runtime map[string]bool, vars Variables) {
+	texts := make([]string, 0, 2+len(prepared.Headers)+len(prepared.Query)+2*len(prepared.Files))
+	texts = append(texts, prepared.URL, prepared.Body)
+	for _, k := range sortedKeys(prepared.Headers) {
+		texts = append(texts, prepared.Headers[k])
+	}
+	for _, k := range sortedKeys(prepared.Query) {
+		texts = append(texts, prepared.Query[k])
+	}
+	for _, f := range prepared.Files {
+		texts = append(texts, f.FilePath, f.FieldName)
+	}
+
+	data := make(map[string]bool, len(vars.Data))
+	for _, column := range vars.Data {
+		data[column] = true
+	}
+
+	environments := vars.Environments
+	if len(environments) == 0 {
+		environments = map[string]map[string]string{NoEnvironment: nil}
+	}
+	names := sortedKeys(environments)
+
+	missing := make(map[string][]string)
+	functions := make(map[string]bool)
+	for _, env := range names {
+		layers := []map[string]string{prepared.Variables, environments[env], col.Variables, vars.Globals}
+		lookup := func(name string) (string, bool) {
+			for _, layer := range layers {
+				if value, ok := layer[name]; ok {
+					return value, true
+				}
+			}
+			return "", false
+		}
+
+		seen := make(map[string]bool)
+		queue := append([]string(nil), texts...)
+		for len(queue) > 0 {
+			text := queue[0]
+			queue = queue[1:]
+			for _, name := range util.Placeholders(text) {
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+
+				if strings.HasPrefix(name, "$") {
+					functions[name] = true
+					continue
+				}
+				if runtime[name] || data[name] {
+					continue
+				}
+				value, ok := lookup(name)
+				if !ok {
+					missing[name] = append(missing[name], env)
+					continue
+				}
+				queue = append(queue, value)
+			}
+		}
+	}
+
+	for _, call := range sortedKeys(functions) {
+		fields := strings.Fields(strings.TrimPrefix(call, "$"))
+		if len(fields) == 0 || !util.IsTemplateFunction(fields[0]) {
+			r.errorf(RuleTemplateFunction, label, "", "unknown template function {{%s}}", call)
+		}
+	}
+
+	for _, name := range sortedKeys(missing) {
+		envs := missing[name]
+		if len(envs) < len(names) {
+			r.warnf(RuleVariable, label, "", "{{%s}} is not defined in environment %s", name, strings.Join(envs, ", "))
+			continue
+		}
+		where := "in any environment, the collection, globals or a data file"
+		if len(vars.Environments) == 1 {
+			where = "in environment " + names[0] + ", the collection, globals or a data file"
+		}
+		if col.LenientVariables {
+			r.warnf(RuleVariable, label, "", "{{%s}} is not defined %s", name, where)
+			continue
+		}
+		r.errorf(RuleVariable, label, "", "{{%s}} is not defined %s", name, where)
+	}
+}
@@ -0,0 +1,147 @@
+// Package sarif writes lint findings as a SARIF 2.1.0 log, the format code scanning services
+// such as GitHub's read.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"raco/lint/func/check"
+	"strings"
+)
+
+const (
+	schemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	version   = "2.1.0"
+)
+
+type log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []run  `json:"runs"`
+}
+
+type run struct {
+	Tool    tool     `json:"tool"`
+	Results []result `json:"results"`
+}
+
+type tool struct {
+	Driver driver `json:"driver"`
+}
+
+type driver struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Rules   []rule `json:"rules"`
+}
+
+type rule struct {
+	ID               string  `json:"id"`
+	ShortDescription message `json:"shortDescription"`
+}
+
+type message struct {
+	Text string `json:"text"`
+}
+
+type result struct {
+	RuleID    string     `json:"ruleId"`
+	RuleIndex int        `json:"ruleIndex"`
+	Level     string     `json:"level"`
+	Message   message    `json:"message"`
+	Locations []location `json:"locations,omitempty"`
+}
+
+type location struct {
+	PhysicalLocation *physicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []logicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type physicalLocation struct {
+	ArtifactLocation artifactLocation `json:"artifactLocation"`
+}
+
+type artifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type logicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind,omitempty"`
+}
+
+// Write writes findings as one SARIF run of the tool named name. File paths of findings are
+// used as artifact URIs as they are; callers make them relative to the repository root.
+func Write(w io.Writer, findings []check.Finding, name string, toolVersion string) error {
+	index := make(map[string]int, len(check.Rules))
+	rules := make([]rule, 0, len(check.Rules))
+	for i, r := range check.Rules {
+		index[r.ID] = i
+		rules = append(rules, rule{ID: r.ID, ShortDescription: message{Text: r.Description}})
+	}
+
+	results := make([]result, 0, len(findings))
+	for _, f := range findings {
+		res := result{
+			RuleID:    f.Rule,
+			RuleIndex: index[f.Rule],
+			Level:     level(f.Severity),
+			Message:   message{Text: f.Message},
+		}
+
+		loc := location{}
+		if f.File != "" {
+			loc.PhysicalLocation = &physicalLocation{ArtifactLocation: artifactLocation{URI: toURI(f.File)}}
+		}
+		if qualified := qualifiedName(f); qualified != "" {
+			loc.LogicalLocations = []logicalLocation{{FullyQualifiedName: qualified, Kind: "member"}}
+		}
+		if loc.PhysicalLocation != nil || loc.LogicalLocations != nil {
+			res.Locations = []location{loc}
+		}
+		results = append(results, res)
+	}
+
+	doc := log{
+		Schema:  schemaURI,
+		Version: version,
+		Runs: []run{{
+			Tool:    tool{Driver: driver{Name: name, Version: toolVersion, Rules: rules}},
+			Results: results,
+		}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func level(severity check.Severity) string {
+	if severity == check.SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// qualifiedName locates a finding inside its file, e.g. "my-api/Users / Create user/url".
+func qualifiedName(f check.Finding) string {
+	parts := make([]string, 0, 3)
+	if f.Collection != "" {
+		parts = append(parts, f.Collection)
+	}
+	if f.Environment != "" {
+		parts = append(parts, f.Environment)
+	}
+	if f.Request != "" {
+		parts = append(parts, f.Request)
+	}
+	if f.Field != "" {
+		parts = append(parts, f.Field)
+	}
+	return strings.Join(parts, "/")
+}
+
+// toURI turns a relative file path into a URI reference with forward slashes.
+func toURI(path string) string {
+	return strings.ReplaceAll(path, "\\", "/")
+}
@@ -0,0 +1,172 @@
+// Package lint checks stored collections and environments without sending requests: the rules
+// in lint/func/check report what a run would reject or could not resolve, and
+// lint/func/sarif writes the findings for code scanning.
+package lint
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"raco/lint/func/check"
+	"raco/lint/func/sarif"
+	"raco/model"
+	"raco/storage"
+)
+
+type Finding = check.Finding
+type Severity = check.Severity
+
+const (
+	SeverityError   = check.SeverityError
+	SeverityWarning = check.SeverityWarning
+)
+
+// Options select what Workspace checks.
+type Options struct {
+	// Collection limits the check to one collection ID; empty checks every collection.
+	Collection string
+	// Environment is the environment the collections will run with; empty checks them
+	// against every environment.
+	Environment string
+	// DataColumns are variables a data file provides to every iteration.
+	DataColumns []string
+}
+
+// Report holds the findings of Workspace in the order they were found.
+type Report struct {
+	Collections  []string  `json:"collections"`
+	Environments []string  `json:"environments"`
+	Findings     []Finding `json:"findings"`
+	Errors       int       `json:"errors"`
+	Warnings     int       `json:"warnings"`
+}
+
+// Workspace checks the collections and environments of store. It fails only when the
+// selected collection or environment does not exist or the workspace cannot be listed; every
+// other problem is a finding.
+func Workspace(store *storage.Storage, opts Options) (*Report, error) {
+	names, err := store.EnvironmentNames()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Environment != "" && !contains(names, opts.Environment) {
+		return nil, fmt.Errorf("environment %s not found", opts.Environment)
+	}
+
+	ids, err := store.CollectionIDs()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Collection != "" {
+		if !contains(ids, opts.Collection) {
+			return nil, fmt.Errorf("collection %s not found", opts.Collection)
+		}
+		ids = []string{opts.Collection}
+	}
+
+	report := &Report{Collections: ids, Environments: make([]string, 0, len(names)), Findings: make([]Finding, 0)}
+	vars := check.Variables{Environments: make(map[string]map[string]string), Data: opts.DataColumns}
+
+	for _, name := range names {
+		if name == model.GlobalsEnvironment {
+			continue
+		}
+		if opts.Environment != "" && name != opts.Environment {
+			continue
+		}
+		report.Environments = append(report.Environments, name)
+		file := environmentFile(name)
+
+		raw, err := store.LoadEnvironment(name)
+		if err != nil {
+			report.add(check.LoadError("", name, file, err))
+			continue
+		}
+		report.add(check.Environment(raw, file)...)
+
+		merged, _, err := store.MergeEnvironment(name)
+		if err != nil {
+			report.add(check.EnvironmentError(name, file, err))
+			continue
+		}
+		vars.Environments[name] = merged.Variables
+	}
+
+	globals, err := store.LoadGlobals()
+	if err != nil {
+		report.add(check.LoadError("", model.GlobalsEnvironment, environmentFile(model.GlobalsEnvironment), err))
+	}
+	if err == nil {
+		if contains(names, model.GlobalsEnvironment) {
+			report.add(check.Environment(globals, environmentFile(model.GlobalsEnvironment))...)
+		}
+		vars.Globals = globals.Variables
+	}
+
+	for _, id := range ids {
+		file := collectionFile(store, id)
+		col, err := store.LoadCollection(id)
+		if err != nil {
+			report.add(check.LoadError(id, "", file, err))
+			continue
+		}
+		report.add(check.Collection(col, file, vars)...)
+	}
+	return report, nil
+}
+
+// Failed reports whether the report has errors, or with strict also warnings.
+func (r *Report) Failed(strict bool) bool {
+	return r.Errors > 0 || (strict && r.Warnings > 0)
+}
+
+// WriteSARIF writes the findings as a SARIF 2.1.0 log. base is the directory the file paths
+// of findings are relative to, and root the one they are made relative to in the log, usually
+// the repository root.
+func (r *Report) WriteSARIF(w io.Writer, base string, root string, version string) error {
+	findings := make([]Finding, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		if f.File != "" {
+			path := filepath.Join(base, f.File)
+			if rel, err := filepath.Rel(root, path); err == nil {
+				path = rel
+			}
+			f.File = filepath.ToSlash(path)
+		}
+		findings = append(findings, f)
+	}
+	return sarif.Write(w, findings, "raco", version)
+}
+
+func (r *Report) add(findings ...Finding) {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			r.Errors++
+		}
+		if f.Severity == SeverityWarning {
+			r.Warnings++
+		}
+		r.Findings = append(r.Findings, f)
+	}
+}
+
+func collectionFile(store *storage.Storage, id string) string {
+	layout, format, err := store.CollectionLayoutOf(id)
+	if err == nil && layout == storage.LayoutDir {
+		return filepath.Join("collections", id, "collection."+format)
+	}
+	return filepath.Join("collections", id+".json")
+}
+
+func environmentFile(name string) string {
+	return filepath.Join("environments", name+".yaml")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
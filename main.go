@@ -3,27 +3,26 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"raco/cli"
 	"raco/ui"
+	"raco/workspace"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	if len(os.Args) > 1 {
+	explicit, args := workspace.TakeFlag(os.Args[1:])
+	if len(args) > 0 {
 		os.Exit(cli.Run(os.Args[1:]))
 	}
 
-	homeDir, err := os.UserHomeDir()
+	ws, err := workspace.Discover(explicit)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	storagePath := filepath.Join(homeDir, ".raco")
-
-	model := ui.NewModel(storagePath)
+	model := ui.NewModel(ws)
 	program := tea.NewProgram(&model, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
 	if _, err := program.Run(); err != nil {
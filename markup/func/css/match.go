@@ -0,0 +1,317 @@
+package css
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const maxDocumentSize = 10 * 1024 * 1024
+
+// Select returns the normalised text of every element in body matching selector, in document
+// order. A trailing "@name" (e.g. "a.next@href" or "meta[name=description] @content") returns
+// that attribute instead, skipping elements that do not have it.
+func Select(body, selector string) ([]string, error) {
+	if len(body) > maxDocumentSize {
+		return nil, errors.New("document too large (max 10MB)")
+	}
+
+	sel, attr := splitAttribute(selector)
+	group, err := parseGroup(sel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	root, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTML: %w", err)
+	}
+
+	values := make([]string, 0)
+	walk(root, func(n *html.Node) {
+		if !matchesGroup(n, group) {
+			return
+		}
+		if attr == "" {
+			values = append(values, textContent(n))
+			return
+		}
+		if v, ok := attribute(n, attr); ok {
+			values = append(values, v)
+		}
+	})
+	return values, nil
+}
+
+// Compile checks that selector, with an optional trailing @attr, parses.
+func Compile(selector string) error {
+	sel, _ := splitAttribute(selector)
+	if _, err := parseGroup(sel); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	return nil
+}
+
+// splitAttribute separates a trailing @attr from the selector, ignoring @ inside brackets or quotes.
+func splitAttribute(selector string) (string, string) {
+	depth := 0
+	var quote byte
+	at := -1
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == '@' && depth == 0:
+			at = i
+		}
+	}
+	if at < 0 {
+		return selector, ""
+	}
+	return strings.TrimSpace(selector[:at]), strings.ToLower(strings.TrimSpace(selector[at+1:]))
+}
+
+func walk(n *html.Node, visit func(*html.Node)) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			visit(c)
+		}
+		walk(c, visit)
+	}
+}
+
+func matchesGroup(n *html.Node, group []*complexSelector) bool {
+	for _, sel := range group {
+		if matchesComplex(n, sel, len(sel.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesComplex matches right to left: parts[idx] against n, then the remaining parts against
+// the ancestors or preceding siblings the combinator points at.
+func matchesComplex(n *html.Node, sel *complexSelector, idx int) bool {
+	if !matchesCompound(n, sel.parts[idx]) {
+		return false
+	}
+	if idx == 0 {
+		return true
+	}
+
+	switch sel.combinators[idx-1] {
+	case '>':
+		parent := n.Parent
+		return parent != nil && parent.Type == html.ElementNode && matchesComplex(parent, sel, idx-1)
+	case ' ':
+		for p := n.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+			if matchesComplex(p, sel, idx-1) {
+				return true
+			}
+		}
+		return false
+	case '+':
+		prev := previousElement(n)
+		return prev != nil && matchesComplex(prev, sel, idx-1)
+	case '~':
+		for prev := previousElement(n); prev != nil; prev = previousElement(prev) {
+			if matchesComplex(prev, sel, idx-1) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func matchesCompound(n *html.Node, c compoundSelector) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != n.Data {
+		return false
+	}
+
+	for _, id := range c.ids {
+		if v, ok := attribute(n, "id"); !ok || v != id {
+			return false
+		}
+	}
+
+	if len(c.classes) > 0 {
+		classes := strings.Fields(attributeValue(n, "class"))
+		for _, class := range c.classes {
+			if !containsString(classes, class) {
+				return false
+			}
+		}
+	}
+
+	for _, a := range c.attrs {
+		if !matchesAttr(n, a) {
+			return false
+		}
+	}
+
+	for _, p := range c.pseudos {
+		if !matchesPseudo(n, p) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAttr(n *html.Node, a attrSelector) bool {
+	value, ok := attribute(n, a.name)
+	if !ok {
+		return false
+	}
+	if a.op == "" {
+		return true
+	}
+
+	expected := a.value
+	if a.fold {
+		value = strings.ToLower(value)
+		expected = strings.ToLower(expected)
+	}
+
+	switch a.op {
+	case "=":
+		return value == expected
+	case "~=":
+		return containsString(strings.Fields(value), expected)
+	case "|=":
+		return value == expected || strings.HasPrefix(value, expected+"-")
+	case "^=":
+		return expected != "" && strings.HasPrefix(value, expected)
+	case "$=":
+		return expected != "" && strings.HasSuffix(value, expected)
+	case "*=":
+		return expected != "" && strings.Contains(value, expected)
+	}
+	return false
+}
+
+func matchesPseudo(n *html.Node, p pseudoSelector) bool {
+	switch p.name {
+	case "first-child":
+		return previousElement(n) == nil
+	case "last-child":
+		return nextElement(n) == nil
+	case "only-child":
+		return previousElement(n) == nil && nextElement(n) == nil
+	case "first-of-type":
+		return siblingIndex(n, true, false) == 1
+	case "last-of-type":
+		return siblingIndex(n, true, true) == 1
+	case "nth-child":
+		return nthMatches(p.a, p.b, siblingIndex(n, false, false))
+	case "nth-last-child":
+		return nthMatches(p.a, p.b, siblingIndex(n, false, true))
+	case "nth-of-type":
+		return nthMatches(p.a, p.b, siblingIndex(n, true, false))
+	case "nth-last-of-type":
+		return nthMatches(p.a, p.b, siblingIndex(n, true, true))
+	case "empty":
+		return n.FirstChild == nil
+	case "root":
+		return n.Parent != nil && n.Parent.Type == html.DocumentNode
+	case "not":
+		return !matchesGroup(n, p.not)
+	case "contains":
+		return strings.Contains(textContent(n), p.text)
+	}
+	return false
+}
+
+// siblingIndex is the 1-based position of n among its element siblings, optionally only those
+// with the same tag and optionally counted from the end.
+func siblingIndex(n *html.Node, sameType bool, fromEnd bool) int {
+	idx := 1
+	step := previousElement
+	if fromEnd {
+		step = nextElement
+	}
+	for s := step(n); s != nil; s = step(s) {
+		if !sameType || s.Data == n.Data {
+			idx++
+		}
+	}
+	return idx
+}
+
+func nthMatches(a, b, idx int) bool {
+	if a == 0 {
+		return idx == b
+	}
+	diff := idx - b
+	return diff%a == 0 && diff/a >= 0
+}
+
+func previousElement(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func nextElement(n *html.Node) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func attribute(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attributeValue(n *html.Node, name string) string {
+	v, _ := attribute(n, name)
+	return v
+}
+
+// textContent joins the text below n, skipping script and style, with whitespace collapsed.
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				b.WriteString(c.Data)
+			}
+			if c.Type == html.ElementNode && c.Data != "script" && c.Data != "style" {
+				collect(c)
+			}
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,366 @@
+package css
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const maxSelectorLength = 4096
+
+type attrSelector struct {
+	name  string
+	op    string
+	value string
+	fold  bool
+}
+
+type pseudoSelector struct {
+	name string
+	a, b int
+	not  []*complexSelector
+	text string
+}
+
+type compoundSelector struct {
+	tag     string
+	ids     []string
+	classes []string
+	attrs   []attrSelector
+	pseudos []pseudoSelector
+}
+
+// complexSelector is a chain of compounds joined by combinators; combinators[i] sits between
+// parts[i] and parts[i+1] and is one of ' ', '>', '+' or '~'.
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte
+}
+
+type selectorParser struct {
+	src string
+	pos int
+}
+
+func parseGroup(src string) ([]*complexSelector, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, errors.New("empty selector")
+	}
+	if len(src) > maxSelectorLength {
+		return nil, errors.New("selector too long (max 4KB)")
+	}
+
+	p := &selectorParser{src: src}
+	group, err := p.parseGroup()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q at %d", p.src[p.pos], p.pos)
+	}
+	return group, nil
+}
+
+func (p *selectorParser) parseGroup() ([]*complexSelector, error) {
+	group := make([]*complexSelector, 0, 1)
+	for {
+		p.skipSpace()
+		sel, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, sel)
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != ',' {
+			return group, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *selectorParser) parseComplex() (*complexSelector, error) {
+	sel := &complexSelector{}
+	first, err := p.parseCompound()
+	if err != nil {
+		return nil, err
+	}
+	sel.parts = append(sel.parts, first)
+
+	for {
+		sawSpace := p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] == ',' || p.src[p.pos] == ')' {
+			return sel, nil
+		}
+
+		combinator := byte(' ')
+		if c := p.src[p.pos]; c == '>' || c == '+' || c == '~' {
+			combinator = c
+			p.pos++
+			p.skipSpace()
+		}
+		if combinator == ' ' && !sawSpace {
+			return nil, fmt.Errorf("unexpected %q at %d", p.src[p.pos], p.pos)
+		}
+
+		next, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		sel.combinators = append(sel.combinators, combinator)
+		sel.parts = append(sel.parts, next)
+	}
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var c compoundSelector
+	start := p.pos
+
+	if p.pos < len(p.src) && p.src[p.pos] == '*' {
+		p.pos++
+		c.tag = "*"
+	}
+	if c.tag == "" {
+		if name := p.parseIdent(); name != "" {
+			c.tag = strings.ToLower(name)
+		}
+	}
+
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '#':
+			p.pos++
+			id := p.parseIdent()
+			if id == "" {
+				return c, errors.New("expected id after #")
+			}
+			c.ids = append(c.ids, id)
+		case '.':
+			p.pos++
+			class := p.parseIdent()
+			if class == "" {
+				return c, errors.New("expected class name after .")
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			attr, err := p.parseAttr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, attr)
+		case ':':
+			pseudo, err := p.parsePseudo()
+			if err != nil {
+				return c, err
+			}
+			c.pseudos = append(c.pseudos, pseudo)
+		default:
+			if p.pos == start {
+				return c, fmt.Errorf("unexpected %q at %d", p.src[p.pos], p.pos)
+			}
+			return c, nil
+		}
+	}
+
+	if p.pos == start {
+		return c, errors.New("expected a selector")
+	}
+	return c, nil
+}
+
+func (p *selectorParser) parseAttr() (attrSelector, error) {
+	p.pos++
+	p.skipSpace()
+	attr := attrSelector{name: strings.ToLower(p.parseIdent())}
+	if attr.name == "" {
+		return attr, errors.New("expected attribute name")
+	}
+	p.skipSpace()
+
+	if p.pos < len(p.src) && p.src[p.pos] == ']' {
+		p.pos++
+		return attr, nil
+	}
+
+	for _, op := range []string{"~=", "|=", "^=", "$=", "*=", "="} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			attr.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if attr.op == "" {
+		return attr, errors.New("invalid attribute operator")
+	}
+
+	p.skipSpace()
+	value, err := p.parseValue()
+	if err != nil {
+		return attr, err
+	}
+	attr.value = value
+
+	p.skipSpace()
+	if p.pos < len(p.src) && (p.src[p.pos] == 'i' || p.src[p.pos] == 'I') {
+		attr.fold = true
+		p.pos++
+		p.skipSpace()
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+		return attr, errors.New("expected ]")
+	}
+	p.pos++
+	return attr, nil
+}
+
+func (p *selectorParser) parsePseudo() (pseudoSelector, error) {
+	p.pos++
+	for p.pos < len(p.src) && p.src[p.pos] == ':' {
+		p.pos++
+	}
+	pseudo := pseudoSelector{name: strings.ToLower(p.parseIdent())}
+
+	switch pseudo.name {
+	case "first-child", "last-child", "only-child", "first-of-type", "last-of-type", "empty", "root":
+		return pseudo, nil
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		arg, err := p.parseParenthesized()
+		if err != nil {
+			return pseudo, err
+		}
+		pseudo.a, pseudo.b, err = parseNth(arg)
+		return pseudo, err
+	case "not":
+		if p.pos >= len(p.src) || p.src[p.pos] != '(' {
+			return pseudo, errors.New("expected ( after :not")
+		}
+		p.pos++
+		group, err := p.parseGroup()
+		if err != nil {
+			return pseudo, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != ')' {
+			return pseudo, errors.New("expected ) after :not(")
+		}
+		p.pos++
+		pseudo.not = group
+		return pseudo, nil
+	case "contains":
+		arg, err := p.parseParenthesized()
+		if err != nil {
+			return pseudo, err
+		}
+		pseudo.text = unquote(strings.TrimSpace(arg))
+		return pseudo, nil
+	}
+
+	return pseudo, fmt.Errorf("unsupported pseudo-class :%s", pseudo.name)
+}
+
+func (p *selectorParser) parseParenthesized() (string, error) {
+	if p.pos >= len(p.src) || p.src[p.pos] != '(' {
+		return "", errors.New("expected (")
+	}
+	end := strings.IndexByte(p.src[p.pos:], ')')
+	if end < 0 {
+		return "", errors.New("expected )")
+	}
+	arg := p.src[p.pos+1 : p.pos+end]
+	p.pos += end + 1
+	return arg, nil
+}
+
+// parseNth parses an+b, odd, even or a plain integer.
+func parseNth(arg string) (int, int, error) {
+	arg = strings.ToLower(strings.ReplaceAll(arg, " ", ""))
+	switch arg {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+
+	idx := strings.IndexByte(arg, 'n')
+	if idx < 0 {
+		b, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", arg)
+		}
+		return 0, b, nil
+	}
+
+	a := 1
+	switch arg[:idx] {
+	case "", "+":
+	case "-":
+		a = -1
+	default:
+		n, err := strconv.Atoi(arg[:idx])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", arg)
+		}
+		a = n
+	}
+
+	b := 0
+	if rest := arg[idx+1:]; rest != "" {
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", arg)
+		}
+		b = n
+	}
+	return a, b, nil
+}
+
+func (p *selectorParser) parseValue() (string, error) {
+	if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+		quote := p.src[p.pos]
+		end := strings.IndexByte(p.src[p.pos+1:], quote)
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		value := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, nil
+	}
+	value := p.parseIdent()
+	if value == "" {
+		return "", errors.New("expected attribute value")
+	}
+	return value, nil
+}
+
+func (p *selectorParser) parseIdent() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c >= 0x80 {
+			p.pos++
+			continue
+		}
+		if c == '\\' && p.pos+1 < len(p.src) {
+			p.pos += 2
+			continue
+		}
+		break
+	}
+	return strings.ReplaceAll(p.src[start:p.pos], "\\", "")
+}
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,163 @@
+package xpath
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+const maxDocumentSize = 10 * 1024 * 1024
+
+type nodeKind int
+
+const (
+	documentNode nodeKind = iota
+	elementNode
+	attributeNode
+	textNode
+	commentNode
+)
+
+type node struct {
+	kind     nodeKind
+	space    string
+	prefix   string
+	local    string
+	data     string
+	parent   *node
+	children []*node
+	attrs    []*node
+	// ns holds the namespace declarations made on this element; "" is the default namespace.
+	ns    map[string]string
+	order int
+}
+
+type document struct {
+	root *node
+	// declared maps the prefixes declared in the document to their namespace URIs.
+	declared map[string]string
+}
+
+func parseDocument(body string) (*document, error) {
+	if len(body) > maxDocumentSize {
+		return nil, errors.New("document too large (max 10MB)")
+	}
+
+	doc := &document{
+		root:     &node{kind: documentNode},
+		declared: make(map[string]string),
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	current := doc.root
+	order := 1
+	sawElement := false
+
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &node{kind: elementNode, prefix: t.Name.Space, local: t.Name.Local, parent: current, order: order}
+			order++
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" {
+					el.declare(a.Name.Local, a.Value)
+					doc.declared[a.Name.Local] = a.Value
+					continue
+				}
+				if a.Name.Space == "" && a.Name.Local == "xmlns" {
+					el.declare("", a.Value)
+					continue
+				}
+				el.attrs = append(el.attrs, &node{kind: attributeNode, prefix: a.Name.Space, local: a.Name.Local, data: a.Value, parent: el, order: order})
+				order++
+			}
+			el.space = lookupNamespace(el, el.prefix)
+			for _, a := range el.attrs {
+				if a.prefix != "" {
+					a.space = lookupNamespace(el, a.prefix)
+				}
+			}
+			current.children = append(current.children, el)
+			current = el
+			sawElement = true
+		case xml.EndElement:
+			if current.parent != nil {
+				current = current.parent
+			}
+		case xml.CharData:
+			text := string(t)
+			if strings.TrimSpace(text) == "" || current.kind == documentNode {
+				continue
+			}
+			current.children = append(current.children, &node{kind: textNode, data: text, parent: current, order: order})
+			order++
+		case xml.Comment:
+			current.children = append(current.children, &node{kind: commentNode, data: string(t), parent: current, order: order})
+			order++
+		}
+	}
+
+	if !sawElement {
+		return nil, errors.New("no XML element found")
+	}
+
+	return doc, nil
+}
+
+func (n *node) declare(prefix, uri string) {
+	if n.ns == nil {
+		n.ns = make(map[string]string)
+	}
+	n.ns[prefix] = uri
+}
+
+// lookupNamespace resolves prefix against the declarations in scope at n. RawToken is used
+// for parsing so prefixes stay available for name(); this does the resolution instead.
+func lookupNamespace(n *node, prefix string) string {
+	for ; n != nil && n.kind == elementNode; n = n.parent {
+		if uri, ok := n.ns[prefix]; ok {
+			return uri
+		}
+	}
+	return ""
+}
+
+func (n *node) name() string {
+	if n.prefix != "" {
+		return n.prefix + ":" + n.local
+	}
+	return n.local
+}
+
+// stringValue is the XPath string-value: descendant text for elements and documents, data otherwise.
+func (n *node) stringValue() string {
+	if n.kind != elementNode && n.kind != documentNode {
+		return n.data
+	}
+	var b strings.Builder
+	n.appendText(&b)
+	return b.String()
+}
+
+func (n *node) appendText(b *strings.Builder) {
+	for _, c := range n.children {
+		if c.kind == textNode {
+			b.WriteString(c.data)
+		}
+		if c.kind == elementNode {
+			c.appendText(b)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+package xpath
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxNodeVisits bounds the work a single expression may do so pathological paths cannot hang a run.
+const maxNodeVisits = 5000000
+
+type context struct {
+	node     *node
+	position int
+	size     int
+}
+
+type evaluator struct {
+	doc        *document
+	namespaces map[string]string
+	visits     int
+}
+
+// Evaluate runs expr against the XML document in body. Node-sets are returned as the string
+// values of their nodes in document order; numbers, strings and booleans as a single value.
+// namespaces maps the prefixes used in expr to URIs; prefixes declared in the document are used
+// when not given. Unprefixed names match on local name in any namespace.
+func Evaluate(body, src string, namespaces map[string]string) ([]string, error) {
+	e, err := compile(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XPath: %w", err)
+	}
+
+	doc, err := parseDocument(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XML: %w", err)
+	}
+
+	ns := make(map[string]string, len(doc.declared)+len(namespaces))
+	for k, v := range doc.declared {
+		ns[k] = v
+	}
+	for k, v := range namespaces {
+		ns[k] = v
+	}
+
+	ev := &evaluator{doc: doc, namespaces: ns}
+	result, err := ev.eval(e, context{node: doc.root, position: 1, size: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if nodes, ok := result.([]*node); ok {
+		values := make([]string, len(nodes))
+		for i, n := range nodes {
+			values[i] = n.stringValue()
+		}
+		return values, nil
+	}
+	return []string{toString(result)}, nil
+}
+
+// Compile checks that src parses as an XPath expression.
+func Compile(src string) error {
+	if _, err := compile(src); err != nil {
+		return fmt.Errorf("invalid XPath: %w", err)
+	}
+	return nil
+}
+
+func (ev *evaluator) eval(e expr, ctx context) (interface{}, error) {
+	switch x := e.(type) {
+	case *literalExpr:
+		return x.value, nil
+	case *numberExpr:
+		return x.value, nil
+	case *negateExpr:
+		v, err := ev.eval(x.operand, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return -toNumber(v), nil
+	case *binaryExpr:
+		return ev.evalBinary(x, ctx)
+	case *callExpr:
+		return ev.call(x, ctx)
+	case *filterExpr:
+		v, err := ev.eval(x.primary, ctx)
+		if err != nil {
+			return nil, err
+		}
+		nodes, ok := v.([]*node)
+		if !ok {
+			return nil, errors.New("predicates can only filter node-sets")
+		}
+		for _, pred := range x.predicates {
+			nodes, err = ev.filter(nodes, pred)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nodes, nil
+	case *pathExpr:
+		return ev.evalPath(x, ctx)
+	}
+	return nil, errors.New("unsupported expression")
+}
+
+func (ev *evaluator) evalBinary(x *binaryExpr, ctx context) (interface{}, error) {
+	left, err := ev.eval(x.left, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if x.op == "or" && toBool(left) {
+		return true, nil
+	}
+	if x.op == "and" && !toBool(left) {
+		return false, nil
+	}
+
+	right, err := ev.eval(x.right, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch x.op {
+	case "or", "and":
+		return toBool(right), nil
+	case "|":
+		l, lok := left.([]*node)
+		r, rok := right.([]*node)
+		if !lok || !rok {
+			return nil, errors.New("| requires node-sets")
+		}
+		return documentOrder(append(append([]*node{}, l...), r...)), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return compare(x.op, left, right), nil
+	case "+":
+		return toNumber(left) + toNumber(right), nil
+	case "-":
+		return toNumber(left) - toNumber(right), nil
+	case "*":
+		return toNumber(left) * toNumber(right), nil
+	case "div":
+		return toNumber(left) / toNumber(right), nil
+	case "mod":
+		return math.Mod(toNumber(left), toNumber(right)), nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", x.op)
+}
+
+func (ev *evaluator) evalPath(x *pathExpr, ctx context) (interface{}, error) {
+	var current []*node
+	switch {
+	case x.filter != nil:
+		v, err := ev.eval(x.filter, ctx)
+		if err != nil {
+			return nil, err
+		}
+		nodes, ok := v.([]*node)
+		if !ok {
+			return nil, errors.New("path must start from a node-set")
+		}
+		current = nodes
+	case x.absolute:
+		current = []*node{ev.doc.root}
+	default:
+		current = []*node{ctx.node}
+	}
+
+	for _, s := range x.steps {
+		next := make([]*node, 0)
+		seen := make(map[*node]bool)
+		for _, n := range current {
+			matched, err := ev.applyStep(n, s)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range matched {
+				if !seen[m] {
+					seen[m] = true
+					next = append(next, m)
+				}
+			}
+		}
+		current = documentOrder(next)
+	}
+
+	return current, nil
+}
+
+func (ev *evaluator) applyStep(n *node, s step) ([]*node, error) {
+	candidates := axisNodes(n, s.axis)
+	matched := make([]*node, 0, len(candidates))
+	for _, c := range candidates {
+		ev.visits++
+		if ev.visits > maxNodeVisits {
+			return nil, errors.New("expression too expensive")
+		}
+		ok, err := ev.matches(c, s)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, c)
+		}
+	}
+
+	var err error
+	for _, pred := range s.predicates {
+		matched, err = ev.filter(matched, pred)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matched, nil
+}
+
+// filter keeps the nodes for which pred holds; numeric predicates select by position.
+func (ev *evaluator) filter(nodes []*node, pred expr) ([]*node, error) {
+	out := make([]*node, 0, len(nodes))
+	for i, n := range nodes {
+		v, err := ev.eval(pred, context{node: n, position: i + 1, size: len(nodes)})
+		if err != nil {
+			return nil, err
+		}
+		if num, ok := v.(float64); ok {
+			if num == float64(i+1) {
+				out = append(out, n)
+			}
+			continue
+		}
+		if toBool(v) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+func (ev *evaluator) matches(n *node, s step) (bool, error) {
+	switch s.test.kind {
+	case testNode:
+		return true, nil
+	case testText:
+		return n.kind == textNode, nil
+	case testComment:
+		return n.kind == commentNode, nil
+	}
+
+	principal := elementNode
+	if s.axis == "attribute" {
+		principal = attributeNode
+	}
+	if n.kind != principal {
+		return false, nil
+	}
+
+	if s.test.prefix != "" {
+		uri, ok := ev.namespaces[s.test.prefix]
+		if !ok {
+			return false, fmt.Errorf("undeclared namespace prefix %q", s.test.prefix)
+		}
+		if n.space != uri {
+			return false, nil
+		}
+	}
+	return s.test.local == "*" || s.test.local == n.local, nil
+}
+
+// axisNodes lists the nodes on axis from n in proximity order, so positional predicates on
+// reverse axes (ancestor, preceding-sibling) count outwards from n.
+func axisNodes(n *node, axis string) []*node {
+	switch axis {
+	case "child":
+		return n.children
+	case "attribute":
+		return n.attrs
+	case "self":
+		return []*node{n}
+	case "parent":
+		if n.parent != nil {
+			return []*node{n.parent}
+		}
+		return nil
+	case "descendant":
+		out := make([]*node, 0)
+		collectDescendants(n, &out)
+		return out
+	case "descendant-or-self":
+		out := []*node{n}
+		collectDescendants(n, &out)
+		return out
+	case "ancestor", "ancestor-or-self":
+		out := make([]*node, 0)
+		if axis == "ancestor-or-self" {
+			out = append(out, n)
+		}
+		for p := n.parent; p != nil; p = p.parent {
+			out = append(out, p)
+		}
+		return out
+	case "following-sibling", "preceding-sibling":
+		if n.parent == nil || n.kind == attributeNode {
+			return nil
+		}
+		siblings := n.parent.children
+		idx := 0
+		for i, s := range siblings {
+			if s == n {
+				idx = i
+			}
+		}
+		if axis == "following-sibling" {
+			return siblings[idx+1:]
+		}
+		out := make([]*node, 0, idx)
+		for i := idx - 1; i >= 0; i-- {
+			out = append(out, siblings[i])
+		}
+		return out
+	}
+	return nil
+}
+
+func collectDescendants(n *node, out *[]*node) {
+	for _, c := range n.children {
+		*out = append(*out, c)
+		collectDescendants(c, out)
+	}
+}
+
+func documentOrder(nodes []*node) []*node {
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].order < nodes[j].order })
+	out := nodes[:0]
+	var last *node
+	for _, n := range nodes {
+		if n != last {
+			out = append(out, n)
+		}
+		last = n
+	}
+	return out
+}
+
+// compare implements XPath 1.0 comparison, including the existential rules for node-sets.
+func compare(op string, left, right interface{}) bool {
+	if l, ok := left.([]*node); ok {
+		for _, n := range l {
+			if compareAtomic(op, nodeOperand(n, right), right) {
+				return true
+			}
+		}
+		if _, isBool := right.(bool); isBool {
+			return compareAtomic(op, len(l) > 0, right)
+		}
+		return false
+	}
+	if r, ok := right.([]*node); ok {
+		for _, n := range r {
+			if compareAtomic(op, left, nodeOperand(n, left)) {
+				return true
+			}
+		}
+		if _, isBool := left.(bool); isBool {
+			return compareAtomic(op, left, len(r) > 0)
+		}
+		return false
+	}
+	return compareAtomic(op, left, right)
+}
+
+// nodeOperand converts a node to the type it is being compared with.
+func nodeOperand(n *node, other interface{}) interface{} {
+	switch other.(type) {
+	case float64:
+		return toNumber(n.stringValue())
+	case bool:
+		return nil
+	}
+	return n.stringValue()
+}
+
+func compareAtomic(op string, left, right interface{}) bool {
+	if left == nil || right == nil {
+		return false
+	}
+	if op == "=" || op == "!=" {
+		var equal bool
+		_, lb := left.(bool)
+		_, rb := right.(bool)
+		_, ln := left.(float64)
+		_, rn := right.(float64)
+		switch {
+		case lb || rb:
+			equal = toBool(left) == toBool(right)
+		case ln || rn:
+			equal = toNumber(left) == toNumber(right)
+		default:
+			equal = toString(left) == toString(right)
+		}
+		if op == "=" {
+			return equal
+		}
+		return !equal
+	}
+
+	l, r := toNumber(left), toNumber(right)
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case bool:
+		if x {
+			return "true"
+		}
+		return "false"
+	case float64:
+		if math.IsNaN(x) {
+			return "NaN"
+		}
+		if x == math.Trunc(x) && math.Abs(x) < 1e15 {
+			return strconv.FormatInt(int64(x), 10)
+		}
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case []*node:
+		if len(x) == 0 {
+			return ""
+		}
+		return x[0].stringValue()
+	}
+	return ""
+}
+
+func toNumber(v interface{}) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case bool:
+		if x {
+			return 1
+		}
+		return 0
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(toString(v)), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return n
+}
+
+func toBool(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case float64:
+		return x != 0 && !math.IsNaN(x)
+	case string:
+		return x != ""
+	case []*node:
+		return len(x) > 0
+	}
+	return false
+}
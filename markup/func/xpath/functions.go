@@ -0,0 +1,269 @@
+package xpath
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type function func(ev *evaluator, ctx context, args []interface{}) (interface{}, error)
+
+type functionSpec struct {
+	fn       function
+	min, max int
+}
+
+var functions map[string]functionSpec
+
+func init() {
+	functions = map[string]functionSpec{
+		"last":             {fnLast, 0, 0},
+		"position":         {fnPosition, 0, 0},
+		"count":            {fnCount, 1, 1},
+		"name":             {fnName, 0, 1},
+		"local-name":       {fnLocalName, 0, 1},
+		"namespace-uri":    {fnNamespaceURI, 0, 1},
+		"string":           {fnString, 0, 1},
+		"concat":           {fnConcat, 2, 64},
+		"starts-with":      {fnStartsWith, 2, 2},
+		"ends-with":        {fnEndsWith, 2, 2},
+		"contains":         {fnContains, 2, 2},
+		"substring-before": {fnSubstringBefore, 2, 2},
+		"substring-after":  {fnSubstringAfter, 2, 2},
+		"substring":        {fnSubstring, 2, 3},
+		"string-length":    {fnStringLength, 0, 1},
+		"normalize-space":  {fnNormalizeSpace, 0, 1},
+		"translate":        {fnTranslate, 3, 3},
+		"lower-case":       {fnLowerCase, 1, 1},
+		"upper-case":       {fnUpperCase, 1, 1},
+		"not":              {fnNot, 1, 1},
+		"true":             {fnTrue, 0, 0},
+		"false":            {fnFalse, 0, 0},
+		"boolean":          {fnBoolean, 1, 1},
+		"number":           {fnNumber, 0, 1},
+		"sum":              {fnSum, 1, 1},
+		"floor":            {fnFloor, 1, 1},
+		"ceiling":          {fnCeiling, 1, 1},
+		"round":            {fnRound, 1, 1},
+	}
+}
+
+func (ev *evaluator) call(x *callExpr, ctx context) (interface{}, error) {
+	spec, ok := functions[x.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s()", x.name)
+	}
+	if len(x.args) < spec.min || len(x.args) > spec.max {
+		return nil, fmt.Errorf("%s() takes %d-%d arguments, got %d", x.name, spec.min, spec.max, len(x.args))
+	}
+
+	args := make([]interface{}, len(x.args))
+	for i, arg := range x.args {
+		v, err := ev.eval(arg, ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return spec.fn(ev, ctx, args)
+}
+
+// argOrContext returns the first argument, or the context node as a node-set when omitted.
+func argOrContext(ctx context, args []interface{}) interface{} {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return []*node{ctx.node}
+}
+
+func firstNode(v interface{}) (*node, error) {
+	nodes, ok := v.([]*node)
+	if !ok {
+		return nil, fmt.Errorf("expected a node-set")
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	return nodes[0], nil
+}
+
+func fnLast(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return float64(ctx.size), nil
+}
+
+func fnPosition(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return float64(ctx.position), nil
+}
+
+func fnCount(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	nodes, ok := args[0].([]*node)
+	if !ok {
+		return nil, fmt.Errorf("count() expects a node-set")
+	}
+	return float64(len(nodes)), nil
+}
+
+func fnName(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	n, err := firstNode(argOrContext(ctx, args))
+	if err != nil || n == nil {
+		return "", err
+	}
+	return n.name(), nil
+}
+
+func fnLocalName(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	n, err := firstNode(argOrContext(ctx, args))
+	if err != nil || n == nil {
+		return "", err
+	}
+	return n.local, nil
+}
+
+func fnNamespaceURI(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	n, err := firstNode(argOrContext(ctx, args))
+	if err != nil || n == nil {
+		return "", err
+	}
+	return n.space, nil
+}
+
+func fnString(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return toString(argOrContext(ctx, args)), nil
+}
+
+func fnConcat(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	var b strings.Builder
+	for _, a := range args {
+		b.WriteString(toString(a))
+	}
+	return b.String(), nil
+}
+
+func fnStartsWith(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+}
+
+func fnEndsWith(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+}
+
+func fnContains(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return strings.Contains(toString(args[0]), toString(args[1])), nil
+}
+
+func fnSubstringBefore(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	s, sep := toString(args[0]), toString(args[1])
+	if idx := strings.Index(s, sep); idx >= 0 {
+		return s[:idx], nil
+	}
+	return "", nil
+}
+
+func fnSubstringAfter(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	s, sep := toString(args[0]), toString(args[1])
+	if idx := strings.Index(s, sep); idx >= 0 {
+		return s[idx+len(sep):], nil
+	}
+	return "", nil
+}
+
+// fnSubstring follows XPath's 1-based, rounded positions.
+func fnSubstring(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	runes := []rune(toString(args[0]))
+	start := math.Round(toNumber(args[1]))
+	end := math.Inf(1)
+	if len(args) == 3 {
+		end = start + math.Round(toNumber(args[2]))
+	}
+
+	var b strings.Builder
+	for i, r := range runes {
+		pos := float64(i + 1)
+		if pos >= start && pos < end {
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+func fnStringLength(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return float64(len([]rune(toString(argOrContext(ctx, args))))), nil
+}
+
+func fnNormalizeSpace(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return strings.Join(strings.Fields(toString(argOrContext(ctx, args))), " "), nil
+}
+
+func fnTranslate(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	from, to := []rune(toString(args[1])), []rune(toString(args[2]))
+	var b strings.Builder
+	for _, r := range toString(args[0]) {
+		idx := -1
+		for i, f := range from {
+			if f == r {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			b.WriteRune(r)
+			continue
+		}
+		if idx < len(to) {
+			b.WriteRune(to[idx])
+		}
+	}
+	return b.String(), nil
+}
+
+func fnLowerCase(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return strings.ToLower(toString(args[0])), nil
+}
+
+func fnUpperCase(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return strings.ToUpper(toString(args[0])), nil
+}
+
+func fnNot(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return !toBool(args[0]), nil
+}
+
+func fnTrue(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return true, nil
+}
+
+func fnFalse(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return false, nil
+}
+
+func fnBoolean(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return toBool(args[0]), nil
+}
+
+func fnNumber(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return toNumber(argOrContext(ctx, args)), nil
+}
+
+func fnSum(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	nodes, ok := args[0].([]*node)
+	if !ok {
+		return nil, fmt.Errorf("sum() expects a node-set")
+	}
+	total := 0.0
+	for _, n := range nodes {
+		total += toNumber(n.stringValue())
+	}
+	return total, nil
+}
+
+func fnFloor(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return math.Floor(toNumber(args[0])), nil
+}
+
+func fnCeiling(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return math.Ceil(toNumber(args[0])), nil
+}
+
+func fnRound(ev *evaluator, ctx context, args []interface{}) (interface{}, error) {
+	return math.Floor(toNumber(args[0]) + 0.5), nil
+}
@@ -0,0 +1,585 @@
+package xpath
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const maxExpressionLength = 4096
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokName
+	tokNumber
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func lex(src string) ([]token, error) {
+	tokens := make([]token, 0, len(src)/2)
+	i := 0
+
+	for i < len(src) {
+		c := src[i]
+
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			i++
+			continue
+		}
+
+		if c == '"' || c == '\'' {
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, errors.New("unterminated string literal")
+			}
+			tokens = append(tokens, token{kind: tokString, text: src[i+1 : i+1+end]})
+			i += end + 2
+			continue
+		}
+
+		if isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])) {
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i]})
+			continue
+		}
+
+		if isNameStart(c) {
+			start := i
+			for i < len(src) && isNameChar(src[i]) {
+				i++
+			}
+			// prefix:local and prefix:* are single names; "::" is the axis separator.
+			if i+1 < len(src) && src[i] == ':' && src[i+1] != ':' {
+				switch {
+				case src[i+1] == '*':
+					i += 2
+				case isNameStart(src[i+1]):
+					i++
+					for i < len(src) && isNameChar(src[i]) {
+						i++
+					}
+				}
+			}
+			tokens = append(tokens, token{kind: tokName, text: src[start:i]})
+			continue
+		}
+
+		if i+1 < len(src) {
+			pair := src[i : i+2]
+			if pair == "//" || pair == ".." || pair == "::" || pair == "!=" || pair == "<=" || pair == ">=" {
+				tokens = append(tokens, token{kind: tokOp, text: pair})
+				i += 2
+				continue
+			}
+		}
+
+		if strings.IndexByte("/[]()@,|.*=<>+-", c) >= 0 {
+			tokens = append(tokens, token{kind: tokOp, text: string(c)})
+			i++
+			continue
+		}
+
+		return nil, fmt.Errorf("unexpected character %q", c)
+	}
+
+	tokens = append(tokens, token{kind: tokEOF})
+	return tokens, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c >= 0x80
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || isDigit(c) || c == '-' || c == '.'
+}
+
+type expr interface{}
+
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+type negateExpr struct {
+	operand expr
+}
+
+type literalExpr struct {
+	value string
+}
+
+type numberExpr struct {
+	value float64
+}
+
+type callExpr struct {
+	name string
+	args []expr
+}
+
+type filterExpr struct {
+	primary    expr
+	predicates []expr
+}
+
+// pathExpr is a location path, optionally rooted at a filter expression such as (//a)[1]/b.
+type pathExpr struct {
+	filter   expr
+	absolute bool
+	steps    []step
+}
+
+type step struct {
+	axis       string
+	test       nodeTest
+	predicates []expr
+}
+
+type testKind int
+
+const (
+	testName testKind = iota
+	testNode
+	testText
+	testComment
+)
+
+type nodeTest struct {
+	kind   testKind
+	prefix string
+	local  string
+}
+
+var axes = map[string]bool{
+	"child": true, "descendant": true, "descendant-or-self": true, "parent": true,
+	"ancestor": true, "ancestor-or-self": true, "following-sibling": true,
+	"preceding-sibling": true, "attribute": true, "self": true,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func compile(src string) (expr, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, errors.New("empty expression")
+	}
+	if len(src) > maxExpressionLength {
+		return nil, errors.New("expression too long (max 4KB)")
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return token{kind: tokEOF}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(text string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == text
+}
+
+// isKeyword reports an operator name (and, or, div, mod) in operator position.
+func (p *parser) isKeyword(text string) bool {
+	t := p.peek()
+	return t.kind == tokName && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if !p.isOp(text) {
+		return fmt.Errorf("expected %q", text)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseEquality() (expr, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("=") || p.isOp("!=") {
+		op := p.next().text
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseRelational() (expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("<") || p.isOp("<=") || p.isOp(">") || p.isOp(">=") {
+		op := p.next().text
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.next().text
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isKeyword("div") || p.isKeyword("mod") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.isOp("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateExpr{operand: operand}, nil
+	}
+	return p.parseUnion()
+}
+
+func (p *parser) parseUnion() (expr, error) {
+	left, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("|") {
+		p.next()
+		right, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "|", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parsePath() (expr, error) {
+	if p.startsPrimary() {
+		primary, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		preds, err := p.parsePredicates()
+		if err != nil {
+			return nil, err
+		}
+		var filter expr = primary
+		if len(preds) > 0 {
+			filter = &filterExpr{primary: primary, predicates: preds}
+		}
+		if !p.isOp("/") && !p.isOp("//") {
+			return filter, nil
+		}
+		path := &pathExpr{filter: filter}
+		if err := p.parseRelativeSteps(path); err != nil {
+			return nil, err
+		}
+		return path, nil
+	}
+
+	path := &pathExpr{}
+	if p.isOp("/") {
+		p.next()
+		path.absolute = true
+		if !p.startsStep() {
+			return path, nil
+		}
+	}
+	if !path.absolute && p.isOp("//") {
+		p.next()
+		path.absolute = true
+		path.steps = append(path.steps, descendantOrSelf())
+	}
+
+	s, err := p.parseStep()
+	if err != nil {
+		return nil, err
+	}
+	path.steps = append(path.steps, s)
+
+	if err := p.parseRelativeSteps(path); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+func (p *parser) parseRelativeSteps(path *pathExpr) error {
+	for p.isOp("/") || p.isOp("//") {
+		if p.next().text == "//" {
+			path.steps = append(path.steps, descendantOrSelf())
+		}
+		s, err := p.parseStep()
+		if err != nil {
+			return err
+		}
+		path.steps = append(path.steps, s)
+	}
+	return nil
+}
+
+func descendantOrSelf() step {
+	return step{axis: "descendant-or-self", test: nodeTest{kind: testNode}}
+}
+
+func (p *parser) startsPrimary() bool {
+	t := p.peek()
+	if t.kind == tokString || t.kind == tokNumber {
+		return true
+	}
+	if t.kind == tokOp && t.text == "(" {
+		return true
+	}
+	if t.kind == tokName && p.peekAt(1).kind == tokOp && p.peekAt(1).text == "(" {
+		return !isNodeType(t.text)
+	}
+	return false
+}
+
+func (p *parser) startsStep() bool {
+	t := p.peek()
+	if t.kind == tokName {
+		return true
+	}
+	return t.kind == tokOp && (t.text == "." || t.text == ".." || t.text == "@" || t.text == "*")
+}
+
+func isNodeType(name string) bool {
+	return name == "node" || name == "text" || name == "comment"
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return &literalExpr{value: t.text}, nil
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return &numberExpr{value: n}, nil
+	case tokName:
+		p.next()
+		call := &callExpr{name: t.text}
+		for !p.isOp(")") {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if _, ok := functions[call.name]; !ok {
+			return nil, fmt.Errorf("unknown function %s()", call.name)
+		}
+		return call, nil
+	}
+
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (p *parser) parseStep() (step, error) {
+	if p.isOp(".") {
+		p.next()
+		return step{axis: "self", test: nodeTest{kind: testNode}}, nil
+	}
+	if p.isOp("..") {
+		p.next()
+		return step{axis: "parent", test: nodeTest{kind: testNode}}, nil
+	}
+
+	s := step{axis: "child"}
+	if p.isOp("@") {
+		p.next()
+		s.axis = "attribute"
+	}
+	if p.peek().kind == tokName && axes[p.peek().text] && p.peekAt(1).kind == tokOp && p.peekAt(1).text == "::" {
+		s.axis = p.next().text
+		p.next()
+	}
+
+	test, err := p.parseNodeTest()
+	if err != nil {
+		return s, err
+	}
+	s.test = test
+
+	preds, err := p.parsePredicates()
+	if err != nil {
+		return s, err
+	}
+	s.predicates = preds
+	return s, nil
+}
+
+func (p *parser) parseNodeTest() (nodeTest, error) {
+	if p.isOp("*") {
+		p.next()
+		return nodeTest{kind: testName, local: "*"}, nil
+	}
+
+	t := p.next()
+	if t.kind != tokName {
+		return nodeTest{}, errors.New("expected a node test")
+	}
+
+	if isNodeType(t.text) && p.isOp("(") {
+		p.next()
+		if err := p.expect(")"); err != nil {
+			return nodeTest{}, err
+		}
+		switch t.text {
+		case "text":
+			return nodeTest{kind: testText}, nil
+		case "comment":
+			return nodeTest{kind: testComment}, nil
+		}
+		return nodeTest{kind: testNode}, nil
+	}
+
+	prefix, local := "", t.text
+	if idx := strings.IndexByte(t.text, ':'); idx >= 0 {
+		prefix, local = t.text[:idx], t.text[idx+1:]
+	}
+	return nodeTest{kind: testName, prefix: prefix, local: local}, nil
+}
+
+func (p *parser) parsePredicates() ([]expr, error) {
+	var preds []expr
+	for p.isOp("[") {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		preds = append(preds, e)
+	}
+	return preds, nil
+}
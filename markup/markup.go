@@ -0,0 +1,28 @@
+// Package markup queries response bodies that are not JSON: XML with XPath 1.0 expressions
+// (see markup/func/xpath) and HTML with CSS selectors (see markup/func/css).
+package markup
+
+import (
+	"raco/markup/func/css"
+	"raco/markup/func/xpath"
+)
+
+// XPath evaluates expr against an XML body. namespaces maps prefixes used in expr to URIs.
+func XPath(body, expr string, namespaces map[string]string) ([]string, error) {
+	return xpath.Evaluate(body, expr, namespaces)
+}
+
+// CSS returns the text, or the attribute named by a trailing @attr, of elements matching selector.
+func CSS(body, selector string) ([]string, error) {
+	return css.Select(body, selector)
+}
+
+// CheckXPath reports whether expr is a valid XPath expression, without a document.
+func CheckXPath(expr string) error {
+	return xpath.Compile(expr)
+}
+
+// CheckCSS reports whether selector is a valid selector, without a document.
+func CheckCSS(selector string) error {
+	return css.Compile(selector)
+}
@@ -2,10 +2,14 @@ package metrics
 
 import (
 	"raco/metrics/func/collector"
+	"time"
 )
 
 type RequestMetric = collector.RequestMetric
 
+// Dimensions lists the values accepted by GroupBy.
+var Dimensions = collector.Dimensions
+
 type Collector struct {
 	history *collector.History
 }
@@ -25,7 +29,38 @@ func (c *Collector) GetRecent(count int) []RequestMetric {
 }
 
 func (c *Collector) GetStats() Stats {
-	raw := collector.CalculateStats(c.history.GetAll())
+	return CalculateStats(c.history.GetAll())
+}
+
+// CalculateStats summarises metrics, including latency percentiles and status and error counts.
+func CalculateStats(metrics []RequestMetric) Stats {
+	return fromRaw(collector.CalculateStats(metrics))
+}
+
+// GroupBy summarises metrics per endpoint, host, protocol, collection, hour or day.
+func GroupBy(metrics []RequestMetric, dimension string) ([]Group, error) {
+	raw, err := collector.GroupBy(metrics, dimension)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]Group, 0, len(raw))
+	for _, g := range raw {
+		groups = append(groups, Group{Key: g.Key, Label: g.Label, Stats: fromRaw(g.Stats)})
+	}
+	return groups, nil
+}
+
+// Window returns the metrics recorded in [since, until); a zero bound is open.
+func Window(metrics []RequestMetric, since time.Time, until time.Time) []RequestMetric {
+	return collector.Window(metrics, since, until)
+}
+
+// ErrorKind returns the category of a transport error message, e.g. "timeout" or "dns".
+func ErrorKind(message string) string {
+	return collector.ErrorKind(message)
+}
+
+func fromRaw(raw collector.Stats) Stats {
 	return Stats{
 		TotalRequests:   raw.TotalRequests,
 		SuccessCount:    raw.SuccessCount,
@@ -34,6 +69,12 @@ func (c *Collector) GetStats() Stats {
 		AverageDuration: raw.AverageDuration,
 		MinDuration:     raw.MinDuration,
 		MaxDuration:     raw.MaxDuration,
+		P50Duration:     raw.P50Duration,
+		P90Duration:     raw.P90Duration,
+		P95Duration:     raw.P95Duration,
+		P99Duration:     raw.P99Duration,
+		StatusCodes:     raw.StatusCodes,
+		Errors:          raw.Errors,
 		LastUpdated:     raw.LastUpdated,
 	}
 }
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"raco/metrics/func/exposition"
+	"strings"
+)
+
+// Exposition formats accepted by WriteExposition.
+const (
+	FormatPrometheus  = exposition.FormatPrometheus
+	FormatOpenMetrics = exposition.FormatOpenMetrics
+)
+
+// WriteExposition renders metrics as request counters, duration histograms and error and
+// assertion counters in the Prometheus text format or OpenMetrics.
+func WriteExposition(w io.Writer, metrics []RequestMetric, format string) error {
+	return exposition.Write(w, metrics, format)
+}
+
+// Handler serves the metrics returned by load on every request, in OpenMetrics when the
+// scraper asks for it and in the Prometheus text format otherwise.
+func Handler(load func() ([]RequestMetric, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loaded, err := load()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		format, contentType := FormatPrometheus, exposition.ContentTypePrometheus
+		if strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+			format, contentType = FormatOpenMetrics, exposition.ContentTypeOpenMetrics
+		}
+
+		var buf bytes.Buffer
+		if err := exposition.Write(&buf, loaded, format); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"strings"
+)
+
+// Error categories reported by ErrorKind.
+const (
+	ErrorTimeout = "timeout"
+	ErrorDNS     = "dns"
+	ErrorRefused = "refused"
+	ErrorReset   = "reset"
+	ErrorTLS     = "tls"
+	ErrorBlocked = "blocked"
+	ErrorInvalid = "invalid"
+	ErrorOther   = "other"
+)
+
+// errorPatterns maps fragments of Go network error messages to their category, checked in order.
+var errorPatterns = []struct {
+	kind      string
+	fragments []string
+}{
+	{ErrorTimeout, []string{"deadline exceeded", "timeout", "timed out"}},
+	{ErrorDNS, []string{"no such host", "server misbehaving"}},
+	{ErrorRefused, []string{"connection refused"}},
+	{ErrorReset, []string{"connection reset", "broken pipe", "eof"}},
+	{ErrorTLS, []string{"tls:", "x509:", "certificate"}},
+	{ErrorBlocked, []string{"blocked"}},
+	{ErrorInvalid, []string{"invalid url", "invalid http method", "unsupported protocol", "undefined variable"}},
+}
+
+// ErrorKind sorts an error message into one of the categories above, so metrics can count
+// failures without storing messages that may contain URLs or credentials.
+func ErrorKind(message string) string {
+	lower := strings.ToLower(message)
+	for _, p := range errorPatterns {
+		for _, fragment := range p.fragments {
+			if strings.Contains(lower, fragment) {
+				return p.kind
+			}
+		}
+	}
+	return ErrorOther
+}
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dimensions that GroupBy accepts.
+const (
+	ByEndpoint   = "endpoint"
+	ByHost       = "host"
+	ByProtocol   = "protocol"
+	ByCollection = "collection"
+	ByHour       = "hour"
+	ByDay        = "day"
+)
+
+// Dimensions lists the values accepted by GroupBy, for usage text.
+var Dimensions = []string{ByEndpoint, ByHost, ByProtocol, ByCollection, ByHour, ByDay}
+
+// Group is the summary of the metrics that share one value of a dimension. Label is the
+// readable form of Key; they differ for endpoints, which are keyed by request ID.
+type Group struct {
+	Key   string
+	Label string
+	Stats Stats
+}
+
+// GroupBy splits metrics by dimension and summarises each part. Time buckets are returned in
+// chronological order, every other dimension by descending request count.
+func GroupBy(metrics []RequestMetric, dimension string) ([]Group, error) {
+	key, err := keyFunc(dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make(map[string][]RequestMetric)
+	labels := make(map[string]string)
+	order := make([]string, 0)
+	for _, m := range metrics {
+		k := key(m)
+		if _, ok := parts[k]; !ok {
+			order = append(order, k)
+		}
+		parts[k] = append(parts[k], m)
+		// The latest metric names the group, so a renamed request shows its current name.
+		labels[k] = k
+		if dimension == ByEndpoint {
+			labels[k] = Endpoint(m)
+		}
+	}
+
+	groups := make([]Group, 0, len(order))
+	for _, k := range order {
+		groups = append(groups, Group{Key: k, Label: labels[k], Stats: CalculateStats(parts[k])})
+	}
+
+	if dimension == ByHour || dimension == ByDay {
+		sort.SliceStable(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+		return groups, nil
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Stats.TotalRequests != groups[j].Stats.TotalRequests {
+			return groups[i].Stats.TotalRequests > groups[j].Stats.TotalRequests
+		}
+		return groups[i].Label < groups[j].Label
+	})
+	return groups, nil
+}
+
+func keyFunc(dimension string) (func(RequestMetric) string, error) {
+	switch dimension {
+	case ByEndpoint:
+		return endpointKey, nil
+	case ByHost:
+		return func(m RequestMetric) string { return orNone(m.Host) }, nil
+	case ByProtocol:
+		return func(m RequestMetric) string { return orNone(m.Protocol) }, nil
+	case ByCollection:
+		return func(m RequestMetric) string { return orNone(m.Collection) }, nil
+	case ByHour:
+		return func(m RequestMetric) string { return m.Timestamp.Local().Format("2006-01-02 15:00") }, nil
+	case ByDay:
+		return func(m RequestMetric) string { return m.Timestamp.Local().Format("2006-01-02") }, nil
+	}
+	return nil, fmt.Errorf("unknown grouping %q (use %s)", dimension, strings.Join(Dimensions, ", "))
+}
+
+// Endpoint names the request m was made for: the collection and request name for saved
+// requests, otherwise the method, host and path.
+func Endpoint(m RequestMetric) string {
+	if m.RequestName != "" {
+		return m.Collection + " → " + m.RequestName
+	}
+	endpoint := strings.TrimSpace(m.Method + " " + m.Host + m.Path)
+	return orNone(endpoint)
+}
+
+func endpointKey(m RequestMetric) string {
+	if m.RequestID != "" {
+		return m.RequestID
+	}
+	return Endpoint(m)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// Window returns the metrics recorded at or after since and before until. A zero bound is open.
+func Window(metrics []RequestMetric, since time.Time, until time.Time) []RequestMetric {
+	out := make([]RequestMetric, 0, len(metrics))
+	for _, m := range metrics {
+		if !since.IsZero() && m.Timestamp.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !m.Timestamp.Before(until) {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
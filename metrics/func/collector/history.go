@@ -5,12 +5,28 @@ import (
 	"time"
 )
 
+// RequestMetric describes one executed request. The JSON form is what the metrics store
+// persists; it holds no URLs beyond host and path and no error text beyond its category.
 type RequestMetric struct {
-	Timestamp  time.Time
-	Duration   time.Duration
-	StatusCode int
-	Success    bool
-	Protocol   string
+	Timestamp  time.Time     `json:"timestamp"`
+	Duration   time.Duration `json:"duration_ns"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Success    bool          `json:"success"`
+	Protocol   string        `json:"protocol"`
+	Method     string        `json:"method,omitempty"`
+	Host       string        `json:"host,omitempty"`
+	Path       string        `json:"path,omitempty"`
+	// RequestID, RequestName and Collection are set for requests saved in a collection.
+	RequestID   string `json:"request_id,omitempty"`
+	RequestName string `json:"request_name,omitempty"`
+	Collection  string `json:"collection,omitempty"`
+	// ErrorKind is the category of a transport error, see ErrorKind; empty when a response arrived.
+	ErrorKind string `json:"error,omitempty"`
+	// AssertionsPassed and AssertionsFailed count the request's assertion results.
+	AssertionsPassed int `json:"assertions_passed,omitempty"`
+	AssertionsFailed int `json:"assertions_failed,omitempty"`
+	// Source is "tui", "req" or "run".
+	Source string `json:"source,omitempty"`
 }
 
 type History struct {
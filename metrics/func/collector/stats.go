@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"sort"
 	"time"
 )
 
@@ -12,11 +13,23 @@ type Stats struct {
 	AverageDuration time.Duration
 	MinDuration     time.Duration
 	MaxDuration     time.Duration
-	LastUpdated     time.Time
+	P50Duration     time.Duration
+	P90Duration     time.Duration
+	P95Duration     time.Duration
+	P99Duration     time.Duration
+	// StatusCodes counts responses per status code.
+	StatusCodes map[int]int
+	// Errors counts requests that got no response, per ErrorKind category.
+	Errors      map[string]int
+	LastUpdated time.Time
 }
 
+// CalculateStats summarises metrics. Durations are taken from requests that received a
+// response; requests that failed before one arrived count only towards FailureCount and Errors.
 func CalculateStats(metrics []RequestMetric) Stats {
 	stats := Stats{
+		StatusCodes: make(map[int]int),
+		Errors:      make(map[string]int),
 		LastUpdated: time.Now(),
 	}
 
@@ -26,8 +39,9 @@ func CalculateStats(metrics []RequestMetric) Stats {
 
 	stats.TotalRequests = len(metrics)
 	totalDuration := time.Duration(0)
+	durations := make([]time.Duration, 0, len(metrics))
 
-	for i, m := range metrics {
+	for _, m := range metrics {
 		if m.Success {
 			stats.SuccessCount++
 		}
@@ -35,25 +49,55 @@ func CalculateStats(metrics []RequestMetric) Stats {
 			stats.FailureCount++
 		}
 
-		totalDuration += m.Duration
-
-		if i == 0 {
-			stats.MinDuration = m.Duration
-			stats.MaxDuration = m.Duration
-		}
-
-		if m.Duration < stats.MinDuration {
-			stats.MinDuration = m.Duration
+		if !responded(m) {
+			stats.Errors[errorKindOf(m)]++
+			continue
 		}
-		if m.Duration > stats.MaxDuration {
-			stats.MaxDuration = m.Duration
+		if m.StatusCode > 0 {
+			stats.StatusCodes[m.StatusCode]++
 		}
+
+		totalDuration += m.Duration
+		durations = append(durations, m.Duration)
 	}
 
-	if stats.TotalRequests > 0 {
-		stats.SuccessRate = float64(stats.SuccessCount) / float64(stats.TotalRequests) * 100
-		stats.AverageDuration = totalDuration / time.Duration(stats.TotalRequests)
+	stats.SuccessRate = float64(stats.SuccessCount) / float64(stats.TotalRequests) * 100
+
+	if len(durations) > 0 {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		stats.AverageDuration = totalDuration / time.Duration(len(durations))
+		stats.MinDuration = durations[0]
+		stats.MaxDuration = durations[len(durations)-1]
+		stats.P50Duration = percentile(durations, 50)
+		stats.P90Duration = percentile(durations, 90)
+		stats.P95Duration = percentile(durations, 95)
+		stats.P99Duration = percentile(durations, 99)
 	}
 
 	return stats
 }
+
+// responded reports whether m received a response. Metrics recorded before error categories
+// existed have neither a status nor an ErrorKind; a zero duration marks those as failed.
+func responded(m RequestMetric) bool {
+	if m.ErrorKind != "" {
+		return false
+	}
+	return m.StatusCode > 0 || m.Duration > 0
+}
+
+func errorKindOf(m RequestMetric) string {
+	if m.ErrorKind == "" {
+		return ErrorOther
+	}
+	return m.ErrorKind
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted, which must not be empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,218 @@
+// Package exposition renders request metrics in the Prometheus text format and in OpenMetrics,
+// so runs can be charted from the node-exporter textfile collector or a scrape of /metrics.
+package exposition
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"raco/metrics/func/collector"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats written by Write.
+const (
+	FormatPrometheus  = "prometheus"
+	FormatOpenMetrics = "openmetrics"
+)
+
+// Content types of the two formats, for HTTP responses.
+const (
+	ContentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Buckets are the upper bounds, in seconds, of the request duration histogram.
+var Buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type requestKey struct {
+	collection string
+	request    string
+	status     string
+	protocol   string
+}
+
+type assertionKey struct {
+	collection string
+	request    string
+	result     string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Write renders metrics in format. Counters cover everything in metrics, so a store that
+// rotates old files away shows up as a counter reset, which rate() and increase() handle.
+func Write(w io.Writer, metrics []collector.RequestMetric, format string) error {
+	if format != FormatPrometheus && format != FormatOpenMetrics {
+		return fmt.Errorf("unknown exposition format %q", format)
+	}
+	openMetrics := format == FormatOpenMetrics
+
+	requests := make(map[requestKey]uint64)
+	durations := make(map[requestKey]*histogram)
+	errors := make(map[string]uint64)
+	assertions := make(map[assertionKey]uint64)
+
+	for _, m := range metrics {
+		collection, request := m.Collection, requestLabel(m)
+		status := strconv.Itoa(m.StatusCode)
+		if m.ErrorKind != "" || m.StatusCode == 0 {
+			status = "error"
+		}
+		key := requestKey{collection: collection, request: request, status: status, protocol: m.Protocol}
+		requests[key]++
+
+		if status == "error" {
+			kind := m.ErrorKind
+			if kind == "" {
+				kind = collector.ErrorOther
+			}
+			errors[kind]++
+		}
+		if status != "error" {
+			h := durations[key]
+			if h == nil {
+				h = &histogram{counts: make([]uint64, len(Buckets))}
+				durations[key] = h
+			}
+			seconds := m.Duration.Seconds()
+			for i, bound := range Buckets {
+				if seconds <= bound {
+					h.counts[i]++
+				}
+			}
+			h.sum += seconds
+			h.count++
+		}
+
+		if m.AssertionsPassed > 0 {
+			assertions[assertionKey{collection: collection, request: request, result: "passed"}] += uint64(m.AssertionsPassed)
+		}
+		if m.AssertionsFailed > 0 {
+			assertions[assertionKey{collection: collection, request: request, result: "failed"}] += uint64(m.AssertionsFailed)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+
+	writeHeader(out, "raco_requests", "counter", "Requests sent by raco, by collection, request, status and protocol.", openMetrics)
+	for _, key := range sortedRequestKeys(requests) {
+		fmt.Fprintf(out, "raco_requests_total{%s} %d\n", labels("collection", key.collection, "request", key.request, "status", key.status, "protocol", key.protocol), requests[key])
+	}
+
+	writeHeader(out, "raco_request_duration_seconds", "histogram", "Time from sending a request to reading its response.", openMetrics)
+	for _, key := range sortedRequestKeys(durations) {
+		h := durations[key]
+		base := labels("collection", key.collection, "request", key.request, "status", key.status, "protocol", key.protocol)
+		for i, bound := range Buckets {
+			fmt.Fprintf(out, "raco_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", base, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(out, "raco_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", base, h.count)
+		fmt.Fprintf(out, "raco_request_duration_seconds_sum{%s} %s\n", base, formatFloat(h.sum))
+		fmt.Fprintf(out, "raco_request_duration_seconds_count{%s} %d\n", base, h.count)
+	}
+
+	writeHeader(out, "raco_request_errors", "counter", "Requests that failed without a response, by error category.", openMetrics)
+	for _, kind := range sortedKeys(errors) {
+		fmt.Fprintf(out, "raco_request_errors_total{%s} %d\n", labels("kind", kind), errors[kind])
+	}
+
+	writeHeader(out, "raco_assertions", "counter", "Assertion results, by collection, request and result.", openMetrics)
+	assertionKeys := make([]assertionKey, 0, len(assertions))
+	for key := range assertions {
+		assertionKeys = append(assertionKeys, key)
+	}
+	sort.Slice(assertionKeys, func(i, j int) bool {
+		a, b := assertionKeys[i], assertionKeys[j]
+		if a.collection != b.collection {
+			return a.collection < b.collection
+		}
+		if a.request != b.request {
+			return a.request < b.request
+		}
+		return a.result < b.result
+	})
+	for _, key := range assertionKeys {
+		fmt.Fprintf(out, "raco_assertions_total{%s} %d\n", labels("collection", key.collection, "request", key.request, "result", key.result), assertions[key])
+	}
+
+	if openMetrics {
+		fmt.Fprintln(out, "# EOF")
+	}
+	return out.Flush()
+}
+
+// requestLabel names the request of m: its saved name, or method and host. The path is left
+// out because IDs in paths would give every ID a series of its own.
+func requestLabel(m collector.RequestMetric) string {
+	if m.RequestName != "" {
+		return m.RequestName
+	}
+	return strings.TrimSpace(m.Method + " " + m.Host)
+}
+
+// writeHeader writes the HELP and TYPE lines of a family. OpenMetrics names counter families
+// without the _total suffix their samples carry; the Prometheus format uses the sample name.
+func writeHeader(out io.Writer, family string, kind string, help string, openMetrics bool) {
+	name := family
+	if kind == "counter" && !openMetrics {
+		name = family + "_total"
+	}
+	fmt.Fprintf(out, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(out, "# TYPE %s %s\n", name, kind)
+}
+
+// labels renders name/value pairs as a label set, escaping values as both formats require.
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+"=\""+escape(pairs[i+1])+"\"")
+	}
+	return strings.Join(parts, ",")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedRequestKeys[V any](m map[requestKey]V) []requestKey {
+	keys := make([]requestKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.collection != b.collection {
+			return a.collection < b.collection
+		}
+		if a.request != b.request {
+			return a.request < b.request
+		}
+		if a.status != b.status {
+			return a.status < b.status
+		}
+		return a.protocol < b.protocol
+	})
+	return keys
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"net/url"
+	"raco/model"
+	"time"
+)
+
+// NewMetric describes a request to rawURL that returned resp or failed with errMsg. Success
+// means a 2xx response. Only the host and path of rawURL are kept.
+func NewMetric(protocol string, method string, rawURL string, resp *model.Response, errMsg string) RequestMetric {
+	metric := RequestMetric{
+		Timestamp: time.Now(),
+		Protocol:  protocol,
+		Method:    method,
+	}
+	if parsed, err := url.Parse(rawURL); err == nil {
+		metric.Host = parsed.Host
+		metric.Path = parsed.Path
+	}
+
+	if errMsg != "" || resp == nil {
+		metric.ErrorKind = ErrorKind(errMsg)
+		return metric
+	}
+
+	if !resp.Timestamp.IsZero() {
+		metric.Timestamp = resp.Timestamp
+	}
+	metric.Duration = resp.Duration
+	metric.StatusCode = resp.StatusCode
+	metric.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	return metric
+}
+
+// CountAssertions sets the assertion counters of metric from results.
+func CountAssertions(metric *RequestMetric, results []model.AssertionResult) {
+	for _, r := range results {
+		if r.Passed {
+			metric.AssertionsPassed++
+		}
+		if !r.Passed {
+			metric.AssertionsFailed++
+		}
+	}
+}
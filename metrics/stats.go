@@ -12,5 +12,18 @@ type Stats struct {
 	AverageDuration time.Duration
 	MinDuration     time.Duration
 	MaxDuration     time.Duration
+	P50Duration     time.Duration
+	P90Duration     time.Duration
+	P95Duration     time.Duration
+	P99Duration     time.Duration
+	StatusCodes     map[int]int
+	Errors          map[string]int
 	LastUpdated     time.Time
 }
+
+// Group is the summary of the metrics that share one value of a grouping dimension.
+type Group struct {
+	Key   string
+	Label string
+	Stats Stats
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"raco/markup"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,13 +16,18 @@ const (
 	AssertJSONPath   AssertionType = "jsonpath"
 	AssertRegex      AssertionType = "regex"
 	AssertHeader     AssertionType = "header"
+	AssertXPath      AssertionType = "xpath"
+	AssertCSS        AssertionType = "css"
+	AssertScript     AssertionType = "script"
+	AssertPoll       AssertionType = "poll"
 )
 
 type Assertion struct {
-	Type     AssertionType `json:"type" yaml:"type"`
-	Field    string        `json:"field" yaml:"field"`
-	Operator string        `json:"operator" yaml:"operator"`
-	Value    string        `json:"value" yaml:"value"`
+	Type       AssertionType     `json:"type" yaml:"type"`
+	Field      string            `json:"field" yaml:"field"`
+	Operator   string            `json:"operator" yaml:"operator"`
+	Value      string            `json:"value" yaml:"value"`
+	Namespaces map[string]string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
 }
 
 type AssertionResult struct {
@@ -55,54 +61,25 @@ func ValidateAssertion(assertion Assertion, response *Response) AssertionResult
 		return validateHeader(assertion, response)
 	}
 
-	return AssertionResult{
-		Assertion: assertion,
-		Passed:    false,
-		Message:   "Unknown assertion type",
-	}
-}
-
-func validateStatusCode(assertion Assertion, response *Response) AssertionResult {
-	expected := assertion.Value
-	actual := strconv.Itoa(response.StatusCode)
-
-	if assertion.Operator == "equals" {
-		if actual == expected {
-			return AssertionResult{
-				Assertion: assertion,
-				Passed:    true,
-				Message:   fmt.Sprintf("Status code is %s", actual),
-			}
-		}
-		return AssertionResult{
-			Assertion: assertion,
-			Passed:    false,
-			Message:   fmt.Sprintf("Expected %s but got %s", expected, actual),
-		}
+	if assertion.Type == AssertXPath {
+		return validateMarkup(assertion, response, "XPath "+assertion.Field)
 	}
 
-	if assertion.Operator == "not_equals" {
-		if actual != expected {
-			return AssertionResult{
-				Assertion: assertion,
-				Passed:    true,
-				Message:   fmt.Sprintf("Status code is not %s", expected),
-			}
-		}
-		return AssertionResult{
-			Assertion: assertion,
-			Passed:    false,
-			Message:   fmt.Sprintf("Status code should not be %s", expected),
-		}
+	if assertion.Type == AssertCSS {
+		return validateMarkup(assertion, response, "Selector "+assertion.Field)
 	}
 
 	return AssertionResult{
 		Assertion: assertion,
 		Passed:    false,
-		Message:   "Invalid operator for status_code",
+		Message:   "Unknown assertion type",
 	}
 }
 
+func validateStatusCode(assertion Assertion, response *Response) AssertionResult {
+	return checkValue(assertion, "Status code", strconv.Itoa(response.StatusCode), true)
+}
+
 func validateJSONPath(assertion Assertion, response *Response) AssertionResult {
 	if response.Body == "" {
 		return AssertionResult{
@@ -123,50 +100,10 @@ func validateJSONPath(assertion Assertion, response *Response) AssertionResult {
 
 	value := extractJSONPath(data, assertion.Field)
 	if value == nil {
-		return AssertionResult{
-			Assertion: assertion,
-			Passed:    false,
-			Message:   fmt.Sprintf("Path %s not found", assertion.Field),
-		}
+		return checkValue(assertion, "Path "+assertion.Field, "", false)
 	}
 
-	valueStr := fmt.Sprintf("%v", value)
-
-	if assertion.Operator == "equals" {
-		if valueStr == assertion.Value {
-			return AssertionResult{
-				Assertion: assertion,
-				Passed:    true,
-				Message:   fmt.Sprintf("Value at %s is %s", assertion.Field, valueStr),
-			}
-		}
-		return AssertionResult{
-			Assertion: assertion,
-			Passed:    false,
-			Message:   fmt.Sprintf("Expected %s but got %s", assertion.Value, valueStr),
-		}
-	}
-
-	if assertion.Operator == "contains" {
-		if strings.Contains(valueStr, assertion.Value) {
-			return AssertionResult{
-				Assertion: assertion,
-				Passed:    true,
-				Message:   fmt.Sprintf("Value contains %s", assertion.Value),
-			}
-		}
-		return AssertionResult{
-			Assertion: assertion,
-			Passed:    false,
-			Message:   fmt.Sprintf("Value does not contain %s", assertion.Value),
-		}
-	}
-
-	return AssertionResult{
-		Assertion: assertion,
-		Passed:    false,
-		Message:   "Invalid operator for jsonpath",
-	}
+	return checkValue(assertion, "Value at "+assertion.Field, fmt.Sprintf("%v", value), true)
 }
 
 const maxRegexPatternLength = 4096
@@ -231,49 +168,43 @@ func validateHeader(assertion Assertion, response *Response) AssertionResult {
 	}
 
 	value, exists := response.Headers[assertion.Field]
-	if !exists {
+	return checkValue(assertion, "Header "+assertion.Field, value, exists)
+}
+
+// validateMarkup checks the first node matched by an XPath expression or CSS selector.
+func validateMarkup(assertion Assertion, response *Response, subject string) AssertionResult {
+	if response.Body == "" {
 		return AssertionResult{
 			Assertion: assertion,
 			Passed:    false,
-			Message:   fmt.Sprintf("Header %s not found", assertion.Field),
+			Message:   "Response body is empty",
 		}
 	}
 
-	if assertion.Operator == "equals" {
-		if value == assertion.Value {
-			return AssertionResult{
-				Assertion: assertion,
-				Passed:    true,
-				Message:   fmt.Sprintf("Header %s is %s", assertion.Field, value),
-			}
-		}
+	values, err := queryMarkup(assertion.Type, response.Body, assertion.Field, assertion.Namespaces)
+	if err != nil {
 		return AssertionResult{
 			Assertion: assertion,
 			Passed:    false,
-			Message:   fmt.Sprintf("Expected %s but got %s", assertion.Value, value),
+			Message:   err.Error(),
 		}
 	}
 
-	if assertion.Operator == "contains" {
-		if strings.Contains(value, assertion.Value) {
-			return AssertionResult{
-				Assertion: assertion,
-				Passed:    true,
-				Message:   fmt.Sprintf("Header contains %s", assertion.Value),
-			}
-		}
-		return AssertionResult{
-			Assertion: assertion,
-			Passed:    false,
-			Message:   fmt.Sprintf("Header does not contain %s", assertion.Value),
-		}
+	if len(values) == 0 {
+		return checkValue(assertion, subject, "", false)
+	}
+	return checkValue(assertion, subject, values[0], true)
+}
+
+func queryMarkup(kind AssertionType, body, query string, namespaces map[string]string) ([]string, error) {
+	if body == "" {
+		return nil, fmt.Errorf("body is empty")
 	}
 
-	return AssertionResult{
-		Assertion: assertion,
-		Passed:    false,
-		Message:   "Invalid operator for header",
+	if kind == AssertXPath {
+		return markup.XPath(body, query, namespaces)
 	}
+	return markup.CSS(body, query)
 }
 
 func extractJSONPath(data interface{}, path string) interface{} {
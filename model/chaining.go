@@ -12,13 +12,16 @@ const (
 	ExtractJSONPath ExtractionType = "jsonpath"
 	ExtractRegex    ExtractionType = "regex"
 	ExtractHeader   ExtractionType = "header"
+	ExtractXPath    ExtractionType = "xpath"
+	ExtractCSS      ExtractionType = "css"
 )
 
 type Extractor struct {
-	Type     ExtractionType `json:"type" yaml:"type"`
-	Source   string         `json:"source" yaml:"source"`
-	Target   string         `json:"target" yaml:"target"`
-	Pattern  string         `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Type       ExtractionType    `json:"type" yaml:"type"`
+	Source     string            `json:"source" yaml:"source"`
+	Target     string            `json:"target" yaml:"target"`
+	Pattern    string            `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Namespaces map[string]string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
 }
 
 func ExtractValue(extractor Extractor, response *Response, env *Environment) error {
@@ -54,6 +57,13 @@ func ExtractValue(extractor Extractor, response *Response, env *Environment) err
 		}
 	}
 
+	if extractor.Type == ExtractXPath || extractor.Type == ExtractCSS {
+		value, err = extractFromMarkup(extractor, response.Body)
+		if err != nil {
+			return err
+		}
+	}
+
 	if value == "" {
 		return fmt.Errorf("extracted value is empty")
 	}
@@ -129,3 +139,21 @@ func extractFromHeader(headers map[string]string, key string) (string, error) {
 
 	return value, nil
 }
+
+func extractFromMarkup(extractor Extractor, body string) (string, error) {
+	kind := AssertXPath
+	if extractor.Type == ExtractCSS {
+		kind = AssertCSS
+	}
+
+	values, err := queryMarkup(kind, body, extractor.Source, extractor.Namespaces)
+	if err != nil {
+		return "", err
+	}
+
+	if len(values) == 0 {
+		return "", fmt.Errorf("no match found: %s", extractor.Source)
+	}
+
+	return values[0], nil
+}
@@ -1,7 +1,23 @@
 package model
 
 type Collection struct {
-	ID       string     `json:"id" yaml:"id"`
-	Name     string     `json:"name" yaml:"name"`
-	Requests []*Request `json:"requests" yaml:"requests"`
+	// SchemaVersion is the version of the stored format the collection was read from; saving
+	// writes the current one.
+	SchemaVersion      int               `json:"schema_version,omitempty" yaml:"schema_version,omitempty"`
+	ID                 string            `json:"id" yaml:"id"`
+	Name               string            `json:"name" yaml:"name"`
+	Requests           []*Request        `json:"requests" yaml:"requests"`
+	Folders            []*Folder         `json:"folders,omitempty" yaml:"folders,omitempty"`
+	Variables          map[string]string `json:"variables,omitempty" yaml:"variables,omitempty"`
+	PreRequestScript   string            `json:"pre_request_script,omitempty" yaml:"pre_request_script,omitempty"`
+	PostResponseScript string            `json:"post_response_script,omitempty" yaml:"post_response_script,omitempty"`
+	LenientVariables   bool              `json:"lenient_variables,omitempty" yaml:"lenient_variables,omitempty"`
+	// Defaults apply to every request in the collection; see Collection.ApplyDefaults.
+	Defaults *Defaults `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	// Revision identifies the stored copy this collection was loaded from; saving fails when the
+	// stored copy has changed since. It is empty for collections that were never loaded.
+	Revision string `json:"-" yaml:"-"`
+	// Order lists the IDs of the top-level requests and folders as they are shown and run; see
+	// Entries.
+	Order []string `json:"order,omitempty" yaml:"order,omitempty"`
 }
@@ -0,0 +1,73 @@
+package model
+
+import "strings"
+
+// RetryPolicy controls how often a request is retried after a transport error and, for
+// idempotent methods, after a 429 or 5xx response. The wait doubles after every attempt,
+// starting at DelayMs.
+type RetryPolicy struct {
+	MaxRetries int `json:"max_retries" yaml:"max_retries"`
+	DelayMs    int `json:"delay_ms,omitempty" yaml:"delay_ms,omitempty"`
+}
+
+// Defaults are collection-wide request settings. Requests and folders that set the same header,
+// query parameter, timeout or retry policy take precedence.
+type Defaults struct {
+	// BaseURL is prepended to request URLs that have no scheme and do not start with a
+	// {{variable}}, e.g. "/users" becomes "https://api.example.com/v1/users".
+	BaseURL        string            `json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Query          map[string]string `json:"query,omitempty" yaml:"query,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
+	Retry          *RetryPolicy      `json:"retry,omitempty" yaml:"retry,omitempty"`
+}
+
+// ApplyDefaults returns a copy of req with the collection's defaults filled in wherever req
+// does not set them itself. Call it after ApplyFolders so folder headers win over defaults.
+func (c *Collection) ApplyDefaults(req *Request) *Request {
+	clone := req.Clone()
+	if c == nil || c.Defaults == nil || clone == nil {
+		return clone
+	}
+	d := c.Defaults
+
+	clone.URL = JoinBaseURL(d.BaseURL, clone.URL)
+
+	if len(d.Headers) > 0 {
+		headers := make(map[string]string, len(d.Headers)+len(clone.Headers))
+		mergeHeaders(headers, d.Headers)
+		mergeHeaders(headers, clone.Headers)
+		clone.Headers = headers
+	}
+
+	if len(d.Query) > 0 {
+		query := make(map[string]string, len(d.Query)+len(clone.Query))
+		copyInto(query, d.Query)
+		copyInto(query, clone.Query)
+		clone.Query = query
+	}
+
+	if clone.TimeoutSeconds <= 0 {
+		clone.TimeoutSeconds = d.TimeoutSeconds
+	}
+
+	if clone.Retry == nil && d.Retry != nil {
+		retry := *d.Retry
+		clone.Retry = &retry
+	}
+	return clone
+}
+
+// JoinBaseURL prefixes url with base unless url is absolute or starts with a {{variable}}.
+func JoinBaseURL(base, url string) string {
+	if base == "" || strings.Contains(url, "://") || strings.HasPrefix(url, "{{") {
+		return url
+	}
+	if url == "" {
+		return base
+	}
+	if strings.HasPrefix(url, "?") {
+		return strings.TrimRight(base, "/") + url
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(url, "/")
+}
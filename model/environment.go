@@ -1,8 +1,18 @@
 package model
 
 type Environment struct {
-	Name      string            `json:"name" yaml:"name"`
+	// SchemaVersion is the version of the stored format; see Collection.SchemaVersion.
+	SchemaVersion int    `json:"schema_version,omitempty" yaml:"schema_version,omitempty"`
+	Name          string `json:"name" yaml:"name"`
+	// Extends names the environment whose variables this one inherits and overrides.
+	Extends   string            `json:"extends,omitempty" yaml:"extends,omitempty"`
 	Variables map[string]string `json:"variables" yaml:"variables"`
+	// Secrets holds encrypted values ("enc:v1:..."). They are decrypted into Variables only in
+	// memory, when the environment is resolved for a run.
+	Secrets map[string]string `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+	// Revision identifies the stored file this environment was loaded from; see
+	// Collection.Revision.
+	Revision string `json:"-" yaml:"-"`
 }
 
 func (e *Environment) GetVariable(key string) string {
@@ -18,3 +28,9 @@ func (e *Environment) GetVariables() map[string]string {
 	}
 	return e.Variables
 }
+
+// IsSecret reports whether key is one of the environment's secrets.
+func (e *Environment) IsSecret(key string) bool {
+	_, ok := e.Secrets[key]
+	return ok
+}
@@ -7,11 +7,11 @@ import (
 )
 
 type FileUpload struct {
-	FieldName string `json:"field_name" yaml:"field_name"`
-	FilePath  string `json:"file_path" yaml:"file_path"`
-	FileName  string `json:"file_name" yaml:"file_name"`
+	FieldName   string `json:"field_name" yaml:"field_name"`
+	FilePath    string `json:"file_path" yaml:"file_path"`
+	FileName    string `json:"file_name" yaml:"file_name"`
 	ContentType string `json:"content_type" yaml:"content_type"`
-	Size      int64  `json:"size" yaml:"size"`
+	Size        int64  `json:"size" yaml:"size"`
 }
 
 func (f *FileUpload) Validate() error {
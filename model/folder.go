@@ -0,0 +1,224 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Folder groups requests inside a collection and may contain further folders. Its headers and
+// variables apply to every request below it unless the request (or a deeper folder) sets the
+// same name; its scripts run after the collection's and before the request's own.
+type Folder struct {
+	ID                 string            `json:"id" yaml:"id"`
+	Name               string            `json:"name" yaml:"name"`
+	Requests           []*Request        `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Folders            []*Folder         `json:"folders,omitempty" yaml:"folders,omitempty"`
+	Headers            map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Variables          map[string]string `json:"variables,omitempty" yaml:"variables,omitempty"`
+	PreRequestScript   string            `json:"pre_request_script,omitempty" yaml:"pre_request_script,omitempty"`
+	PostResponseScript string            `json:"post_response_script,omitempty" yaml:"post_response_script,omitempty"`
+	// Order lists the IDs of the folder's requests and folders as they are shown and run; see
+	// Entries.
+	Order []string `json:"order,omitempty" yaml:"order,omitempty"`
+}
+
+// maxFolderDepth bounds recursion over hand-edited or imported collections.
+const maxFolderDepth = 32
+
+// Entry is one request or folder directly inside a collection or folder; the other field is nil.
+type Entry struct {
+	Request *Request
+	Folder  *Folder
+}
+
+// Entries lists the folder's requests and folders in order; see OrderEntries.
+func (f *Folder) Entries() []Entry {
+	if f == nil {
+		return nil
+	}
+	return OrderEntries(f.Requests, f.Folders, f.Order)
+}
+
+// Entries lists the collection's top-level requests and folders in order; see OrderEntries.
+func (c *Collection) Entries() []Entry {
+	if c == nil {
+		return nil
+	}
+	return OrderEntries(c.Requests, c.Folders, c.Order)
+}
+
+// OrderEntries merges requests and folders in the order of the IDs in order, which imported
+// collections use to keep requests and folders interleaved. Entries that order does not name
+// follow, requests before folders, as all of them do when order is empty.
+func OrderEntries(requests []*Request, folders []*Folder, order []string) []Entry {
+	entries := make([]Entry, 0, len(requests)+len(folders))
+	placed := make(map[interface{}]bool, len(order))
+	for _, id := range order {
+		if id == "" {
+			continue
+		}
+		for _, req := range requests {
+			if req != nil && req.ID == id && !placed[req] {
+				entries = append(entries, Entry{Request: req})
+				placed[req] = true
+			}
+		}
+		for _, folder := range folders {
+			if folder != nil && folder.ID == id && !placed[folder] {
+				entries = append(entries, Entry{Folder: folder})
+				placed[folder] = true
+			}
+		}
+	}
+	for _, req := range requests {
+		if req != nil && !placed[req] {
+			entries = append(entries, Entry{Request: req})
+		}
+	}
+	for _, folder := range folders {
+		if folder != nil && !placed[folder] {
+			entries = append(entries, Entry{Folder: folder})
+		}
+	}
+	return entries
+}
+
+// AllRequests lists the folder's requests and those of its subfolders in entry order, depth
+// first.
+func (f *Folder) AllRequests() []*Request {
+	out := make([]*Request, 0)
+	if f == nil {
+		return out
+	}
+	return collectRequests(out, f.Entries(), 0)
+}
+
+// AllRequests lists the collection's requests and those of its folders in entry order, depth
+// first. This is the order of a collection run.
+func (c *Collection) AllRequests() []*Request {
+	out := make([]*Request, 0)
+	if c == nil {
+		return out
+	}
+	return collectRequests(out, c.Entries(), 0)
+}
+
+func collectRequests(out []*Request, entries []Entry, depth int) []*Request {
+	for _, entry := range entries {
+		if entry.Request != nil {
+			out = append(out, entry.Request)
+			continue
+		}
+		if depth < maxFolderDepth {
+			out = collectRequests(out, entry.Folder.Entries(), depth+1)
+		}
+	}
+	return out
+}
+
+// FolderPath returns the folders containing req from the outermost inwards, or nil when req
+// sits at the top level or is not part of the collection.
+func (c *Collection) FolderPath(req *Request) []*Folder {
+	if c == nil || req == nil {
+		return nil
+	}
+	path, _ := findFolderPath(c.Folders, req, nil, 0)
+	return path
+}
+
+func findFolderPath(folders []*Folder, req *Request, trail []*Folder, depth int) ([]*Folder, bool) {
+	if depth >= maxFolderDepth {
+		return nil, false
+	}
+	for _, folder := range folders {
+		if folder == nil {
+			continue
+		}
+		here := append(append([]*Folder(nil), trail...), folder)
+		for _, r := range folder.Requests {
+			if r == req {
+				return here, true
+			}
+		}
+		if path, ok := findFolderPath(folder.Folders, req, here, depth+1); ok {
+			return path, true
+		}
+	}
+	return nil, false
+}
+
+// FindFolder resolves a slash-separated path such as "users/admin", matching each segment
+// against folder IDs first and then names.
+func (c *Collection) FindFolder(path string) (*Folder, error) {
+	if c == nil {
+		return nil, fmt.Errorf("collection is nil")
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) == 0 || segments[0] == "" {
+		return nil, fmt.Errorf("folder path is empty")
+	}
+
+	folders := c.Folders
+	var found *Folder
+	for i, segment := range segments {
+		found = matchFolder(folders, segment)
+		if found == nil {
+			return nil, fmt.Errorf("folder %q not found", strings.Join(segments[:i+1], "/"))
+		}
+		folders = found.Folders
+	}
+	return found, nil
+}
+
+func matchFolder(folders []*Folder, segment string) *Folder {
+	for _, folder := range folders {
+		if folder != nil && folder.ID != "" && folder.ID == segment {
+			return folder
+		}
+	}
+	for _, folder := range folders {
+		if folder != nil && folder.Name == segment {
+			return folder
+		}
+	}
+	return nil
+}
+
+// ApplyFolders returns a copy of req with the headers and variables of folders (outermost
+// first) filled in wherever req does not set them itself.
+func ApplyFolders(folders []*Folder, req *Request) *Request {
+	clone := req.Clone()
+	if clone == nil || len(folders) == 0 {
+		return clone
+	}
+
+	headers := make(map[string]string)
+	variables := make(map[string]string)
+	for _, folder := range folders {
+		mergeHeaders(headers, folder.Headers)
+		copyInto(variables, folder.Variables)
+	}
+
+	if len(headers) > 0 {
+		mergeHeaders(headers, clone.Headers)
+		clone.Headers = headers
+	}
+	if len(variables) > 0 {
+		copyInto(variables, clone.Variables)
+		clone.Variables = variables
+	}
+	return clone
+}
+
+// mergeHeaders copies src into dst. Header names are case-insensitive, so "authorization" in
+// src replaces "Authorization" in dst instead of sending both.
+func mergeHeaders(dst, src map[string]string) {
+	for name, value := range src {
+		for existing := range dst {
+			if existing != name && strings.EqualFold(existing, name) {
+				delete(dst, existing)
+			}
+		}
+		dst[name] = value
+	}
+}
@@ -15,6 +15,25 @@ type HistoryEntry struct {
 	Files     []FileUpload      `json:"files,omitempty" yaml:"files,omitempty"`
 	Protocol  string            `json:"protocol" yaml:"protocol"`
 	Timestamp time.Time         `json:"timestamp" yaml:"timestamp"`
+	// Response summary; StatusCode is zero when no response was received and Error says why.
+	StatusCode   int    `json:"status_code,omitempty" yaml:"status_code,omitempty"`
+	DurationMs   int64  `json:"duration_ms,omitempty" yaml:"duration_ms,omitempty"`
+	ResponseSize int    `json:"response_size,omitempty" yaml:"response_size,omitempty"`
+	Error        string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Source is where the request was made ("tui" or "cli"); Environment is the environment it
+	// was resolved with, if any.
+	Source      string `json:"source,omitempty" yaml:"source,omitempty"`
+	Environment string `json:"environment,omitempty" yaml:"environment,omitempty"`
+}
+
+// SetResponse records the summary of resp; a nil resp leaves the entry unchanged.
+func (e *HistoryEntry) SetResponse(resp *Response) {
+	if resp == nil {
+		return
+	}
+	e.StatusCode = resp.StatusCode
+	e.DurationMs = resp.Duration.Milliseconds()
+	e.ResponseSize = len(resp.Body)
 }
 
 func generateHistoryID() string {
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operators shared by every assertion type that checks a single value.
+const (
+	OpEquals      = "equals"
+	OpNotEquals   = "not_equals"
+	OpContains    = "contains"
+	OpNotContains = "not_contains"
+	OpMatches     = "matches"
+	OpGreater     = "gt"
+	OpGreaterEq   = "gte"
+	OpLess        = "lt"
+	OpLessEq      = "lte"
+	OpExists      = "exists"
+	OpNotExists   = "not_exists"
+)
+
+// checkValue applies assertion.Operator to actual, the value found for subject (e.g. "Header X-Id").
+// found is false when nothing matched; only not_exists can pass then.
+func checkValue(assertion Assertion, subject string, actual string, found bool) AssertionResult {
+	result := AssertionResult{Assertion: assertion}
+	expected := assertion.Value
+
+	if !found {
+		if assertion.Operator == OpNotExists {
+			result.Passed = true
+			result.Message = fmt.Sprintf("%s does not exist", subject)
+			return result
+		}
+		result.Message = fmt.Sprintf("%s not found", subject)
+		return result
+	}
+
+	switch assertion.Operator {
+	case OpExists:
+		result.Passed = true
+		result.Message = fmt.Sprintf("%s exists", subject)
+	case OpNotExists:
+		result.Message = fmt.Sprintf("%s exists", subject)
+	case OpEquals:
+		result.Passed = actual == expected
+		result.Message = fmt.Sprintf("%s is %s", subject, actual)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("Expected %s but got %s", expected, actual)
+		}
+	case OpNotEquals:
+		result.Passed = actual != expected
+		result.Message = fmt.Sprintf("%s is not %s", subject, expected)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("%s should not be %s", subject, expected)
+		}
+	case OpContains:
+		result.Passed = strings.Contains(actual, expected)
+		result.Message = fmt.Sprintf("%s contains %s", subject, expected)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("%s does not contain %s", subject, expected)
+		}
+	case OpNotContains:
+		result.Passed = !strings.Contains(actual, expected)
+		result.Message = fmt.Sprintf("%s does not contain %s", subject, expected)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("%s contains %s", subject, expected)
+		}
+	case OpMatches:
+		return checkMatches(result, subject, actual)
+	case OpGreater, OpGreaterEq, OpLess, OpLessEq:
+		return checkNumber(result, subject, actual)
+	default:
+		result.Message = fmt.Sprintf("Invalid operator for %s", assertion.Type)
+	}
+
+	return result
+}
+
+func checkMatches(result AssertionResult, subject string, actual string) AssertionResult {
+	pattern := result.Assertion.Value
+	if len(pattern) > maxRegexPatternLength {
+		result.Message = "Regex pattern too long (max 4KB)"
+		return result
+	}
+	if len(actual) > 1024*1024 {
+		result.Message = "Value too large for regex matching (max 1MB)"
+		return result
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		result.Message = "Invalid regex pattern"
+		return result
+	}
+
+	result.Passed = re.MatchString(actual)
+	result.Message = fmt.Sprintf("%s matches %s", subject, pattern)
+	if !result.Passed {
+		result.Message = fmt.Sprintf("%s does not match %s", subject, pattern)
+	}
+	return result
+}
+
+var numericSymbols = map[string]string{OpGreater: ">", OpGreaterEq: ">=", OpLess: "<", OpLessEq: "<="}
+
+func checkNumber(result AssertionResult, subject string, actual string) AssertionResult {
+	op := result.Assertion.Operator
+	expected := result.Assertion.Value
+
+	a, err := strconv.ParseFloat(strings.TrimSpace(actual), 64)
+	if err != nil {
+		result.Message = fmt.Sprintf("%s is not a number: %s", subject, actual)
+		return result
+	}
+	e, err := strconv.ParseFloat(strings.TrimSpace(expected), 64)
+	if err != nil {
+		result.Message = fmt.Sprintf("Expected value is not a number: %s", expected)
+		return result
+	}
+
+	switch op {
+	case OpGreater:
+		result.Passed = a > e
+	case OpGreaterEq:
+		result.Passed = a >= e
+	case OpLess:
+		result.Passed = a < e
+	case OpLessEq:
+		result.Passed = a <= e
+	}
+
+	result.Message = fmt.Sprintf("%s is %s (%s %s)", subject, actual, numericSymbols[op], expected)
+	if !result.Passed {
+		result.Message = fmt.Sprintf("Expected %s %s %s but got %s", subject, numericSymbols[op], expected, actual)
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultPollInterval = time.Second
+	minPollInterval     = 100 * time.Millisecond
+	maxPollInterval     = 5 * time.Minute
+	defaultPollAttempts = 10
+	maxPollAttempts     = 1000
+	maxPollTimeout      = 30 * time.Minute
+)
+
+// Polling repeats a request until every Until assertion passes, MaxAttempts is reached or
+// TimeoutSeconds elapses. Without Until the request's own assertions are used. Each wait is
+// IntervalMs multiplied by Backoff after every attempt, capped at MaxIntervalMs.
+type Polling struct {
+	Until          []Assertion `json:"until,omitempty" yaml:"until,omitempty"`
+	IntervalMs     int         `json:"interval_ms,omitempty" yaml:"interval_ms,omitempty"`
+	Backoff        float64     `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	MaxIntervalMs  int         `json:"max_interval_ms,omitempty" yaml:"max_interval_ms,omitempty"`
+	MaxAttempts    int         `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	TimeoutSeconds int         `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
+}
+
+// Conditions returns the assertions that end polling, falling back to the request's assertions.
+func (p *Polling) Conditions(fallback []Assertion) []Assertion {
+	if len(p.Until) > 0 {
+		return p.Until
+	}
+	return fallback
+}
+
+// Attempts is the maximum number of requests to send. A timeout without an attempt limit allows
+// up to maxPollAttempts; with neither, defaultPollAttempts are made.
+func (p *Polling) Attempts() int {
+	if p.MaxAttempts > 0 {
+		if p.MaxAttempts > maxPollAttempts {
+			return maxPollAttempts
+		}
+		return p.MaxAttempts
+	}
+	if p.TimeoutSeconds > 0 {
+		return maxPollAttempts
+	}
+	return defaultPollAttempts
+}
+
+// Timeout is the overall polling deadline, or zero when only the attempt limit applies.
+func (p *Polling) Timeout() time.Duration {
+	if p.TimeoutSeconds <= 0 {
+		return 0
+	}
+	t := time.Duration(p.TimeoutSeconds) * time.Second
+	if t > maxPollTimeout {
+		return maxPollTimeout
+	}
+	return t
+}
+
+// Delay is the wait after the given 1-based attempt.
+func (p *Polling) Delay(attempt int) time.Duration {
+	delay := defaultPollInterval
+	if p.IntervalMs > 0 {
+		delay = time.Duration(p.IntervalMs) * time.Millisecond
+	}
+
+	limit := maxPollInterval
+	if p.MaxIntervalMs > 0 && time.Duration(p.MaxIntervalMs)*time.Millisecond < limit {
+		limit = time.Duration(p.MaxIntervalMs) * time.Millisecond
+	}
+
+	if p.Backoff > 1 {
+		for i := 1; i < attempt && delay < limit; i++ {
+			delay = time.Duration(float64(delay) * p.Backoff)
+		}
+	}
+
+	if delay < minPollInterval {
+		delay = minPollInterval
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// CheckConditions validates every condition against resp and returns the failed results.
+func CheckConditions(conditions []Assertion, resp *Response) []AssertionResult {
+	failed := make([]AssertionResult, 0)
+	for _, condition := range conditions {
+		result := ValidateAssertion(condition, resp)
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// PollFailure describes polling that ended before its conditions passed, as an assertion result.
+func PollFailure(attempts int, timedOut bool, failed []AssertionResult) AssertionResult {
+	reason := fmt.Sprintf("Condition not met after %d attempts", attempts)
+	if timedOut {
+		reason = fmt.Sprintf("Condition not met before timeout (%d attempts)", attempts)
+	}
+	if len(failed) > 0 {
+		reason += ": " + failed[0].Message
+	}
+	return AssertionResult{
+		Assertion: Assertion{Type: AssertPoll},
+		Passed:    false,
+		Message:   reason,
+	}
+}
@@ -3,21 +3,45 @@ package model
 import "time"
 
 type Request struct {
-	ID             string            `json:"id" yaml:"id"`
-	Name           string            `json:"name" yaml:"name"`
-	Method         string            `json:"method" yaml:"method"`
-	URL            string            `json:"url" yaml:"url"`
-	Query          map[string]string  `json:"query,omitempty" yaml:"query,omitempty"`
-	Headers        map[string]string `json:"headers" yaml:"headers"`
-	Body           string            `json:"body" yaml:"body"`
-	Files          []FileUpload      `json:"files,omitempty" yaml:"files,omitempty"`
-	TimeoutSeconds int               `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
-	CreatedAt      time.Time         `json:"created_at" yaml:"created_at"`
-	CollectionID   string            `json:"collection_id" yaml:"collection_id"`
-	Assertions     []Assertion       `json:"assertions,omitempty" yaml:"assertions,omitempty"`
-	Extractors     []Extractor       `json:"extractors,omitempty" yaml:"extractors,omitempty"`
+	ID                 string            `json:"id" yaml:"id"`
+	Name               string            `json:"name" yaml:"name"`
+	Method             string            `json:"method" yaml:"method"`
+	URL                string            `json:"url" yaml:"url"`
+	Query              map[string]string `json:"query,omitempty" yaml:"query,omitempty"`
+	Variables          map[string]string `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Headers            map[string]string `json:"headers" yaml:"headers"`
+	Body               string            `json:"body" yaml:"body"`
+	Files              []FileUpload      `json:"files,omitempty" yaml:"files,omitempty"`
+	TimeoutSeconds     int               `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
+	CreatedAt          time.Time         `json:"created_at" yaml:"created_at"`
+	CollectionID       string            `json:"collection_id" yaml:"collection_id"`
+	Assertions         []Assertion       `json:"assertions,omitempty" yaml:"assertions,omitempty"`
+	Extractors         []Extractor       `json:"extractors,omitempty" yaml:"extractors,omitempty"`
+	PreRequestScript   string            `json:"pre_request_script,omitempty" yaml:"pre_request_script,omitempty"`
+	PostResponseScript string            `json:"post_response_script,omitempty" yaml:"post_response_script,omitempty"`
+	// Sequential makes a parallel run wait for every earlier request before starting this one,
+	// and hold back later requests until it has finished.
+	Sequential bool `json:"sequential,omitempty" yaml:"sequential,omitempty"`
+	// DependsOn lists the names or IDs of requests that must pass before this one runs.
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	// RunIf and SkipIf are script expressions evaluated against the run's variables.
+	RunIf  string `json:"run_if,omitempty" yaml:"run_if,omitempty"`
+	SkipIf string `json:"skip_if,omitempty" yaml:"skip_if,omitempty"`
+	// Stage is empty, StageSetup or StageTeardown.
+	Stage string `json:"stage,omitempty" yaml:"stage,omitempty"`
+	// Poll repeats the request until its conditions pass; see Polling.
+	Poll *Polling `json:"poll,omitempty" yaml:"poll,omitempty"`
+	// Retry overrides the default retry policy for this request.
+	Retry *RetryPolicy `json:"retry,omitempty" yaml:"retry,omitempty"`
 }
 
+// Request stages in a collection run: setup requests run before all others, teardown requests
+// after them and even when earlier requests failed or the run was stopped.
+const (
+	StageSetup    = "setup"
+	StageTeardown = "teardown"
+)
+
 type Response struct {
 	StatusCode int               `json:"status_code"`
 	Headers    map[string]string `json:"headers"`
@@ -25,3 +49,49 @@ type Response struct {
 	Duration   time.Duration     `json:"duration"`
 	Timestamp  time.Time         `json:"timestamp"`
 }
+
+// Clone returns a deep copy of r so callers can mutate headers, query and body
+// (e.g. for variable substitution or scripts) without touching the stored request.
+func (r *Request) Clone() *Request {
+	if r == nil {
+		return nil
+	}
+
+	clone := *r
+	clone.Headers = copyStringMap(r.Headers)
+	clone.Query = copyStringMap(r.Query)
+	clone.Variables = copyStringMap(r.Variables)
+	if r.Files != nil {
+		clone.Files = append([]FileUpload(nil), r.Files...)
+	}
+	if r.Assertions != nil {
+		clone.Assertions = append([]Assertion(nil), r.Assertions...)
+	}
+	if r.Extractors != nil {
+		clone.Extractors = append([]Extractor(nil), r.Extractors...)
+	}
+	if r.DependsOn != nil {
+		clone.DependsOn = append([]string(nil), r.DependsOn...)
+	}
+	if r.Poll != nil {
+		poll := *r.Poll
+		poll.Until = append([]Assertion(nil), r.Poll.Until...)
+		clone.Poll = &poll
+	}
+	if r.Retry != nil {
+		retry := *r.Retry
+		clone.Retry = &retry
+	}
+	return &clone
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
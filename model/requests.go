@@ -0,0 +1,137 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FindRequest resolves ref against request IDs first and then names, like depends_on. A name
+// shared by several requests is an error listing their IDs.
+func (c *Collection) FindRequest(ref string) (*Request, error) {
+	if c == nil {
+		return nil, fmt.Errorf("collection is nil")
+	}
+	requests := c.AllRequests()
+	for _, req := range requests {
+		if req.ID != "" && req.ID == ref {
+			return req, nil
+		}
+	}
+
+	matches := make([]*Request, 0, 1)
+	for _, req := range requests {
+		if req.Name == ref {
+			matches = append(matches, req)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("request %q not found in collection %s", ref, c.ID)
+	}
+	if len(matches) > 1 {
+		ids := make([]string, 0, len(matches))
+		for _, req := range matches {
+			ids = append(ids, req.ID)
+		}
+		return nil, fmt.Errorf("request name %q is ambiguous; use one of the IDs %s", ref, strings.Join(ids, ", "))
+	}
+	return matches[0], nil
+}
+
+// RemoveRequest takes req out of the collection or whichever folder holds it and reports
+// whether it was found.
+func (c *Collection) RemoveRequest(req *Request) bool {
+	if c == nil || req == nil {
+		return false
+	}
+	if removed, ok := without(c.Requests, req); ok {
+		c.Requests = removed
+		return true
+	}
+	folders := c.FolderPath(req)
+	if len(folders) == 0 {
+		return false
+	}
+	folder := folders[len(folders)-1]
+	folder.Requests, _ = without(folder.Requests, req)
+	return true
+}
+
+// InsertRequest adds req to folder, or to the top level when folder is nil, at the 1-based
+// position among its requests; positions outside the list append it.
+func (c *Collection) InsertRequest(req *Request, folder *Folder, position int) {
+	if c == nil || req == nil {
+		return
+	}
+	if folder == nil {
+		c.Requests = inserted(c.Requests, req, position)
+		c.Order = reordered(c.Order, c.Requests, c.Folders)
+		return
+	}
+	folder.Requests = inserted(folder.Requests, req, position)
+	folder.Order = reordered(folder.Order, folder.Requests, folder.Folders)
+}
+
+// Dependents returns the requests whose depends_on refers to req by ID or name.
+func (c *Collection) Dependents(req *Request) []*Request {
+	out := make([]*Request, 0)
+	if c == nil || req == nil {
+		return out
+	}
+	for _, other := range c.AllRequests() {
+		if other == req {
+			continue
+		}
+		for _, ref := range other.DependsOn {
+			if (req.ID != "" && ref == req.ID) || (req.Name != "" && ref == req.Name) {
+				out = append(out, other)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func without(requests []*Request, req *Request) ([]*Request, bool) {
+	for i, r := range requests {
+		if r == req {
+			return append(requests[:i:i], requests[i+1:]...), true
+		}
+	}
+	return requests, false
+}
+
+// reordered rewrites an order in use so the requests it lists follow requests, while folders
+// keep their places.
+func reordered(order []string, requests []*Request, folders []*Folder) []string {
+	if len(order) == 0 {
+		return order
+	}
+	listed := make([]*Request, 0, len(requests))
+	for _, req := range requests {
+		if req != nil {
+			listed = append(listed, req)
+		}
+	}
+
+	out := make([]string, 0, len(order)+1)
+	next := 0
+	for _, entry := range OrderEntries(requests, folders, order) {
+		if entry.Folder != nil {
+			out = append(out, entry.Folder.ID)
+			continue
+		}
+		out = append(out, listed[next].ID)
+		next++
+	}
+	return out
+}
+
+func inserted(requests []*Request, req *Request, position int) []*Request {
+	if position < 1 || position > len(requests) {
+		return append(requests, req)
+	}
+	out := make([]*Request, 0, len(requests)+1)
+	out = append(out, requests[:position-1]...)
+	out = append(out, req)
+	return append(out, requests[position-1:]...)
+}
@@ -0,0 +1,99 @@
+package model
+
+import "sync"
+
+// GlobalsEnvironment is the name of the environment whose variables form the globals layer.
+const GlobalsEnvironment = "globals"
+
+// Scope layers variables from lowest to highest precedence: globals, collection variables,
+// the selected environment, the current data row of a data-driven run, run-scoped runtime
+// values (extractors and script writes) and per-request overrides. Only the runtime layer is
+// written to during a run. A Scope is safe for concurrent use by requests of a parallel run.
+type Scope struct {
+	mu sync.Mutex
+
+	Globals     map[string]string
+	Collection  map[string]string
+	Environment map[string]string
+	Data        map[string]string
+	Runtime     map[string]string
+}
+
+func NewScope(globals, collection, environment map[string]string) *Scope {
+	return &Scope{
+		Globals:     globals,
+		Collection:  collection,
+		Environment: environment,
+		Runtime:     make(map[string]string),
+	}
+}
+
+// Resolve merges every layer plus the per-request overrides into a fresh map.
+func (s *Scope) Resolve(overrides map[string]string) map[string]string {
+	out := make(map[string]string)
+	if s == nil {
+		copyInto(out, overrides)
+		return out
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copyInto(out, s.Globals)
+	copyInto(out, s.Collection)
+	copyInto(out, s.Environment)
+	copyInto(out, s.Data)
+	copyInto(out, s.Runtime)
+	copyInto(out, overrides)
+	return out
+}
+
+// Set writes key to the runtime layer.
+func (s *Scope) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(key, value)
+}
+
+func (s *Scope) set(key, value string) {
+	if s.Runtime == nil {
+		s.Runtime = make(map[string]string)
+	}
+	s.Runtime[key] = value
+}
+
+// Commit moves the differences between before and after (as left by a script) into the runtime layer.
+func (s *Scope) Commit(before, after map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range after {
+		if old, ok := before[k]; !ok || old != v {
+			s.set(k, v)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			delete(s.Runtime, k)
+		}
+	}
+}
+
+// Snapshot returns a copy of the runtime layer.
+func (s *Scope) Snapshot() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]string, len(s.Runtime))
+	copyInto(out, s.Runtime)
+	return out
+}
+
+// CloneVariables copies a variable map so a script's writes can later be diffed with Scope.Commit.
+func CloneVariables(vars map[string]string) map[string]string {
+	out := make(map[string]string, len(vars))
+	copyInto(out, vars)
+	return out
+}
+
+func copyInto(dst, src map[string]string) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
@@ -0,0 +1,309 @@
+package interp
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"raco/util/func/template"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type builtin func(args []interface{}) (interface{}, error)
+
+const (
+	maxRegexPatternLength = 4096
+	maxRegexInputLength   = 1024 * 1024
+)
+
+var builtins map[string]builtin
+
+func init() {
+	builtins = map[string]builtin{
+		"len":        fnLen,
+		"str":        fnStr,
+		"num":        fnNum,
+		"upper":      stringFn(strings.ToUpper),
+		"lower":      stringFn(strings.ToLower),
+		"trim":       stringFn(strings.TrimSpace),
+		"contains":   fnContains,
+		"startsWith": fnStartsWith,
+		"endsWith":   fnEndsWith,
+		"replace":    fnReplace,
+		"split":      fnSplit,
+		"join":       fnJoin,
+		"substr":     fnSubstr,
+		"matches":    fnMatches,
+		"keys":       fnKeys,
+		"json":       fnJSON,
+		"stringify":  fnStringify,
+		"timestamp":  fnTimestamp,
+		"randomInt":  fnRandomInt,
+	}
+
+	// The string helpers shared with {{$fn}} placeholders come from the template package so both
+	// behave identically. processEnv is left out to keep scripts away from the host environment.
+	for _, name := range template.Names() {
+		if _, ok := builtins[name]; ok || name == "processEnv" {
+			continue
+		}
+		builtins[name] = templateFn(name)
+	}
+}
+
+func templateFn(name string) builtin {
+	return func(args []interface{}) (interface{}, error) {
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			strs[i] = toString(arg)
+		}
+		return template.Call(name, strs)
+	}
+}
+
+func expectArgs(args []interface{}, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("expected %d argument(s), got %d", min, len(args))
+		}
+		return fmt.Errorf("expected %d-%d arguments, got %d", min, max, len(args))
+	}
+	return nil
+}
+
+func stringFn(f func(string) string) builtin {
+	return func(args []interface{}) (interface{}, error) {
+		if err := expectArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		return f(toString(args[0])), nil
+	}
+}
+
+func fnLen(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case []interface{}:
+		return float64(len(v)), nil
+	case map[string]interface{}:
+		return float64(len(v)), nil
+	case object:
+		return float64(len(v.keys())), nil
+	case nil:
+		return float64(0), nil
+	}
+	return float64(len(toString(args[0]))), nil
+}
+
+func fnStr(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return toString(args[0]), nil
+}
+
+func fnNum(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	n, ok := toNumber(args[0])
+	if !ok {
+		return nil, fmt.Errorf("%q is not a number", toString(args[0]))
+	}
+	return n, nil
+}
+
+func fnContains(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if valuesEqual(item, args[1]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		_, ok := v[toString(args[1])]
+		return ok, nil
+	case object:
+		item, err := v.get(toString(args[1]))
+		return err == nil && item != nil, nil
+	}
+	return strings.Contains(toString(args[0]), toString(args[1])), nil
+}
+
+func fnStartsWith(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+}
+
+func fnEndsWith(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+}
+
+func fnReplace(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 3, 3); err != nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(toString(args[0]), toString(args[1]), toString(args[2])), nil
+}
+
+func fnSplit(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	parts := strings.Split(toString(args[0]), toString(args[1]))
+	out := make([]interface{}, len(parts))
+	for i, p := range parts {
+		out[i] = p
+	}
+	return out, nil
+}
+
+func fnJoin(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	list, ok := args[0].([]interface{})
+	if !ok {
+		return nil, errors.New("first argument must be a list")
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		parts[i] = toString(item)
+	}
+	return strings.Join(parts, toString(args[1])), nil
+}
+
+func fnSubstr(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 2, 3); err != nil {
+		return nil, err
+	}
+	s := toString(args[0])
+	start, ok := toNumber(args[1])
+	if !ok {
+		return nil, errors.New("start must be a number")
+	}
+	end := float64(len(s))
+	if len(args) == 3 {
+		end, ok = toNumber(args[2])
+		if !ok {
+			return nil, errors.New("end must be a number")
+		}
+	}
+	from := clamp(int(start), 0, len(s))
+	to := clamp(int(end), from, len(s))
+	return s[from:to], nil
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func fnMatches(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	input := toString(args[0])
+	pattern := toString(args[1])
+	if len(pattern) > maxRegexPatternLength {
+		return nil, errors.New("regex pattern too long (max 4KB)")
+	}
+	if len(input) > maxRegexInputLength {
+		return nil, errors.New("input too large for regex matching (max 1MB)")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	return re.MatchString(input), nil
+}
+
+func fnKeys(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	var keys []string
+	switch v := args[0].(type) {
+	case map[string]interface{}:
+		keys = sortedKeys(v)
+	case object:
+		keys = v.keys()
+	default:
+		return nil, fmt.Errorf("cannot list keys of %s", typeName(args[0]))
+	}
+	out := make([]interface{}, len(keys))
+	for i, k := range keys {
+		out[i] = k
+	}
+	return out, nil
+}
+
+func fnJSON(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(toString(args[0])), &data); err != nil {
+		return nil, errors.New("invalid JSON")
+	}
+	return data, nil
+}
+
+func fnStringify(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	if s, ok := args[0].(string); ok {
+		data, _ := json.Marshal(s)
+		return string(data), nil
+	}
+	return toString(args[0]), nil
+}
+
+func fnTimestamp(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 0, 0); err != nil {
+		return nil, err
+	}
+	return float64(time.Now().Unix()), nil
+}
+
+func fnRandomInt(args []interface{}) (interface{}, error) {
+	if err := expectArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	lo, lok := toNumber(args[0])
+	hi, hok := toNumber(args[1])
+	if !lok || !hok || hi < lo {
+		return nil, errors.New("expected min <= max")
+	}
+	span := int64(math.Floor(hi)) - int64(math.Ceil(lo)) + 1
+	if span <= 0 {
+		return nil, errors.New("no integer between min and max")
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(span))
+	if err != nil {
+		return nil, err
+	}
+	return float64(int64(math.Ceil(lo)) + n.Int64()), nil
+}
@@ -0,0 +1,66 @@
+package interp
+
+import "fmt"
+
+// Check parses src without running it and returns the names of the env variables it assigns
+// with env.NAME = ... or env["NAME"] = ..., in order of appearance.
+func Check(src string) ([]string, error) {
+	if len(src) > maxSourceLength {
+		return nil, fmt.Errorf("script too long (max %dKB)", maxSourceLength/1024)
+	}
+
+	program, err := parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("syntax error: %w", err)
+	}
+
+	names := make([]string, 0)
+	collectAssigned(program, &names)
+	return names, nil
+}
+
+// CheckCondition parses src as a single expression without evaluating it.
+func CheckCondition(src string) error {
+	if len(src) > maxSourceLength {
+		return fmt.Errorf("expression too long (max %dKB)", maxSourceLength/1024)
+	}
+
+	if _, err := parseExpression(src); err != nil {
+		return fmt.Errorf("syntax error: %w", err)
+	}
+	return nil
+}
+
+func collectAssigned(block []node, names *[]string) {
+	for _, stmt := range block {
+		switch s := stmt.(type) {
+		case *assignStmt:
+			if name, ok := envKey(s.target); ok {
+				*names = append(*names, name)
+			}
+		case *ifStmt:
+			collectAssigned(s.body, names)
+			collectAssigned(s.elseBody, names)
+		case *forStmt:
+			collectAssigned(s.body, names)
+		}
+	}
+}
+
+// envKey returns NAME for env.NAME and env["NAME"].
+func envKey(target node) (string, bool) {
+	member, ok := target.(*memberExpr)
+	if !ok {
+		return "", false
+	}
+	object, ok := member.object.(*identExpr)
+	if !ok || object.name != "env" {
+		return "", false
+	}
+	key, ok := member.key.(*literal)
+	if !ok {
+		return "", false
+	}
+	name, ok := key.value.(string)
+	return name, ok
+}
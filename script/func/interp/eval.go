@@ -0,0 +1,606 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"raco/model"
+	"time"
+)
+
+const (
+	maxSourceLength = 64 * 1024
+	maxVariables    = 1000
+	maxLogLines     = 200
+	maxCallDepth    = 64
+)
+
+// Limits bounds a single script execution so a broken or hostile script cannot hang or exhaust raco.
+type Limits struct {
+	Timeout   time.Duration
+	MaxSteps  int
+	MaxMemory int
+}
+
+// Env is what a script can see: variables it may read and write, the outgoing request and,
+// after execution, the response. RequestReadOnly is set for post-response hooks.
+type Env struct {
+	Variables       map[string]string
+	Request         *model.Request
+	Response        *model.Response
+	RequestReadOnly bool
+}
+
+type TestResult struct {
+	Name   string
+	Passed bool
+}
+
+type Output struct {
+	Tests []TestResult
+	Logs  []string
+	// Next is set when the script called setNextRequest; an empty name (setNextRequest(null))
+	// asks the runner to stop after the current request.
+	Next *string
+}
+
+type interpreter struct {
+	ctx    context.Context
+	limits Limits
+	steps  int
+	memory int
+	depth  int
+	scopes []map[string]interface{}
+	hosts  map[string]object
+	out    *Output
+}
+
+type failError struct {
+	message string
+}
+
+func (f *failError) Error() string {
+	return f.message
+}
+
+func Run(src string, env *Env, limits Limits) (*Output, error) {
+	out := &Output{
+		Tests: make([]TestResult, 0),
+		Logs:  make([]string, 0),
+	}
+
+	if len(src) > maxSourceLength {
+		return out, fmt.Errorf("script too long (max %dKB)", maxSourceLength/1024)
+	}
+
+	program, err := parse(src)
+	if err != nil {
+		return out, fmt.Errorf("syntax error: %w", err)
+	}
+
+	in, cancel := newInterpreter(env, limits, out)
+	defer cancel()
+
+	return out, in.execBlock(program)
+}
+
+// Condition evaluates a single expression, such as a request's run_if, against env and
+// reports whether the result is truthy.
+func Condition(src string, env *Env, limits Limits) (bool, error) {
+	if len(src) > maxSourceLength {
+		return false, fmt.Errorf("expression too long (max %dKB)", maxSourceLength/1024)
+	}
+
+	expr, err := parseExpression(src)
+	if err != nil {
+		return false, fmt.Errorf("syntax error: %w", err)
+	}
+
+	in, cancel := newInterpreter(env, limits, &Output{})
+	defer cancel()
+
+	value, err := in.eval(expr)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+func newInterpreter(env *Env, limits Limits, out *Output) (*interpreter, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), limits.Timeout)
+
+	in := &interpreter{
+		ctx:    ctx,
+		limits: limits,
+		scopes: []map[string]interface{}{make(map[string]interface{})},
+		hosts:  make(map[string]object),
+		out:    out,
+	}
+
+	if env.Variables != nil {
+		in.hosts["env"] = &envObject{vars: env.Variables, in: in}
+	}
+	if env.Request != nil {
+		in.hosts["request"] = &requestObject{req: env.Request, readOnly: env.RequestReadOnly, in: in}
+	}
+	if env.Response != nil {
+		in.hosts["response"] = &responseObject{resp: env.Response, in: in}
+	}
+
+	return in, cancel
+}
+
+func (in *interpreter) step() error {
+	in.steps++
+	if in.limits.MaxSteps > 0 && in.steps > in.limits.MaxSteps {
+		return fmt.Errorf("script exceeded step limit (%d)", in.limits.MaxSteps)
+	}
+	if in.steps%64 == 0 && in.ctx.Err() != nil {
+		return fmt.Errorf("script exceeded time limit (%s)", in.limits.Timeout)
+	}
+	return nil
+}
+
+func (in *interpreter) alloc(size int) error {
+	in.memory += size
+	if in.limits.MaxMemory > 0 && in.memory > in.limits.MaxMemory {
+		return fmt.Errorf("script exceeded memory limit (%d bytes)", in.limits.MaxMemory)
+	}
+	return nil
+}
+
+func (in *interpreter) pushScope() {
+	in.scopes = append(in.scopes, make(map[string]interface{}))
+}
+
+func (in *interpreter) popScope() {
+	in.scopes = in.scopes[:len(in.scopes)-1]
+}
+
+func (in *interpreter) lookupLocal(name string) (map[string]interface{}, bool) {
+	for i := len(in.scopes) - 1; i >= 0; i-- {
+		if _, ok := in.scopes[i][name]; ok {
+			return in.scopes[i], true
+		}
+	}
+	return nil, false
+}
+
+func (in *interpreter) execBlock(stmts []node) error {
+	for _, stmt := range stmts {
+		if err := in.exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *interpreter) exec(stmt node) error {
+	if err := in.step(); err != nil {
+		return err
+	}
+
+	switch s := stmt.(type) {
+	case *letStmt:
+		value, err := in.eval(s.value)
+		if err != nil {
+			return lineErr(s.line, err)
+		}
+		if _, isHost := in.hosts[s.name]; isHost {
+			return lineErr(s.line, fmt.Errorf("%s is reserved", s.name))
+		}
+		in.scopes[len(in.scopes)-1][s.name] = value
+		return nil
+
+	case *assignStmt:
+		value, err := in.eval(s.value)
+		if err != nil {
+			return lineErr(s.line, err)
+		}
+		return lineErr(s.line, in.assign(s.target, value))
+
+	case *deleteStmt:
+		return lineErr(s.line, in.remove(s.target))
+
+	case *ifStmt:
+		cond, err := in.eval(s.cond)
+		if err != nil {
+			return lineErr(s.line, err)
+		}
+		in.pushScope()
+		defer in.popScope()
+		if truthy(cond) {
+			return in.execBlock(s.body)
+		}
+		return in.execBlock(s.elseBody)
+
+	case *forStmt:
+		return in.execFor(s)
+
+	case *testStmt:
+		name, err := in.eval(s.name)
+		if err != nil {
+			return lineErr(s.line, err)
+		}
+		cond, err := in.eval(s.cond)
+		if err != nil {
+			return lineErr(s.line, err)
+		}
+		in.out.Tests = append(in.out.Tests, TestResult{Name: toString(name), Passed: truthy(cond)})
+		return nil
+
+	case *logStmt:
+		value, err := in.eval(s.value)
+		if err != nil {
+			return lineErr(s.line, err)
+		}
+		if len(in.out.Logs) < maxLogLines {
+			in.out.Logs = append(in.out.Logs, toString(value))
+		}
+		return nil
+
+	case *failStmt:
+		message, err := in.eval(s.message)
+		if err != nil {
+			return lineErr(s.line, err)
+		}
+		return &failError{message: toString(message)}
+
+	case *exprStmt:
+		_, err := in.eval(s.expr)
+		return lineErr(s.line, err)
+	}
+
+	return fmt.Errorf("unsupported statement %T", stmt)
+}
+
+func (in *interpreter) execFor(s *forStmt) error {
+	iter, err := in.eval(s.iter)
+	if err != nil {
+		return lineErr(s.line, err)
+	}
+
+	run := func(key, value interface{}) error {
+		if err := in.step(); err != nil {
+			return err
+		}
+		in.pushScope()
+		defer in.popScope()
+		scope := in.scopes[len(in.scopes)-1]
+		if s.key != "" {
+			scope[s.key] = key
+		}
+		scope[s.value] = value
+		return in.execBlock(s.body)
+	}
+
+	switch v := iter.(type) {
+	case []interface{}:
+		for i, item := range v {
+			if err := run(float64(i), item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			if err := run(k, v[k]); err != nil {
+				return err
+			}
+		}
+	case object:
+		for _, k := range v.keys() {
+			item, err := v.get(k)
+			if err != nil {
+				return lineErr(s.line, err)
+			}
+			if err := run(k, item); err != nil {
+				return err
+			}
+		}
+	case nil:
+		return nil
+	default:
+		return lineErr(s.line, fmt.Errorf("cannot iterate over %s", typeName(iter)))
+	}
+
+	return nil
+}
+
+func lineErr(line int, err error) error {
+	if err == nil {
+		return nil
+	}
+	var fail *failError
+	if errors.As(err, &fail) {
+		return err
+	}
+	return fmt.Errorf("line %d: %w", line, err)
+}
+
+func (in *interpreter) assign(target node, value interface{}) error {
+	switch t := target.(type) {
+	case *identExpr:
+		if _, isHost := in.hosts[t.name]; isHost {
+			return fmt.Errorf("%s cannot be reassigned", t.name)
+		}
+		scope, ok := in.lookupLocal(t.name)
+		if !ok {
+			return fmt.Errorf("undefined variable %s (declare it with let)", t.name)
+		}
+		scope[t.name] = value
+		return nil
+
+	case *memberExpr:
+		container, err := in.eval(t.object)
+		if err != nil {
+			return err
+		}
+		keyVal, err := in.eval(t.key)
+		if err != nil {
+			return err
+		}
+		return in.setMember(container, keyVal, value)
+	}
+
+	return errors.New("invalid assignment target")
+}
+
+func (in *interpreter) setMember(container, key, value interface{}) error {
+	switch c := container.(type) {
+	case mutableObject:
+		return c.set(toString(key), value)
+	case object:
+		return errors.New("value is read-only")
+	case map[string]interface{}:
+		c[toString(key)] = value
+		return nil
+	case []interface{}:
+		idx, ok := toNumber(key)
+		if !ok || idx < 0 || int(idx) >= len(c) {
+			return fmt.Errorf("list index %s out of range", toString(key))
+		}
+		c[int(idx)] = value
+		return nil
+	}
+	return fmt.Errorf("cannot set field on %s", typeName(container))
+}
+
+func (in *interpreter) remove(target node) error {
+	t, ok := target.(*memberExpr)
+	if !ok {
+		return errors.New("only fields can be deleted")
+	}
+
+	container, err := in.eval(t.object)
+	if err != nil {
+		return err
+	}
+	keyVal, err := in.eval(t.key)
+	if err != nil {
+		return err
+	}
+
+	switch c := container.(type) {
+	case mutableObject:
+		return c.del(toString(keyVal))
+	case map[string]interface{}:
+		delete(c, toString(keyVal))
+		return nil
+	}
+	return fmt.Errorf("cannot delete field on %s", typeName(container))
+}
+
+func (in *interpreter) eval(n node) (interface{}, error) {
+	if err := in.step(); err != nil {
+		return nil, err
+	}
+
+	switch e := n.(type) {
+	case *literal:
+		return e.value, nil
+
+	case *identExpr:
+		if scope, ok := in.lookupLocal(e.name); ok {
+			return scope[e.name], nil
+		}
+		if host, ok := in.hosts[e.name]; ok {
+			return host, nil
+		}
+		if e.name == "env" || e.name == "request" || e.name == "response" {
+			return nil, fmt.Errorf("%s is not available in this hook", e.name)
+		}
+		return nil, fmt.Errorf("undefined variable %s", e.name)
+
+	case *memberExpr:
+		container, err := in.eval(e.object)
+		if err != nil {
+			return nil, err
+		}
+		key, err := in.eval(e.key)
+		if err != nil {
+			return nil, err
+		}
+		return getMember(container, key)
+
+	case *listExpr:
+		items := make([]interface{}, 0, len(e.items))
+		for _, item := range e.items {
+			v, err := in.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+
+	case *callExpr:
+		return in.call(e)
+
+	case *unaryExpr:
+		v, err := in.eval(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		if e.op == "!" {
+			return !truthy(v), nil
+		}
+		num, ok := toNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %s", typeName(v))
+		}
+		return -num, nil
+
+	case *binaryExpr:
+		return in.evalBinary(e)
+	}
+
+	return nil, fmt.Errorf("unsupported expression %T", n)
+}
+
+func getMember(container, key interface{}) (interface{}, error) {
+	switch c := container.(type) {
+	case object:
+		return c.get(toString(key))
+	case map[string]interface{}:
+		return c[toString(key)], nil
+	case []interface{}:
+		if toString(key) == "length" {
+			return float64(len(c)), nil
+		}
+		idx, ok := toNumber(key)
+		if !ok {
+			return nil, fmt.Errorf("list index must be a number, got %q", toString(key))
+		}
+		i := int(idx)
+		if i < 0 {
+			i += len(c)
+		}
+		if i < 0 || i >= len(c) {
+			return nil, nil
+		}
+		return c[i], nil
+	case string:
+		if toString(key) == "length" {
+			return float64(len(c)), nil
+		}
+	case nil:
+		return nil, fmt.Errorf("cannot read %q of null", toString(key))
+	}
+	return nil, fmt.Errorf("cannot read %q of %s", toString(key), typeName(container))
+}
+
+func (in *interpreter) evalBinary(e *binaryExpr) (interface{}, error) {
+	left, err := in.eval(e.left)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.op == "&&" {
+		if !truthy(left) {
+			return left, nil
+		}
+		return in.eval(e.right)
+	}
+	if e.op == "||" {
+		if truthy(left) {
+			return left, nil
+		}
+		return in.eval(e.right)
+	}
+
+	right, err := in.eval(e.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "<":
+		return compareValues(left, right) < 0, nil
+	case "<=":
+		return compareValues(left, right) <= 0, nil
+	case ">":
+		return compareValues(left, right) > 0, nil
+	case ">=":
+		return compareValues(left, right) >= 0, nil
+	case "+":
+		ln, lok := left.(float64)
+		rn, rok := right.(float64)
+		if lok && rok {
+			return ln + rn, nil
+		}
+		str := toString(left) + toString(right)
+		if err := in.alloc(len(str)); err != nil {
+			return nil, err
+		}
+		return str, nil
+	}
+
+	ln, lok := toNumber(left)
+	rn, rok := toNumber(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %s needs numbers, got %s and %s", e.op, typeName(left), typeName(right))
+	}
+
+	switch e.op {
+	case "-":
+		return ln - rn, nil
+	case "*":
+		return ln * rn, nil
+	case "/":
+		if rn == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return ln / rn, nil
+	case "%":
+		if rn == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return math.Mod(ln, rn), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %s", e.op)
+}
+
+func (in *interpreter) call(e *callExpr) (interface{}, error) {
+	if e.name == "setNextRequest" {
+		return in.setNextRequest(e)
+	}
+
+	fn, ok := builtins[e.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s()", e.name)
+	}
+
+	in.depth++
+	defer func() { in.depth-- }()
+	if in.depth > maxCallDepth {
+		return nil, errors.New("expression nested too deeply")
+	}
+
+	args := make([]interface{}, 0, len(e.args))
+	for _, arg := range e.args {
+		v, err := in.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	result, err := fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", e.name, err)
+	}
+
+	if str, ok := result.(string); ok {
+		if err := in.alloc(len(str)); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,248 @@
+package interp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"raco/model"
+	"strings"
+)
+
+var (
+	errRequestSent   = errors.New("request is read-only after it has been sent")
+	errResponseFixed = errors.New("response is read-only")
+)
+
+type envObject struct {
+	vars map[string]string
+	in   *interpreter
+}
+
+func (e *envObject) get(key string) (interface{}, error) {
+	value, ok := e.vars[key]
+	if !ok {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func (e *envObject) keys() []string {
+	return sortedStringKeys(e.vars)
+}
+
+func (e *envObject) set(key string, value interface{}) error {
+	if key == "" {
+		return errors.New("variable name is empty")
+	}
+	if len(e.vars) >= maxVariables {
+		if _, exists := e.vars[key]; !exists {
+			return fmt.Errorf("too many variables (max %d)", maxVariables)
+		}
+	}
+	str := toString(value)
+	if err := e.in.alloc(len(str)); err != nil {
+		return err
+	}
+	e.vars[key] = str
+	return nil
+}
+
+func (e *envObject) del(key string) error {
+	delete(e.vars, key)
+	return nil
+}
+
+type stringMapObject struct {
+	values     map[string]string
+	readOnly   error
+	ignoreCase bool
+	in         *interpreter
+}
+
+func (s *stringMapObject) lookup(key string) (string, bool) {
+	if _, ok := s.values[key]; ok {
+		return key, true
+	}
+	if !s.ignoreCase {
+		return "", false
+	}
+	for k := range s.values {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func (s *stringMapObject) get(key string) (interface{}, error) {
+	actual, ok := s.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	return s.values[actual], nil
+}
+
+func (s *stringMapObject) keys() []string {
+	return sortedStringKeys(s.values)
+}
+
+func (s *stringMapObject) set(key string, value interface{}) error {
+	if s.readOnly != nil {
+		return s.readOnly
+	}
+	str := toString(value)
+	if err := s.in.alloc(len(str)); err != nil {
+		return err
+	}
+	if actual, ok := s.lookup(key); ok {
+		key = actual
+	}
+	s.values[key] = str
+	return nil
+}
+
+func (s *stringMapObject) del(key string) error {
+	if s.readOnly != nil {
+		return s.readOnly
+	}
+	if actual, ok := s.lookup(key); ok {
+		delete(s.values, actual)
+	}
+	return nil
+}
+
+type requestObject struct {
+	req      *model.Request
+	readOnly bool
+	in       *interpreter
+}
+
+func (r *requestObject) get(key string) (interface{}, error) {
+	switch key {
+	case "id":
+		return r.req.ID, nil
+	case "name":
+		return r.req.Name, nil
+	case "method":
+		return r.req.Method, nil
+	case "url":
+		return r.req.URL, nil
+	case "body":
+		return r.req.Body, nil
+	case "timeout":
+		return float64(r.req.TimeoutSeconds), nil
+	case "header", "headers":
+		if r.req.Headers == nil {
+			r.req.Headers = make(map[string]string)
+		}
+		return &stringMapObject{values: r.req.Headers, readOnly: r.lockErr(), ignoreCase: true, in: r.in}, nil
+	case "query":
+		if r.req.Query == nil {
+			r.req.Query = make(map[string]string)
+		}
+		return &stringMapObject{values: r.req.Query, readOnly: r.lockErr(), in: r.in}, nil
+	}
+	return nil, fmt.Errorf("request has no field %q", key)
+}
+
+func (r *requestObject) lockErr() error {
+	if r.readOnly {
+		return errRequestSent
+	}
+	return nil
+}
+
+func (r *requestObject) keys() []string {
+	return []string{"body", "headers", "id", "method", "name", "query", "timeout", "url"}
+}
+
+func (r *requestObject) set(key string, value interface{}) error {
+	if r.readOnly {
+		return errRequestSent
+	}
+
+	str := toString(value)
+	if err := r.in.alloc(len(str)); err != nil {
+		return err
+	}
+
+	switch key {
+	case "method":
+		r.req.Method = strings.ToUpper(str)
+	case "url":
+		r.req.URL = str
+	case "body":
+		r.req.Body = str
+	case "timeout":
+		n, ok := toNumber(value)
+		if !ok || n < 0 {
+			return errors.New("request.timeout must be a positive number")
+		}
+		r.req.TimeoutSeconds = int(n)
+	default:
+		return fmt.Errorf("request.%s cannot be assigned", key)
+	}
+	return nil
+}
+
+func (r *requestObject) del(key string) error {
+	return fmt.Errorf("request.%s cannot be deleted", key)
+}
+
+type responseObject struct {
+	resp     *model.Response
+	parsed   interface{}
+	parsedOK bool
+	in       *interpreter
+}
+
+func (r *responseObject) get(key string) (interface{}, error) {
+	switch key {
+	case "status", "code":
+		return float64(r.resp.StatusCode), nil
+	case "body", "text":
+		return r.resp.Body, nil
+	case "duration":
+		return float64(r.resp.Duration.Milliseconds()), nil
+	case "header", "headers":
+		return &stringMapObject{values: r.resp.Headers, readOnly: errResponseFixed, ignoreCase: true, in: r.in}, nil
+	case "json":
+		if !r.parsedOK {
+			var data interface{}
+			if err := json.Unmarshal([]byte(r.resp.Body), &data); err != nil {
+				return nil, errors.New("response body is not valid JSON")
+			}
+			r.parsed = data
+			r.parsedOK = true
+		}
+		return r.parsed, nil
+	}
+	return nil, fmt.Errorf("response has no field %q", key)
+}
+
+func (r *responseObject) keys() []string {
+	return []string{"body", "duration", "headers", "json", "status"}
+}
+
+// setNextRequest records which request a collection run should continue with. It is evaluated
+// here rather than as a builtin because it writes to the script's output.
+func (in *interpreter) setNextRequest(e *callExpr) (interface{}, error) {
+	if len(e.args) != 1 {
+		return nil, errors.New("setNextRequest(): expected a request name or null")
+	}
+
+	value, err := in.eval(e.args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	name := ""
+	if value != nil {
+		name = toString(value)
+		if name == "" {
+			return nil, errors.New("setNextRequest(): request name is empty")
+		}
+	}
+	in.out.Next = &name
+	return nil, nil
+}
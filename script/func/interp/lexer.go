@@ -0,0 +1,145 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func lex(src string) ([]token, error) {
+	tokens := make([]token, 0, len(src)/3)
+	line := 1
+	i := 0
+
+	for i < len(src) {
+		c := src[i]
+
+		if c == '\n' || c == ';' {
+			tokens = append(tokens, token{kind: tokNewline, text: "\n", line: line})
+			if c == '\n' {
+				line++
+			}
+			i++
+			continue
+		}
+
+		if c == ' ' || c == '\t' || c == '\r' {
+			i++
+			continue
+		}
+
+		if c == '#' || (c == '/' && i+1 < len(src) && src[i+1] == '/') {
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		if isIdentStart(c) {
+			start := i
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], line: line})
+			continue
+		}
+
+		if c >= '0' && c <= '9' {
+			start := i
+			for i < len(src) && ((src[i] >= '0' && src[i] <= '9') || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], line: line})
+			continue
+		}
+
+		if c == '"' || c == '\'' {
+			text, next, err := lexString(src, i, line)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, line: line})
+			i = next
+			continue
+		}
+
+		if i+1 < len(src) {
+			pair := src[i : i+2]
+			if pair == "==" || pair == "!=" || pair == "<=" || pair == ">=" || pair == "&&" || pair == "||" {
+				tokens = append(tokens, token{kind: tokOp, text: pair, line: line})
+				i += 2
+				continue
+			}
+		}
+
+		if strings.IndexByte("=<>+-*/%!()[]{},.", c) >= 0 {
+			tokens = append(tokens, token{kind: tokOp, text: string(c), line: line})
+			i++
+			continue
+		}
+
+		return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, line: line})
+	return tokens, nil
+}
+
+func lexString(src string, start int, line int) (string, int, error) {
+	quote := src[start]
+	var b strings.Builder
+	i := start + 1
+
+	for i < len(src) {
+		c := src[i]
+		if c == quote {
+			return b.String(), i + 1, nil
+		}
+		if c == '\n' {
+			break
+		}
+		if c == '\\' && i+1 < len(src) {
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(src[i])
+			}
+			i++
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+
+	return "", 0, fmt.Errorf("line %d: unterminated string", line)
+}
+
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '$'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
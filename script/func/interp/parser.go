@@ -0,0 +1,554 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type node interface{}
+
+type (
+	letStmt struct {
+		name  string
+		value node
+		line  int
+	}
+	assignStmt struct {
+		target node
+		value  node
+		line   int
+	}
+	deleteStmt struct {
+		target node
+		line   int
+	}
+	ifStmt struct {
+		cond     node
+		body     []node
+		elseBody []node
+		line     int
+	}
+	forStmt struct {
+		key   string
+		value string
+		iter  node
+		body  []node
+		line  int
+	}
+	testStmt struct {
+		name node
+		cond node
+		line int
+	}
+	logStmt struct {
+		value node
+		line  int
+	}
+	failStmt struct {
+		message node
+		line    int
+	}
+	exprStmt struct {
+		expr node
+		line int
+	}
+)
+
+type (
+	literal struct {
+		value interface{}
+	}
+	identExpr struct {
+		name string
+	}
+	memberExpr struct {
+		object node
+		key    node
+	}
+	callExpr struct {
+		name string
+		args []node
+	}
+	unaryExpr struct {
+		op      string
+		operand node
+	}
+	binaryExpr struct {
+		op    string
+		left  node
+		right node
+	}
+	listExpr struct {
+		items []node
+	}
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(src string) ([]node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	return p.parseBlock(false)
+}
+
+// parseExpression parses src as exactly one expression.
+func parseExpression(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	p.skipNewlines()
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipNewlines()
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("line %d: unexpected %q", tok.line, tok.text)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isOp(text string) bool {
+	tok := p.peek()
+	return tok.kind == tokOp && tok.text == text
+}
+
+func (p *parser) isKeyword(text string) bool {
+	tok := p.peek()
+	return tok.kind == tokIdent && tok.text == text
+}
+
+func (p *parser) expectOp(text string) error {
+	tok := p.next()
+	if tok.kind != tokOp || tok.text != text {
+		return fmt.Errorf("line %d: expected %q", tok.line, text)
+	}
+	return nil
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().kind == tokNewline {
+		p.next()
+	}
+}
+
+func (p *parser) parseBlock(braced bool) ([]node, error) {
+	stmts := make([]node, 0)
+
+	for {
+		p.skipNewlines()
+
+		if p.peek().kind == tokEOF {
+			if braced {
+				return nil, fmt.Errorf("line %d: missing closing }", p.peek().line)
+			}
+			return stmts, nil
+		}
+
+		if braced && p.isOp("}") {
+			p.next()
+			return stmts, nil
+		}
+
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+
+		tok := p.peek()
+		if tok.kind == tokNewline || tok.kind == tokEOF {
+			continue
+		}
+		if braced && tok.kind == tokOp && tok.text == "}" {
+			continue
+		}
+		return nil, fmt.Errorf("line %d: unexpected %q", tok.line, tok.text)
+	}
+}
+
+func (p *parser) parseStatement() (node, error) {
+	tok := p.peek()
+
+	if tok.kind == tokIdent {
+		switch tok.text {
+		case "let":
+			return p.parseLet()
+		case "if":
+			return p.parseIf()
+		case "for":
+			return p.parseFor()
+		case "test":
+			p.next()
+			name, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(","); err != nil {
+				return nil, err
+			}
+			cond, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return &testStmt{name: name, cond: cond, line: tok.line}, nil
+		case "log":
+			p.next()
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return &logStmt{value: value, line: tok.line}, nil
+		case "fail":
+			p.next()
+			message, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return &failStmt{message: message, line: tok.line}, nil
+		case "delete":
+			p.next()
+			target, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if !isAssignable(target) {
+				return nil, fmt.Errorf("line %d: cannot delete this expression", tok.line)
+			}
+			return &deleteStmt{target: target, line: tok.line}, nil
+		}
+	}
+
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isOp("=") {
+		p.next()
+		if !isAssignable(expr) {
+			return nil, fmt.Errorf("line %d: cannot assign to this expression", tok.line)
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &assignStmt{target: expr, value: value, line: tok.line}, nil
+	}
+
+	return &exprStmt{expr: expr, line: tok.line}, nil
+}
+
+func isAssignable(n node) bool {
+	switch n.(type) {
+	case *identExpr, *memberExpr:
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseLet() (node, error) {
+	tok := p.next()
+	name := p.next()
+	if name.kind != tokIdent {
+		return nil, fmt.Errorf("line %d: expected variable name after let", tok.line)
+	}
+	if err := p.expectOp("="); err != nil {
+		return nil, err
+	}
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &letStmt{name: name.text, value: value, line: tok.line}, nil
+}
+
+func (p *parser) parseIf() (node, error) {
+	tok := p.next()
+	cond, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectOp("{"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseBlock(true)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &ifStmt{cond: cond, body: body, line: tok.line}
+	if !p.isKeyword("else") {
+		return stmt, nil
+	}
+	p.next()
+
+	if p.isKeyword("if") {
+		nested, err := p.parseIf()
+		if err != nil {
+			return nil, err
+		}
+		stmt.elseBody = []node{nested}
+		return stmt, nil
+	}
+
+	if err := p.expectOp("{"); err != nil {
+		return nil, err
+	}
+	elseBody, err := p.parseBlock(true)
+	if err != nil {
+		return nil, err
+	}
+	stmt.elseBody = elseBody
+	return stmt, nil
+}
+
+func (p *parser) parseFor() (node, error) {
+	tok := p.next()
+	first := p.next()
+	if first.kind != tokIdent {
+		return nil, fmt.Errorf("line %d: expected loop variable", tok.line)
+	}
+
+	stmt := &forStmt{value: first.text, line: tok.line}
+	if p.isOp(",") {
+		p.next()
+		second := p.next()
+		if second.kind != tokIdent {
+			return nil, fmt.Errorf("line %d: expected loop variable", tok.line)
+		}
+		stmt.key = first.text
+		stmt.value = second.text
+	}
+
+	if !p.isKeyword("in") {
+		return nil, fmt.Errorf("line %d: expected in", tok.line)
+	}
+	p.next()
+
+	iter, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	stmt.iter = iter
+
+	if err := p.expectOp("{"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseBlock(true)
+	if err != nil {
+		return nil, err
+	}
+	stmt.body = body
+	return stmt, nil
+}
+
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+func (p *parser) parseExpr() (node, error) {
+	return p.parseBinary(1)
+}
+
+func (p *parser) parseBinary(minPrec int) (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokOp {
+			return left, nil
+		}
+		prec, ok := binaryPrecedence[tok.text]
+		if !ok || prec < minPrec {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!") || p.isOp("-") {
+		op := p.next().text
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: op, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if p.isOp(".") {
+			p.next()
+			name := p.next()
+			if name.kind != tokIdent {
+				return nil, fmt.Errorf("line %d: expected field name after .", name.line)
+			}
+			expr = &memberExpr{object: expr, key: &literal{value: name.text}}
+			continue
+		}
+
+		if p.isOp("[") {
+			p.next()
+			key, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			expr = &memberExpr{object: expr, key: key}
+			continue
+		}
+
+		return expr, nil
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid number %q", tok.line, tok.text)
+		}
+		return &literal{value: n}, nil
+	case tokString:
+		return &literal{value: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		}
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+		return &identExpr{name: tok.text}, nil
+	case tokOp:
+		if tok.text == "(" {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return expr, nil
+		}
+		if tok.text == "[" {
+			return p.parseList(tok)
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("line %d: unexpected end of script", tok.line)
+	}
+
+	return nil, fmt.Errorf("line %d: unexpected %q", tok.line, tok.text)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	p.next()
+	call := &callExpr{name: name.text, args: make([]node, 0)}
+
+	if p.isOp(")") {
+		p.next()
+		return call, nil
+	}
+
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+
+		if p.isOp(",") {
+			p.next()
+			continue
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		return call, nil
+	}
+}
+
+func (p *parser) parseList(open token) (node, error) {
+	list := &listExpr{items: make([]node, 0)}
+
+	if p.isOp("]") {
+		p.next()
+		return list, nil
+	}
+
+	for {
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list.items = append(list.items, item)
+
+		if p.isOp(",") {
+			p.next()
+			continue
+		}
+		if err := p.expectOp("]"); err != nil {
+			return nil, fmt.Errorf("line %d: unterminated list", open.line)
+		}
+		return list, nil
+	}
+}
@@ -0,0 +1,144 @@
+// Package script runs the pre-request and post-response hooks attached to collections and
+// requests. Scripts are interpreted by script/func/interp in a sandbox with no file, process
+// or network access, and are bounded by DefaultLimits.
+package script
+
+import (
+	"fmt"
+	"raco/model"
+	"raco/script/func/interp"
+	"raco/util"
+	"strings"
+	"time"
+)
+
+type Limits = interp.Limits
+
+// DefaultLimits caps each hook at 2s wall time, 100k evaluation steps and 8MB of string data.
+var DefaultLimits = Limits{
+	Timeout:   2 * time.Second,
+	MaxSteps:  100000,
+	MaxMemory: 8 * 1024 * 1024,
+}
+
+type Result struct {
+	Tests []model.AssertionResult
+	Logs  []string
+	// Next is the request name passed to setNextRequest, or "" after setNextRequest(null).
+	Next *string
+}
+
+func (r *Result) merge(out *interp.Output, hook string) {
+	if out == nil {
+		return
+	}
+	for _, t := range out.Tests {
+		message := t.Name
+		if !t.Passed {
+			message = t.Name + " (failed)"
+		}
+		r.Tests = append(r.Tests, model.AssertionResult{
+			Assertion: model.Assertion{
+				Type:  model.AssertScript,
+				Field: hook,
+				Value: t.Name,
+			},
+			Passed:  t.Passed,
+			Message: message,
+		})
+	}
+	r.Logs = append(r.Logs, out.Logs...)
+	if out.Next != nil {
+		r.Next = out.Next
+	}
+}
+
+// RunPreRequest executes the collection hook, the hooks of folders (outermost first) and then the
+// request hook before the request is built. req is mutated in place, so callers must pass their
+// own copy; vars receives env.* writes.
+func RunPreRequest(col *model.Collection, folders []*model.Folder, req *model.Request, vars map[string]string) (*Result, error) {
+	env := interp.Env{Variables: vars, Request: req}
+	return runHooks("pre-request", col, folders, req, env, func(pre, post string) string { return pre })
+}
+
+// RunPostResponse executes the collection hook, the folder hooks and then the request hook once
+// resp is available. The request is read-only at this point, and hooks see it with the values of
+// secrets replaced by [REDACTED]; env still holds them. Tests registered with `test` are returned
+// as assertion results.
+func RunPostResponse(col *model.Collection, folders []*model.Folder, req *model.Request, resp *model.Response, vars map[string]string) (*Result, error) {
+	env := interp.Env{Variables: vars, Request: redacted(req), Response: resp, RequestReadOnly: true}
+	return runHooks("post-response", col, folders, req, env, func(pre, post string) string { return post })
+}
+
+// runHooks runs one hook of the collection, of each folder (outermost first) and of req in that
+// order; code picks the hook from the pre-request and post-response scripts of each. The first
+// failing hook stops the chain.
+func runHooks(hook string, col *model.Collection, folders []*model.Folder, req *model.Request, env interp.Env, code func(pre, post string) string) (*Result, error) {
+	result := &Result{Tests: make([]model.AssertionResult, 0), Logs: make([]string, 0)}
+
+	sources := make([]hookSource, 0, 2+len(folders))
+	if col != nil {
+		sources = append(sources, hookSource{owner: "collection", code: code(col.PreRequestScript, col.PostResponseScript)})
+	}
+	for _, folder := range folders {
+		sources = append(sources, hookSource{owner: folderOwner(folder), code: code(folder.PreRequestScript, folder.PostResponseScript)})
+	}
+	if req != nil {
+		sources = append(sources, hookSource{owner: "request", code: code(req.PreRequestScript, req.PostResponseScript)})
+	}
+
+	for _, src := range sources {
+		if strings.TrimSpace(src.code) == "" {
+			continue
+		}
+		hookEnv := env
+		out, err := interp.Run(src.code, &hookEnv, DefaultLimits)
+		result.merge(out, hook)
+		if err != nil {
+			return result, fmt.Errorf("%s %s script: %w", src.owner, hook, err)
+		}
+	}
+
+	return result, nil
+}
+
+// redacted returns a copy of req whose URL, headers, query and body have the registered secrets
+// replaced, so a post-response hook cannot log or store the values that were sent.
+func redacted(req *model.Request) *model.Request {
+	if req == nil {
+		return nil
+	}
+	copied := *req
+	copied.URL = util.RedactSecrets(req.URL)
+	copied.Headers = util.RedactSecretsInMap(req.Headers)
+	copied.Query = util.RedactSecretsInMap(req.Query)
+	copied.Body = util.RedactSecrets(req.Body)
+	return &copied
+}
+
+// Condition evaluates a run_if/skip_if expression such as `env.role == "admin"`. vars are
+// readable through env but writes are discarded.
+func Condition(expr string, vars map[string]string) (bool, error) {
+	env := &interp.Env{Variables: model.CloneVariables(vars)}
+	return interp.Condition(expr, env, DefaultLimits)
+}
+
+// Check parses a hook without running it and returns the variables it assigns through env, so
+// they count as defined for the requests that follow.
+func Check(code string) ([]string, error) {
+	return interp.Check(code)
+}
+
+// CheckCondition parses a run_if/skip_if expression without evaluating it.
+func CheckCondition(expr string) error {
+	return interp.CheckCondition(expr)
+}
+
+type hookSource struct {
+	owner string
+	code  string
+}
+
+func folderOwner(folder *model.Folder) string {
+	return fmt.Sprintf("folder %q", folder.Name)
+}
@@ -0,0 +1,200 @@
+package index
+
+import (
+	"raco/model"
+	"raco/search/func/query"
+	"raco/util"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fields a document can be searched by. Weights rank a match in a name above one in a URL and
+// both above one buried in a body.
+const (
+	FieldName     = "name"
+	FieldFolder   = "folder"
+	FieldMethod   = "method"
+	FieldURL      = "url"
+	FieldQuery    = "query"
+	FieldHeader   = "header"
+	FieldBody     = "body"
+	FieldVariable = "variable"
+	FieldError    = "error"
+)
+
+var weights = map[string]int{
+	FieldName:     40,
+	FieldVariable: 30,
+	FieldURL:      30,
+	FieldFolder:   20,
+	FieldMethod:   20,
+	FieldQuery:    12,
+	FieldHeader:   12,
+	FieldBody:     6,
+	FieldError:    6,
+}
+
+// Weight returns the weight of a field name.
+func Weight(field string) int {
+	return weights[field]
+}
+
+// Field is one searchable text of a document. A document has a field per header, query
+// parameter and variable, so a match points at the one that contains it.
+type Field struct {
+	Name string
+	Text string
+}
+
+// Document is a saved request, an environment or a history entry as the search sees it.
+type Document struct {
+	Kind string `json:"kind"`
+	// ID is the request ID, the environment name or the history entry ID.
+	ID string `json:"id"`
+	// Title is the folder path and name of a request, the name of an environment or the method
+	// and URL of a history entry.
+	Title          string `json:"title"`
+	Collection     string `json:"collection,omitempty"`
+	CollectionName string `json:"collection_name,omitempty"`
+	Method         string `json:"method,omitempty"`
+	URL            string `json:"url,omitempty"`
+	// Host is the host of URL, with placeholders resolved where the variables allow.
+	Host string `json:"host,omitempty"`
+	// Timestamp is when a history entry was recorded.
+	Timestamp time.Time `json:"-"`
+
+	Fields      []Field             `json:"-"`
+	Request     *model.Request      `json:"-"`
+	Environment *model.Environment  `json:"-"`
+	History     *model.HistoryEntry `json:"-"`
+}
+
+// Requests returns a document for every request of col. vars resolve placeholders in hosts,
+// such as {{base_url}}; collection variables are layered over them.
+func Requests(col *model.Collection, vars map[string]string) []Document {
+	if col == nil {
+		return nil
+	}
+	hostVars := make(map[string]string, len(vars)+len(col.Variables))
+	for k, v := range vars {
+		hostVars[k] = v
+	}
+	for k, v := range col.Variables {
+		hostVars[k] = v
+	}
+
+	requests := col.AllRequests()
+	docs := make([]Document, 0, len(requests))
+	for _, req := range requests {
+		folders := col.FolderPath(req)
+		names := make([]string, 0, len(folders)+1)
+		for _, f := range folders {
+			names = append(names, f.Name)
+		}
+		names = append(names, req.Name)
+
+		doc := Document{
+			Kind:           query.KindRequest,
+			ID:             req.ID,
+			Title:          strings.Join(names, "/"),
+			Collection:     col.ID,
+			CollectionName: col.Name,
+			Method:         strings.ToUpper(req.Method),
+			URL:            req.URL,
+			Host:           Host(util.RenderTemplate(req.URL, hostVars)),
+			Request:        req,
+		}
+		doc.add(FieldName, req.Name)
+		for _, f := range folders {
+			doc.add(FieldFolder, f.Name)
+		}
+		doc.add(FieldMethod, doc.Method)
+		doc.add(FieldURL, req.URL)
+		for _, k := range sortedKeys(req.Query) {
+			doc.add(FieldQuery, k+"="+req.Query[k])
+		}
+		doc.addHeaders(req.Headers)
+		doc.add(FieldBody, req.Body)
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// Environment returns the document of env. Only variable and secret names are indexed, never
+// their values.
+func Environment(env *model.Environment) Document {
+	doc := Document{Kind: query.KindEnvironment, ID: env.Name, Title: env.Name, Environment: env}
+	doc.add(FieldName, env.Name)
+	names := sortedKeys(env.Variables)
+	names = append(names, sortedKeys(env.Secrets)...)
+	for _, name := range names {
+		doc.add(FieldVariable, name)
+	}
+	return doc
+}
+
+// History returns a document for entry. History is redacted when it is recorded, so its
+// headers and body are indexed as stored.
+func History(entry *model.HistoryEntry) Document {
+	doc := Document{
+		Kind:      query.KindHistory,
+		ID:        entry.ID,
+		Title:     entry.Method + " " + entry.URL,
+		Method:    strings.ToUpper(entry.Method),
+		URL:       entry.URL,
+		Host:      Host(entry.URL),
+		Timestamp: entry.Timestamp,
+		History:   entry,
+	}
+	doc.add(FieldMethod, doc.Method)
+	doc.add(FieldURL, entry.URL)
+	doc.addHeaders(entry.Headers)
+	doc.add(FieldBody, entry.Body)
+	doc.add(FieldError, entry.Error)
+	return doc
+}
+
+// Host returns the host and port of a URL, or the part before the first slash when it has no
+// scheme.
+func Host(rawURL string) string {
+	host := rawURL
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	return host
+}
+
+func (d *Document) add(name string, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	d.Fields = append(d.Fields, Field{Name: name, Text: text})
+}
+
+// addHeaders indexes headers as "Name: value"; sensitive headers such as Authorization by name
+// only, so a search never prints a credential.
+func (d *Document) addHeaders(headers map[string]string) {
+	for _, k := range sortedKeys(headers) {
+		if util.IsSensitiveKey(k) {
+			d.add(FieldHeader, k)
+			continue
+		}
+		d.add(FieldHeader, k+": "+headers[k])
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,157 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds a query can be limited to with in:, by the names Parse accepts for them.
+const (
+	KindRequest     = "request"
+	KindEnvironment = "environment"
+	KindHistory     = "history"
+)
+
+var kindNames = map[string]string{
+	"request":      KindRequest,
+	"requests":     KindRequest,
+	"req":          KindRequest,
+	"environment":  KindEnvironment,
+	"environments": KindEnvironment,
+	"env":          KindEnvironment,
+	"history":      KindHistory,
+}
+
+// Query is a parsed search: every term must match some field of a document, and every filter
+// key that is set must match one of its values.
+type Query struct {
+	Terms []string
+	// Methods, Hosts, Collections and Kinds come from method:, host:, col: and in: filters.
+	Methods     []string
+	Hosts       []string
+	Collections []string
+	Kinds       []string
+}
+
+// Empty reports whether q has neither terms nor filters.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Methods) == 0 && len(q.Hosts) == 0 && len(q.Collections) == 0 && len(q.Kinds) == 0
+}
+
+// Parse splits s into terms and filters. Double quotes keep a phrase together as one term;
+// filters take comma-separated values, e.g. method:GET,POST. A word whose prefix is not a filter
+// key, such as https://api, is an ordinary term.
+func Parse(s string) (Query, error) {
+	var q Query
+	tokens, err := tokenize(s)
+	if err != nil {
+		return q, err
+	}
+
+	for _, tok := range tokens {
+		key, value, isFilter := strings.Cut(tok.text, ":")
+		if tok.quoted || !isFilter {
+			q.Terms = append(q.Terms, tok.text)
+			continue
+		}
+		key = strings.ToLower(key)
+		var dst *[]string
+		switch key {
+		case "method":
+			dst = &q.Methods
+		case "host":
+			dst = &q.Hosts
+		case "col", "collection":
+			dst = &q.Collections
+		case "in", "kind":
+			dst = &q.Kinds
+		default:
+			q.Terms = append(q.Terms, tok.text)
+			continue
+		}
+
+		values := splitValues(value)
+		if len(values) == 0 {
+			return q, fmt.Errorf("filter %s: needs a value", key)
+		}
+		for _, v := range values {
+			switch key {
+			case "in", "kind":
+				kind, ok := kindNames[strings.ToLower(v)]
+				if !ok {
+					return q, fmt.Errorf("unknown kind %q (use request, env or history)", v)
+				}
+				v = kind
+			case "method":
+				v = strings.ToUpper(v)
+			}
+			*dst = append(*dst, v)
+		}
+	}
+
+	if q.Empty() {
+		return q, errors.New("empty search query")
+	}
+	return q, nil
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+// tokenize splits s on whitespace outside double quotes. A quote inside a word, as in
+// host:"api x", quotes the rest of that word without making it a phrase term.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	var cur strings.Builder
+	inQuotes, started, leadingQuote := false, false, false
+
+	flush := func() {
+		if started {
+			tokens = append(tokens, token{text: cur.String(), quoted: leadingQuote})
+		}
+		cur.Reset()
+		started, leadingQuote = false, false
+	}
+
+	for _, r := range s {
+		if r == '"' {
+			if !started {
+				leadingQuote = true
+			}
+			started = true
+			inQuotes = !inQuotes
+			continue
+		}
+		if !inQuotes && (r == ' ' || r == '\t' || r == '\n') {
+			flush()
+			continue
+		}
+		started = true
+		cur.WriteRune(r)
+	}
+	if inQuotes {
+		return nil, errors.New("unterminated quote in search query")
+	}
+	flush()
+
+	out := tokens[:0]
+	for _, tok := range tokens {
+		if tok.text != "" {
+			out = append(out, tok)
+		}
+	}
+	return out, nil
+}
+
+func splitValues(value string) []string {
+	values := make([]string, 0, 1)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,290 @@
+package rank
+
+import (
+	"raco/search/func/index"
+	"raco/search/func/query"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxHits is the number of fields a result shows its matches in.
+	maxHits = 3
+	// snippetLen is the number of bytes of a field shown around its first match.
+	snippetLen = 80
+	// snippetLead is how much of the text before the first match a snippet keeps.
+	snippetLead = 24
+)
+
+// Span is a highlighted byte range of a snippet.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Hit is the context of a match: the field it is in, a one-line snippet of the field and the
+// ranges of the snippet that matched.
+type Hit struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+	Spans   []Span `json:"spans"`
+}
+
+// Result is a document that matched, with its score and where it matched.
+type Result struct {
+	index.Document
+	Score int   `json:"score"`
+	Hits  []Hit `json:"hits"`
+}
+
+// Rank returns the documents that match q, best first. Documents that score the same keep
+// their order in docs.
+func Rank(docs []index.Document, q query.Query) []Result {
+	results := make([]Result, 0)
+	for _, doc := range docs {
+		if result, ok := Match(doc, q); ok {
+			results = append(results, result)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// Match scores doc against q. Every filter must accept the document and every term must occur
+// in one of its fields, ignoring ASCII case, or failing that appear in order in its name. A
+// term scores the weight of the best field it occurs in, doubled when it starts a word and
+// tripled when it is the whole field; each further field it occurs in adds one point.
+func Match(doc index.Document, q query.Query) (Result, bool) {
+	result := Result{Document: doc, Hits: make([]Hit, 0)}
+	if !filtersAccept(doc, q) {
+		return result, false
+	}
+	if len(q.Terms) == 0 {
+		return result, true
+	}
+
+	spans := make([][]Span, len(doc.Fields))
+	for _, term := range q.Terms {
+		needle := lower(term)
+		best, fields := 0, 0
+		for i, field := range doc.Fields {
+			found := occurrences(lower(field.Text), needle)
+			if len(found) == 0 {
+				continue
+			}
+			spans[i] = append(spans[i], found...)
+			fields++
+			if score := index.Weight(field.Name) * quality(field.Text, found[0], needle); score > best {
+				best = score
+			}
+		}
+		if best > 0 {
+			result.Score += best + fields - 1
+			continue
+		}
+
+		matched := false
+		for i, field := range doc.Fields {
+			if field.Name != index.FieldName {
+				continue
+			}
+			if found := subsequence(lower(field.Text), needle); found != nil {
+				spans[i] = append(spans[i], found...)
+				result.Score += index.Weight(field.Name) / 4
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return result, false
+		}
+	}
+
+	order := make([]int, 0, len(doc.Fields))
+	for i := range doc.Fields {
+		if len(spans[i]) > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return index.Weight(doc.Fields[order[a]].Name) > index.Weight(doc.Fields[order[b]].Name)
+	})
+	for _, i := range order {
+		if len(result.Hits) == maxHits {
+			break
+		}
+		result.Hits = append(result.Hits, snippet(doc.Fields[i], spans[i]))
+	}
+	return result, true
+}
+
+func filtersAccept(doc index.Document, q query.Query) bool {
+	if len(q.Kinds) > 0 && !anyOf(q.Kinds, func(kind string) bool { return kind == doc.Kind }) {
+		return false
+	}
+	if len(q.Methods) > 0 && !anyOf(q.Methods, func(method string) bool { return method == doc.Method }) {
+		return false
+	}
+	host := lower(doc.Host)
+	if len(q.Hosts) > 0 && !anyOf(q.Hosts, func(h string) bool { return host != "" && strings.Contains(host, lower(h)) }) {
+		return false
+	}
+	if len(q.Collections) > 0 && !anyOf(q.Collections, func(c string) bool {
+		return doc.Collection != "" && (strings.EqualFold(c, doc.Collection) || strings.Contains(lower(doc.CollectionName), lower(c)))
+	}) {
+		return false
+	}
+	return true
+}
+
+func anyOf(values []string, fn func(string) bool) bool {
+	for _, v := range values {
+		if fn(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// quality is 3 when needle is the whole text, 2 when its first occurrence starts a word and 1
+// otherwise.
+func quality(text string, at Span, needle string) int {
+	if len(needle) == len(text) {
+		return 3
+	}
+	if at.Start == 0 || !isWordByte(text[at.Start-1]) {
+		return 2
+	}
+	return 1
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b >= 0x80
+}
+
+// occurrences returns every non-overlapping occurrence of needle in text.
+func occurrences(text string, needle string) []Span {
+	var found []Span
+	for from := 0; from <= len(text)-len(needle); {
+		i := strings.Index(text[from:], needle)
+		if i < 0 {
+			break
+		}
+		start := from + i
+		found = append(found, Span{Start: start, End: start + len(needle)})
+		from = start + len(needle)
+	}
+	return found
+}
+
+// subsequence returns the bytes of text that spell needle in order, taking the earliest ones,
+// merged into runs; nil when needle is not a subsequence of text.
+func subsequence(text string, needle string) []Span {
+	var found []Span
+	j := 0
+	for i := 0; i < len(text) && j < len(needle); i++ {
+		if text[i] != needle[j] {
+			continue
+		}
+		j++
+		if n := len(found); n > 0 && found[n-1].End == i {
+			found[n-1].End = i + 1
+			continue
+		}
+		found = append(found, Span{Start: i, End: i + 1})
+	}
+	if j < len(needle) {
+		return nil
+	}
+	return found
+}
+
+// snippet cuts one line of field around its first match and moves the spans into it.
+func snippet(field index.Field, spans []Span) Hit {
+	spans = merged(spans)
+	text := flatten(field.Text)
+
+	start, end := 0, len(text)
+	if len(text) > snippetLen {
+		start = spans[0].Start - snippetLead
+		if start < 0 {
+			start = 0
+		}
+		end = start + snippetLen
+		if end > len(text) {
+			end = len(text)
+			start = end - snippetLen
+		}
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+	}
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(text) {
+		suffix = "…"
+	}
+	hit := Hit{Field: field.Name, Snippet: prefix + text[start:end] + suffix, Spans: make([]Span, 0, len(spans))}
+	for _, s := range spans {
+		if s.End <= start || s.Start >= end {
+			continue
+		}
+		if s.Start < start {
+			s.Start = start
+		}
+		if s.End > end {
+			s.End = end
+		}
+		shift := len(prefix) - start
+		hit.Spans = append(hit.Spans, Span{Start: s.Start + shift, End: s.End + shift})
+	}
+	return hit
+}
+
+// merged sorts spans and joins those that overlap or touch.
+func merged(spans []Span) []Span {
+	sorted := append([]Span(nil), spans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	out := make([]Span, 0, len(sorted))
+	for _, s := range sorted {
+		if n := len(out); n > 0 && s.Start <= out[n-1].End {
+			if s.End > out[n-1].End {
+				out[n-1].End = s.End
+			}
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// flatten puts text on one line without moving any byte, so spans stay valid.
+func flatten(text string) string {
+	b := []byte(text)
+	for i, c := range b {
+		if c == '\n' || c == '\r' || c == '\t' {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// lower folds ASCII letters only, so offsets into the result are offsets into s.
+func lower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
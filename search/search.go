@@ -0,0 +1,99 @@
+// Package search finds saved requests, environments and history entries by their names, URLs,
+// methods, headers, bodies and variable names: search/func/query parses a query of terms and
+// filters, search/func/index turns workspace objects into documents and search/func/rank scores
+// them and cuts the highlighted context of every match.
+package search
+
+import (
+	"raco/model"
+	"raco/search/func/index"
+	"raco/search/func/query"
+	"raco/search/func/rank"
+	"raco/storage"
+)
+
+type Query = query.Query
+type Document = index.Document
+type Result = rank.Result
+type Hit = rank.Hit
+type Span = rank.Span
+
+// Kinds of documents, as set in Document.Kind and selected with the in: filter.
+const (
+	KindRequest     = query.KindRequest
+	KindEnvironment = query.KindEnvironment
+	KindHistory     = query.KindHistory
+)
+
+// Parse parses a query such as `orders method:POST host:api.example.org`; see query.Parse.
+func Parse(s string) (Query, error) {
+	return query.Parse(s)
+}
+
+// Documents indexes the requests of collections, the variable names of environments and the
+// history, newest entry first. vars resolve placeholders in request hosts for host: filters.
+func Documents(collections []*model.Collection, environments []*model.Environment, history []*model.HistoryEntry, vars map[string]string) []Document {
+	docs := make([]Document, 0)
+	for _, col := range collections {
+		docs = append(docs, index.Requests(col, vars)...)
+	}
+	for _, env := range environments {
+		if env != nil {
+			docs = append(docs, index.Environment(env))
+		}
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i] != nil {
+			docs = append(docs, index.History(history[i]))
+		}
+	}
+	return docs
+}
+
+// Rank returns the documents that match q, best first; see rank.Match for the scoring.
+func Rank(docs []Document, q Query) []Result {
+	return rank.Rank(docs, q)
+}
+
+// Workspace searches the collections, environments and history of store. Templated hosts are
+// resolved against the globals and, when environment is set, that environment. Collections and
+// environments that fail to load are left out; raco lint reports them.
+func Workspace(store *storage.Storage, q Query, environment string) ([]Result, error) {
+	collections, err := store.ListCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	if globals, err := store.LoadGlobals(); err == nil {
+		for k, v := range globals.Variables {
+			vars[k] = v
+		}
+	}
+	if environment != "" {
+		env, _, err := store.MergeEnvironment(environment)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range env.Variables {
+			vars[k] = v
+		}
+	}
+
+	names, err := store.EnvironmentNames()
+	if err != nil {
+		return nil, err
+	}
+	environments := make([]*model.Environment, 0, len(names))
+	for _, name := range names {
+		if env, err := store.LoadEnvironment(name); err == nil {
+			environments = append(environments, env)
+		}
+	}
+
+	history, err := store.LoadHistory()
+	if err != nil {
+		return nil, err
+	}
+	return Rank(Documents(collections, environments, history, vars), q), nil
+}
@@ -0,0 +1,148 @@
+// Package cipher encrypts single values with AES-256-GCM under a key derived from a passphrase
+// with PBKDF2-HMAC-SHA256.
+package cipher
+
+import (
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Prefix marks an encrypted value. The payload is base64(salt | nonce | ciphertext).
+const Prefix = "enc:v1:"
+
+const (
+	saltSize   = 16
+	keySize    = 32
+	iterations = 200000
+)
+
+var ErrWrongKey = errors.New("wrong key or corrupted value")
+
+// Box encrypts and decrypts values for one passphrase. Derived keys are cached per salt, and
+// values sealed by the same Box share a salt, so a file full of secrets costs one derivation.
+type Box struct {
+	passphrase []byte
+
+	mu   sync.Mutex
+	salt []byte
+	keys map[string][]byte
+}
+
+func New(passphrase string) *Box {
+	return &Box{passphrase: []byte(passphrase), keys: make(map[string][]byte)}
+}
+
+// IsEncrypted reports whether value carries the Prefix.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+func (b *Box) Seal(plaintext string) (string, error) {
+	b.mu.Lock()
+	if b.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			b.mu.Unlock()
+			return "", err
+		}
+		b.salt = salt
+	}
+	salt := b.salt
+	b.mu.Unlock()
+
+	aead, err := b.aead(salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	payload := make([]byte, 0, saltSize+len(nonce)+len(plaintext)+aead.Overhead())
+	payload = append(payload, salt...)
+	payload = append(payload, nonce...)
+	payload = aead.Seal(payload, nonce, []byte(plaintext), nil)
+	return Prefix + base64.StdEncoding.EncodeToString(payload), nil
+}
+
+func (b *Box) Open(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+	payload, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil || len(payload) < saltSize {
+		return "", ErrWrongKey
+	}
+
+	aead, err := b.aead(payload[:saltSize])
+	if err != nil {
+		return "", err
+	}
+	rest := payload[saltSize:]
+	if len(rest) < aead.NonceSize()+aead.Overhead() {
+		return "", ErrWrongKey
+	}
+
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+	return string(plaintext), nil
+}
+
+func (b *Box) aead(salt []byte) (gocipher.AEAD, error) {
+	b.mu.Lock()
+	key, ok := b.keys[string(salt)]
+	if !ok {
+		key = pbkdf2(b.passphrase, salt, iterations, keySize)
+		b.keys[string(salt)] = key
+	}
+	b.mu.Unlock()
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return gocipher.NewGCM(block)
+}
+
+// pbkdf2 implements PBKDF2 (RFC 8018) with HMAC-SHA256.
+func pbkdf2(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	out := make([]byte, 0, blocks*hashLen)
+	buf := make([]byte, 4)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		prf.Write(buf)
+		u = prf.Sum(u[:0])
+
+		t := make([]byte, hashLen)
+		copy(t, u)
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
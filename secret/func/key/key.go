@@ -0,0 +1,109 @@
+// Package key finds the passphrase that protects environment secrets.
+package key
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// EnvKey holds the passphrase itself, which suits CI secrets.
+	EnvKey = "RACO_SECRET_KEY"
+	// EnvKeyFile points at a file containing the passphrase.
+	EnvKeyFile = "RACO_SECRET_KEY_FILE"
+	// DefaultFile is the key file used when neither variable is set, relative to the storage path.
+	DefaultFile = "secret.key"
+)
+
+const maxKeyFileSize = 4096
+
+// ErrNoKey is returned by Load when no key source is configured.
+var ErrNoKey = errors.New("no secret key: set " + EnvKey + " or " + EnvKeyFile + ", or create one with raco env set --secret")
+
+// Load returns the passphrase and a description of its source, trying EnvKey, then EnvKeyFile,
+// then basePath/DefaultFile.
+func Load(basePath string) (string, string, error) {
+	if value := os.Getenv(EnvKey); value != "" {
+		return value, "$" + EnvKey, nil
+	}
+
+	if path := os.Getenv(EnvKeyFile); path != "" {
+		passphrase, err := ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", EnvKeyFile, err)
+		}
+		return passphrase, path, nil
+	}
+
+	path := filepath.Join(basePath, DefaultFile)
+	passphrase, err := ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", ErrNoKey
+		}
+		return "", "", err
+	}
+	return passphrase, path, nil
+}
+
+// ReadFile returns the passphrase stored in path.
+func ReadFile(path string) (string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxKeyFileSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxKeyFileSize {
+		return "", errors.New("key file too large")
+	}
+
+	passphrase := strings.TrimSpace(string(data))
+	if passphrase == "" {
+		return "", errors.New("key file is empty")
+	}
+	return passphrase, nil
+}
+
+// Generate writes a new random key to path, readable only by the owner, and returns it. It
+// refuses to overwrite an existing file unless replace is set.
+func Generate(path string, replace bool) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	passphrase := base64.StdEncoding.EncodeToString(raw)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	if !replace {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.WriteString(passphrase + "\n"); err != nil {
+			file.Close()
+			return "", err
+		}
+		return passphrase, file.Close()
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(passphrase+"\n"), 0600); err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	return passphrase, os.Rename(tempPath, path)
+}
@@ -0,0 +1,100 @@
+// Package secret keeps environment secrets encrypted at rest. Values are sealed with AES-256-GCM
+// (see secret/func/cipher) under a passphrase taken from RACO_SECRET_KEY, the file named by
+// RACO_SECRET_KEY_FILE or secret.key in the workspace (see secret/func/key), and are only
+// decrypted in memory.
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"raco/secret/func/cipher"
+	"raco/secret/func/key"
+)
+
+type Box = cipher.Box
+
+var (
+	ErrNoKey    = key.ErrNoKey
+	ErrWrongKey = cipher.ErrWrongKey
+)
+
+const (
+	EnvKey     = key.EnvKey
+	EnvKeyFile = key.EnvKeyFile
+)
+
+// IsEncrypted reports whether value is a sealed secret.
+func IsEncrypted(value string) bool {
+	return cipher.IsEncrypted(value)
+}
+
+// Open returns a Box for the configured key and a description of where the key came from.
+func Open(basePath string) (*Box, string, error) {
+	passphrase, source, err := key.Load(basePath)
+	if err != nil {
+		return nil, "", err
+	}
+	return cipher.New(passphrase), source, nil
+}
+
+// OpenOrCreate is Open, except that a new key file is generated in basePath when no key is
+// configured yet. created reports whether that happened.
+func OpenOrCreate(basePath string) (box *Box, source string, created bool, err error) {
+	box, source, err = Open(basePath)
+	if !errors.Is(err, key.ErrNoKey) {
+		return box, source, false, err
+	}
+
+	path := DefaultKeyPath(basePath)
+	passphrase, err := key.Generate(path, false)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return cipher.New(passphrase), path, true, nil
+}
+
+// NewKey generates a key and stages it next to the default key file; InstallNewKey moves it into
+// place. Staging lets a rekey finish re-encrypting before the old key file is replaced.
+func NewKey(basePath string) (*Box, string, error) {
+	path := stagedKeyPath(basePath)
+	passphrase, err := key.Generate(path, true)
+	if err != nil {
+		return nil, "", err
+	}
+	return cipher.New(passphrase), path, nil
+}
+
+// InstallNewKey replaces the default key file with the key staged by NewKey.
+func InstallNewKey(basePath string) (string, error) {
+	path := DefaultKeyPath(basePath)
+	return path, os.Rename(stagedKeyPath(basePath), path)
+}
+
+// DiscardNewKey removes a key staged by NewKey that was not installed.
+func DiscardNewKey(basePath string) {
+	os.Remove(stagedKeyPath(basePath))
+}
+
+func stagedKeyPath(basePath string) string {
+	return DefaultKeyPath(basePath) + ".new"
+}
+
+// FromFile returns a Box for the passphrase stored in path.
+func FromFile(path string) (*Box, error) {
+	passphrase, err := key.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.New(passphrase), nil
+}
+
+// WithPassphrase returns a Box for an explicit passphrase, e.g. the new key during a rekey.
+func WithPassphrase(passphrase string) *Box {
+	return cipher.New(passphrase)
+}
+
+// DefaultKeyPath is the key file used when no environment variable names a key.
+func DefaultKeyPath(basePath string) string {
+	return filepath.Join(basePath, key.DefaultFile)
+}
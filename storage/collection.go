@@ -5,10 +5,21 @@ import (
 	"raco/storage/func/collection"
 )
 
+// CollectionLayout is how a collection is kept on disk: LayoutFile or LayoutDir.
+type CollectionLayout = collection.Layout
+
+const (
+	LayoutFile = collection.LayoutFile
+	LayoutDir  = collection.LayoutDir
+)
+
+// SaveCollection writes col in the layout it is already stored in; new collections are single
+// JSON files.
 func (s *Storage) SaveCollection(col *model.Collection) error {
 	return collection.Save(s.basePath, col)
 }
 
+// LoadCollection reads a collection in either layout.
 func (s *Storage) LoadCollection(id string) (*model.Collection, error) {
 	return collection.Load(s.basePath, id)
 }
@@ -16,3 +27,44 @@ func (s *Storage) LoadCollection(id string) (*model.Collection, error) {
 func (s *Storage) ListCollections() ([]*model.Collection, error) {
 	return collection.List(s.basePath)
 }
+
+// CollectionIDs returns the IDs of the stored collections without loading them.
+func (s *Storage) CollectionIDs() ([]string, error) {
+	return collection.IDs(s.basePath)
+}
+
+// CreateCollection writes a new collection in the given layout and format (yaml or json, for
+// LayoutDir).
+func (s *Storage) CreateCollection(col *model.Collection, layout CollectionLayout, format string) error {
+	return collection.SaveAs(s.basePath, col, layout, format)
+}
+
+// RenameCollection sets the name of collection id and, when newID is not empty and differs,
+// moves it to newID.
+func (s *Storage) RenameCollection(id string, name string, newID string) error {
+	return collection.Rename(s.basePath, id, name, newID)
+}
+
+func (s *Storage) DeleteCollection(id string) error {
+	return collection.Delete(s.basePath, id)
+}
+
+// CollectionLayoutOf reports the layout of collection id and the format of its files.
+func (s *Storage) CollectionLayoutOf(id string) (CollectionLayout, string, error) {
+	return collection.Detect(s.basePath, id)
+}
+
+// MigrateCollection converts collection id to layout; see collection.Migrate.
+func (s *Storage) MigrateCollection(id string, layout CollectionLayout, format string) error {
+	return collection.Migrate(s.basePath, id, layout, format)
+}
+
+// ParseCollectionLayout validates a layout name ("file" or "dir").
+func ParseCollectionLayout(name string) (CollectionLayout, error) {
+	return collection.ParseLayout(name)
+}
+
+// ParseCollectionFormat validates the file format of a LayoutDir collection ("yaml" or "json").
+func ParseCollectionFormat(name string) (string, error) {
+	return collection.ParseFormat(name)
+}
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"io"
+	"raco/storage/func/dotenv"
+)
+
+// DotenvVariable is one KEY=value assignment of a .env file.
+type DotenvVariable = dotenv.Variable
+
+// ParseDotenv reads .env content; see dotenv.Parse for the accepted syntax.
+func ParseDotenv(content string) ([]DotenvVariable, error) {
+	return dotenv.Parse(content)
+}
+
+// WriteDotenv renders vars as a .env file.
+func WriteDotenv(w io.Writer, vars []DotenvVariable) error {
+	return dotenv.Write(w, vars)
+}
+
+// WriteShellExports renders vars as shell export statements.
+func WriteShellExports(w io.Writer, vars []DotenvVariable) error {
+	return dotenv.WriteShell(w, vars)
+}
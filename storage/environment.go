@@ -1,14 +1,70 @@
 package storage
 
 import (
+	"os"
 	"raco/model"
+	"raco/secret"
 	"raco/storage/func/environment"
 )
 
+// LocalEnvironmentSuffix marks local override files such as staging.local.yaml.
+const LocalEnvironmentSuffix = environment.LocalSuffix
+
 func (s *Storage) SaveEnvironment(env *model.Environment) error {
 	return environment.Save(s.basePath, env)
 }
 
+// LoadLocalEnvironment returns the local override file of name, or nil when there is none.
+func (s *Storage) LoadLocalEnvironment(name string) (*model.Environment, error) {
+	return environment.LoadLocal(s.basePath, name)
+}
+
+// SaveLocalEnvironment writes the local override file of env; see environment.SaveLocal.
+func (s *Storage) SaveLocalEnvironment(env *model.Environment) (bool, error) {
+	return environment.SaveLocal(s.basePath, env)
+}
+
 func (s *Storage) LoadEnvironment(name string) (*model.Environment, error) {
 	return environment.Load(s.basePath, name)
 }
+
+// EnvironmentNames returns the names of the stored environments, globals included.
+func (s *Storage) EnvironmentNames() ([]string, error) {
+	return environment.Names(s.basePath)
+}
+
+// ResolveEnvironment returns name merged with the environments it extends and its local override
+// file, and the source of every variable; see environment.Resolve.
+func (s *Storage) ResolveEnvironment(name string) (*model.Environment, map[string]string, error) {
+	return environment.Resolve(s.basePath, name)
+}
+
+// MergeEnvironment layers name like ResolveEnvironment but keeps secrets sealed and ${env:NAME}
+// references as written; see environment.Merge.
+func (s *Storage) MergeEnvironment(name string) (*model.Environment, map[string]string, error) {
+	return environment.Merge(s.basePath, name)
+}
+
+// EnvironmentReference returns the ${env:NAME} form that reads name from the process environment
+// when the variable is used.
+func EnvironmentReference(name string) string {
+	return environment.Reference(name)
+}
+
+// LoadGlobals returns the globals environment, or an empty one when none has been saved yet.
+func (s *Storage) LoadGlobals() (*model.Environment, error) {
+	env, err := environment.Load(s.basePath, model.GlobalsEnvironment)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &model.Environment{Name: model.GlobalsEnvironment, Variables: make(map[string]string)}, nil
+		}
+		return nil, err
+	}
+	return env, nil
+}
+
+// RekeySecrets re-encrypts every environment secret from one key to another; see
+// environment.Rekey.
+func (s *Storage) RekeySecrets(from, to *secret.Box) (environment.RekeyResult, error) {
+	return environment.Rekey(s.basePath, from, to)
+}
@@ -0,0 +1,63 @@
+package collection
+
+import (
+	"errors"
+	"os"
+)
+
+// Delete removes collection id in whichever layouts it is stored. Files in a LayoutDir
+// directory that the loader does not read are kept, and so is the directory when they are there.
+func Delete(basePath string, id string) error {
+	if !validIDPattern.MatchString(id) {
+		return errors.New("invalid collection ID format")
+	}
+	unlock, err := lock(basePath, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return remove(basePath, id)
+}
+
+// remove deletes the stored copies of collection id. The caller holds the lock.
+func remove(basePath string, id string) error {
+	layout, format, err := Detect(basePath, id)
+	if err != nil {
+		return err
+	}
+
+	if layout == LayoutDir {
+		if err := removeDir(basePath, id, format); err != nil {
+			return err
+		}
+	}
+
+	path, err := collectionFile(basePath, id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeDir deletes the files of the LayoutDir collection id stored in format, then its
+// directory if nothing else is left in it.
+func removeDir(basePath string, id string, format string) error {
+	dir, err := collectionDir(basePath, id)
+	if err != nil {
+		return err
+	}
+	files, err := readFiles(dir, format)
+	if err != nil {
+		return err
+	}
+	if err := removeFiles(dir, files); err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+		os.Remove(dir)
+	}
+	return nil
+}
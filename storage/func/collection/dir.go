@@ -0,0 +1,494 @@
+package collection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"raco/model"
+	"raco/storage/func/schema"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// manifestName and folderManifestName are the file names (without extension) of the
+	// collection and folder manifests in a LayoutDir collection.
+	manifestName       = "collection"
+	folderManifestName = "folder"
+
+	maxFolderDepth = 32
+	maxStemLength  = 64
+)
+
+// manifest is the collection file of a LayoutDir collection. Requests and Folders list request
+// file names (without extension) and folder directories in order; Order is Collection.Order.
+type manifest struct {
+	SchemaVersion      int               `json:"schema_version,omitempty" yaml:"schema_version,omitempty"`
+	ID                 string            `json:"id" yaml:"id"`
+	Name               string            `json:"name" yaml:"name"`
+	Variables          map[string]string `json:"variables,omitempty" yaml:"variables,omitempty"`
+	PreRequestScript   string            `json:"pre_request_script,omitempty" yaml:"pre_request_script,omitempty"`
+	PostResponseScript string            `json:"post_response_script,omitempty" yaml:"post_response_script,omitempty"`
+	LenientVariables   bool              `json:"lenient_variables,omitempty" yaml:"lenient_variables,omitempty"`
+	Defaults           *model.Defaults   `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	Requests           []string          `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Folders            []string          `json:"folders,omitempty" yaml:"folders,omitempty"`
+	Order              []string          `json:"order,omitempty" yaml:"order,omitempty"`
+}
+
+// folderManifest is the folder file in each folder directory.
+type folderManifest struct {
+	ID                 string            `json:"id,omitempty" yaml:"id,omitempty"`
+	Name               string            `json:"name" yaml:"name"`
+	Headers            map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Variables          map[string]string `json:"variables,omitempty" yaml:"variables,omitempty"`
+	PreRequestScript   string            `json:"pre_request_script,omitempty" yaml:"pre_request_script,omitempty"`
+	PostResponseScript string            `json:"post_response_script,omitempty" yaml:"post_response_script,omitempty"`
+	Requests           []string          `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Folders            []string          `json:"folders,omitempty" yaml:"folders,omitempty"`
+	Order              []string          `json:"order,omitempty" yaml:"order,omitempty"`
+}
+
+// loadDir reads a LayoutDir collection. Request files and folder directories missing from a
+// manifest are appended in name order, so a request can be added by dropping in its file. Every
+// file is migrated from the schema version of the manifest; migrated reports whether a migration
+// step changed any of them.
+func loadDir(basePath string, id string, format string) (col *model.Collection, migrated bool, err error) {
+	dir, err := collectionDir(basePath, id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	manifestPath := filepath.Join(dir, manifestName+"."+format)
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, false, err
+	}
+	version, err := schema.Inspect(data, format)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", filepath.Base(manifestPath), err)
+	}
+
+	var m manifest
+	if err := decodeFile(manifestPath, format, schema.KindCollection, version, &m, &migrated); err != nil {
+		return nil, false, err
+	}
+
+	col = &model.Collection{
+		SchemaVersion:      version,
+		ID:                 m.ID,
+		Name:               m.Name,
+		Variables:          m.Variables,
+		PreRequestScript:   m.PreRequestScript,
+		PostResponseScript: m.PostResponseScript,
+		LenientVariables:   m.LenientVariables,
+		Defaults:           m.Defaults,
+		Order:              m.Order,
+	}
+	if col.ID == "" {
+		col.ID = id
+	}
+
+	col.Requests, col.Folders, err = loadEntries(dir, m.Requests, m.Folders, format, version, 0, &migrated)
+	if err != nil {
+		return nil, false, err
+	}
+	return col, migrated, nil
+}
+
+func loadFolder(dir string, format string, version int, depth int, migrated *bool) (*model.Folder, error) {
+	if depth > maxFolderDepth {
+		return nil, fmt.Errorf("%s: folders nested more than %d levels", dir, maxFolderDepth)
+	}
+
+	var m folderManifest
+	if err := decodeFile(filepath.Join(dir, folderManifestName+"."+format), format, schema.KindFolder, version, &m, migrated); err != nil {
+		return nil, err
+	}
+
+	folder := &model.Folder{
+		ID:                 m.ID,
+		Name:               m.Name,
+		Headers:            m.Headers,
+		Variables:          m.Variables,
+		PreRequestScript:   m.PreRequestScript,
+		PostResponseScript: m.PostResponseScript,
+		Order:              m.Order,
+	}
+
+	var err error
+	folder.Requests, folder.Folders, err = loadEntries(dir, m.Requests, m.Folders, format, version, depth, migrated)
+	if err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// loadEntries reads the requests and folders of one directory in manifest order, followed by
+// the unlisted ones.
+func loadEntries(dir string, requestStems, folderDirs []string, format string, version int, depth int, migrated *bool) ([]*model.Request, []*model.Folder, error) {
+	requestStems, folderDirs, err := withUnlisted(dir, requestStems, folderDirs, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requests := make([]*model.Request, 0, len(requestStems))
+	for _, stem := range requestStems {
+		if !validIDPattern.MatchString(stem) {
+			return nil, nil, fmt.Errorf("%s: invalid request file name %q", dir, stem)
+		}
+		var req model.Request
+		if err := decodeFile(filepath.Join(dir, stem+"."+format), format, schema.KindRequest, version, &req, migrated); err != nil {
+			return nil, nil, err
+		}
+		requests = append(requests, &req)
+	}
+
+	var folders []*model.Folder
+	for _, name := range folderDirs {
+		if !validIDPattern.MatchString(name) {
+			return nil, nil, fmt.Errorf("%s: invalid folder directory %q", dir, name)
+		}
+		folder, err := loadFolder(filepath.Join(dir, name), format, version, depth+1, migrated)
+		if err != nil {
+			return nil, nil, err
+		}
+		folders = append(folders, folder)
+	}
+	return requests, folders, nil
+}
+
+func withUnlisted(dir string, requestStems, folderDirs []string, format string) ([]string, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	listed := make(map[string]bool, len(requestStems)+len(folderDirs))
+	for _, stem := range requestStems {
+		listed[stem+"."+format] = true
+	}
+	for _, name := range folderDirs {
+		listed[name+"/"] = true
+	}
+
+	// ReadDir returns entries sorted by name.
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			_, err := os.Stat(filepath.Join(dir, name, folderManifestName+"."+format))
+			if err == nil && !listed[name+"/"] {
+				folderDirs = append(folderDirs, name)
+			}
+			continue
+		}
+		stem, ok := strings.CutSuffix(name, "."+format)
+		if !ok || listed[name] || stem == manifestName || stem == folderManifestName {
+			continue
+		}
+		requestStems = append(requestStems, stem)
+	}
+	return requestStems, folderDirs, nil
+}
+
+// saveDir writes col as a LayoutDir collection. Files whose content is unchanged are left alone
+// and files of requests or folders that no longer exist are removed, so a change to one request
+// touches only that request's file (and the manifest when the order changes). Files of another
+// format are not touched; Migrate removes them.
+func saveDir(basePath string, col *model.Collection, format string) error {
+	dir, err := collectionDir(basePath, col.ID)
+	if err != nil {
+		return err
+	}
+
+	col.SchemaVersion = schema.Current
+	files := make(map[string][]byte)
+	m := manifest{
+		SchemaVersion:      schema.Current,
+		ID:                 col.ID,
+		Name:               col.Name,
+		Variables:          col.Variables,
+		PreRequestScript:   col.PreRequestScript,
+		PostResponseScript: col.PostResponseScript,
+		LenientVariables:   col.LenientVariables,
+		Defaults:           col.Defaults,
+		Order:              col.Order,
+	}
+	m.Requests, m.Folders, err = addEntries(files, "", col.Requests, col.Folders, format, 0)
+	if err != nil {
+		return err
+	}
+	if files[manifestName+"."+format], err = encode(m, format); err != nil {
+		return err
+	}
+
+	return writeTree(dir, files, format)
+}
+
+// addEntries encodes requests and folders below rel into files and returns their names in order.
+func addEntries(files map[string][]byte, rel string, requests []*model.Request, folders []*model.Folder, format string, depth int) ([]string, []string, error) {
+	if depth > maxFolderDepth {
+		return nil, nil, fmt.Errorf("folders nested more than %d levels", maxFolderDepth)
+	}
+
+	used := map[string]bool{manifestName: true, folderManifestName: true}
+
+	stems := make([]string, 0, len(requests))
+	for i, req := range requests {
+		if req == nil {
+			continue
+		}
+		stem := uniqueStem(req.Name, req.ID, "request-"+strconv.Itoa(i+1), used)
+		data, err := encode(req, format)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[filepath.Join(rel, stem+"."+format)] = data
+		stems = append(stems, stem)
+	}
+
+	dirs := make([]string, 0, len(folders))
+	for i, folder := range folders {
+		if folder == nil {
+			continue
+		}
+		name := uniqueStem(folder.Name, folder.ID, "folder-"+strconv.Itoa(i+1), used)
+		folderRel := filepath.Join(rel, name)
+
+		m := folderManifest{
+			ID:                 folder.ID,
+			Name:               folder.Name,
+			Headers:            folder.Headers,
+			Variables:          folder.Variables,
+			PreRequestScript:   folder.PreRequestScript,
+			PostResponseScript: folder.PostResponseScript,
+			Order:              folder.Order,
+		}
+		var err error
+		m.Requests, m.Folders, err = addEntries(files, folderRel, folder.Requests, folder.Folders, format, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if files[filepath.Join(folderRel, folderManifestName+"."+format)], err = encode(m, format); err != nil {
+			return nil, nil, err
+		}
+		dirs = append(dirs, name)
+	}
+	return stems, dirs, nil
+}
+
+// uniqueStem derives a file name from name, else id, else fallback, and makes it unique in used.
+// The same collection therefore always maps to the same file names.
+func uniqueStem(name, id, fallback string, used map[string]bool) string {
+	stem := slug(name)
+	if stem == "" {
+		stem = slug(id)
+	}
+	if stem == "" {
+		stem = fallback
+	}
+
+	candidate := stem
+	for n := 2; used[candidate]; n++ {
+		suffix := "-" + strconv.Itoa(n)
+		base := stem
+		if len(base)+len(suffix) > maxStemLength {
+			base = strings.TrimRight(base[:maxStemLength-len(suffix)], "-")
+		}
+		candidate = base + suffix
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// slug lowercases s and keeps letters and digits, joining runs of anything else with "-".
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		isAlnum := (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
+		if !isAlnum {
+			dash = b.Len() > 0
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteRune(r)
+		if b.Len() >= maxStemLength {
+			break
+		}
+	}
+	return strings.TrimRight(b.String(), "-")
+}
+
+// writeTree makes dir contain files (paths relative to dir): changed files are replaced
+// atomically and the files the loader read for format that are not in files are removed, along
+// with directories that removal leaves empty. Anything else, such as a README, fixtures or files
+// of another format, is kept.
+func writeTree(dir string, files map[string][]byte, format string) error {
+	owned, err := readFiles(dir, format)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(files))
+	for rel := range files {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+
+	for _, rel := range paths {
+		path := filepath.Join(dir, rel)
+		existing, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(existing, files[rel]) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		tempPath := path + ".tmp"
+		if err := os.WriteFile(tempPath, files[rel], 0600); err != nil {
+			os.Remove(tempPath)
+			return err
+		}
+		if err := os.Rename(tempPath, path); err != nil {
+			return err
+		}
+	}
+
+	stale := make([]string, 0)
+	for _, rel := range owned {
+		if _, keep := files[rel]; !keep {
+			stale = append(stale, rel)
+		}
+	}
+	return removeFiles(dir, stale)
+}
+
+// readFiles returns the paths, relative to dir, of the files loadDir reads for a LayoutDir
+// collection of format: the collection manifest and, in dir and in every folder directory below
+// it, the folder manifest and the request files. A missing dir has none.
+func readFiles(dir string, format string) ([]string, error) {
+	var files []string
+	var walk func(rel string, depth int) error
+	walk = func(rel string, depth int) error {
+		if depth > maxFolderDepth {
+			return fmt.Errorf("%s: folders nested more than %d levels", dir, maxFolderDepth)
+		}
+		entries, err := os.ReadDir(filepath.Join(dir, rel))
+		if err != nil {
+			if os.IsNotExist(err) && rel == "" {
+				return nil
+			}
+			return err
+		}
+		ownManifest := manifestName
+		if rel != "" {
+			ownManifest = folderManifestName
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() {
+				if !validIDPattern.MatchString(name) {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(dir, rel, name, folderManifestName+"."+format)); err != nil {
+					continue
+				}
+				if err := walk(filepath.Join(rel, name), depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			stem, ok := strings.CutSuffix(name, "."+format)
+			if !ok {
+				continue
+			}
+			isRequest := stem != manifestName && stem != folderManifestName && validIDPattern.MatchString(stem)
+			if stem == ownManifest || isRequest {
+				files = append(files, filepath.Join(rel, name))
+			}
+		}
+		return nil
+	}
+	if err := walk("", 0); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// removeFiles deletes the files at rels below dir, then every directory below dir that held one
+// of them and is left empty.
+func removeFiles(dir string, rels []string) error {
+	emptied := make(map[string]bool)
+	for _, rel := range rels {
+		if err := os.Remove(filepath.Join(dir, rel)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for parent := filepath.Dir(rel); parent != "."; parent = filepath.Dir(parent) {
+			emptied[parent] = true
+		}
+	}
+
+	dirs := make([]string, 0, len(emptied))
+	for rel := range emptied {
+		dirs = append(dirs, rel)
+	}
+	// Longest first, so a directory is empty by the time its parent is checked.
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	for _, rel := range dirs {
+		path := filepath.Join(dir, rel)
+		if entries, err := os.ReadDir(path); err == nil && len(entries) == 0 {
+			os.Remove(path)
+		}
+	}
+	return nil
+}
+
+func encode(v interface{}, format string) ([]byte, error) {
+	if format == FormatJSON {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+	return yaml.Marshal(v)
+}
+
+// decodeFile reads a document of the given kind, migrating it from schema version version, and
+// sets migrated when a migration step changed it.
+func decodeFile(path string, format string, kind schema.Kind, version int, v interface{}, migrated *bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	data, changed, err := schema.Migrate(data, format, kind, version)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if changed {
+		*migrated = true
+	}
+	if format == FormatJSON {
+		err = json.Unmarshal(data, v)
+	}
+	if format != FormatJSON {
+		err = yaml.Unmarshal(data, v)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
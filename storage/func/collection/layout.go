@@ -0,0 +1,95 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Layout is how a collection is kept on disk.
+type Layout string
+
+const (
+	// LayoutFile keeps the whole collection in collections/<id>.json.
+	LayoutFile Layout = "file"
+	// LayoutDir keeps a directory per collection: a manifest with the collection settings and the
+	// order of its requests and folders, one file per request and a subdirectory per folder.
+	LayoutDir Layout = "dir"
+)
+
+// Formats of the files in a LayoutDir collection.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// ParseLayout validates a layout name.
+func ParseLayout(s string) (Layout, error) {
+	switch Layout(s) {
+	case LayoutFile, LayoutDir:
+		return Layout(s), nil
+	}
+	return "", fmt.Errorf("unknown layout %q (use file or dir)", s)
+}
+
+// ParseFormat validates a file format name; empty means FormatYAML.
+func ParseFormat(s string) (string, error) {
+	switch s {
+	case "", FormatYAML, "yml":
+		return FormatYAML, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown format %q (use yaml or json)", s)
+}
+
+// Detect reports the layout of collection id and, for LayoutDir, the format of its files. When
+// both layouts exist, as after an interrupted migration, the directory wins.
+func Detect(basePath string, id string) (Layout, string, error) {
+	if !validIDPattern.MatchString(id) {
+		return "", "", errors.New("invalid collection ID format")
+	}
+
+	dir, err := collectionDir(basePath, id)
+	if err != nil {
+		return "", "", err
+	}
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		if _, err := os.Stat(filepath.Join(dir, manifestName+"."+format)); err == nil {
+			return LayoutDir, format, nil
+		}
+	}
+
+	path, err := collectionFile(basePath, id)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", "", err
+	}
+	return LayoutFile, FormatJSON, nil
+}
+
+// collectionFile is the LayoutFile path of id, checked to stay inside the collections directory.
+func collectionFile(basePath string, id string) (string, error) {
+	return contained(basePath, filepath.Join(basePath, "collections", id+".json"))
+}
+
+// collectionDir is the LayoutDir directory of id, checked to stay inside the collections directory.
+func collectionDir(basePath string, id string) (string, error) {
+	return contained(basePath, filepath.Join(basePath, "collections", id))
+}
+
+func contained(basePath string, path string) (string, error) {
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolvedPath = path
+	}
+
+	expectedDir := filepath.Join(basePath, "collections")
+	if !isPathContained(resolvedPath, expectedDir) {
+		return "", errors.New("path traversal detected")
+	}
+	return resolvedPath, nil
+}
@@ -4,32 +4,68 @@ import (
 	"os"
 	"path/filepath"
 	"raco/model"
+	"strings"
 )
 
+// List loads every collection in either layout, skipping those that fail to load.
 func List(basePath string) ([]*model.Collection, error) {
+	ids, err := IDs(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := make([]*model.Collection, 0, len(ids))
+	for _, id := range ids {
+		col, err := Load(basePath, id)
+		if err != nil {
+			continue
+		}
+		collections = append(collections, col)
+	}
+
+	return collections, nil
+}
+
+// IDs returns the ID of every stored collection, in either layout, without loading them.
+func IDs(basePath string) ([]string, error) {
 	collectionsPath := filepath.Join(basePath, "collections")
 	entries, err := os.ReadDir(collectionsPath)
 	if err != nil {
 		notExist := os.IsNotExist(err)
 		if notExist {
-			return []*model.Collection{}, nil
+			return []string{}, nil
 		}
 		return nil, err
 	}
 
-	collections := make([]*model.Collection, 0, len(entries))
+	ids := make([]string, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
 	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+		id := entry.Name()
+		if entry.IsDir() && !hasManifest(filepath.Join(collectionsPath, id)) {
 			continue
 		}
-		name := entry.Name()
-		id := name[:len(name)-5]
-		col, err := Load(basePath, id)
-		if err != nil {
+		if !entry.IsDir() {
+			var ok bool
+			if id, ok = strings.CutSuffix(id, ".json"); !ok {
+				continue
+			}
+		}
+		if seen[id] || !validIDPattern.MatchString(id) {
 			continue
 		}
-		collections = append(collections, col)
+		seen[id] = true
+		ids = append(ids, id)
 	}
 
-	return collections, nil
+	return ids, nil
+}
+
+func hasManifest(dir string) bool {
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		if _, err := os.Stat(filepath.Join(dir, manifestName+"."+format)); err == nil {
+			return true
+		}
+	}
+	return false
 }
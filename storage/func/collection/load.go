@@ -2,44 +2,215 @@ package collection
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"raco/model"
+	"raco/storage/func"
+	"raco/storage/func/schema"
 	"regexp"
 	"strings"
 )
 
 var validIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$`)
 
+// maxReadAttempts bounds how often Load reads a collection again while a save changes it.
+const maxReadAttempts = 3
+
+// Load reads collection id in whichever layout it is stored and records its revision. It takes no
+// lock, so a read-only checkout loads like any other; a read that overlaps a save shows as a
+// revision that changed while it ran and is retried. A collection stored in an older schema
+// version that a migration step changed is upgraded and written back, after a copy of the
+// original is kept in the backups directory; one that no step changed is left as it is.
 func Load(basePath string, id string) (*model.Collection, error) {
-	if !validIDPattern.MatchString(id) {
-		return nil, errors.New("invalid collection ID format")
+	col, layout, format, migrated, err := readStable(basePath, id)
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		// A failed upgrade leaves the stored copy as it was, as in a read-only checkout; it is
+		// migrated again on the next load and raco doctor reports it.
+		upgradeLoaded(basePath, id, col, layout, format)
+	}
+	return col, nil
+}
+
+// load is Load for a caller that holds the lock.
+func load(basePath string, id string) (*model.Collection, error) {
+	col, layout, format, migrated, err := read(basePath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if migrated {
+		upgrade(basePath, id, col, layout, format)
+	}
+	if col.Revision, err = revision(basePath, id); err != nil {
+		return nil, err
+	}
+	return col, nil
+}
+
+// Inspect reads collection id and migrates it in memory without writing anything. It returns the
+// schema version of the stored copy, or the error that keeps it from loading.
+func Inspect(basePath string, id string) (int, error) {
+	col, _, _, _, err := readStable(basePath, id)
+	if err != nil {
+		return 0, err
+	}
+	return col.SchemaVersion, nil
+}
+
+// Upgrade writes collection id in the current schema version when it is stored in an older one,
+// like Load, but reports a failure to do so. It returns the version the collection was stored in.
+func Upgrade(basePath string, id string) (int, error) {
+	unlock, err := lock(basePath, id)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	col, layout, format, _, err := read(basePath, id)
+	if err != nil {
+		return 0, err
+	}
+	from := col.SchemaVersion
+	if from < schema.Current {
+		if err := upgrade(basePath, id, col, layout, format); err != nil {
+			return from, err
+		}
+	}
+	return from, nil
+}
+
+// read loads collection id, migrated in memory to the current schema version, and reports
+// whether a migration step changed it. Its SchemaVersion is that of the stored copy.
+func read(basePath string, id string) (*model.Collection, Layout, string, bool, error) {
+	layout, format, err := Detect(basePath, id)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+
+	var col *model.Collection
+	var migrated bool
+	if layout == LayoutDir {
+		col, migrated, err = loadDir(basePath, id, format)
 	}
+	if layout != LayoutDir {
+		col, migrated, err = loadFile(basePath, id)
+	}
+	if err != nil {
+		return nil, "", "", false, err
+	}
+	return col, layout, format, migrated, nil
+}
 
-	path := filepath.Join(basePath, "collections", id+".json")
+// readStable is read without the lock: it compares the revision before and after reading and
+// reads again when a save changed the collection in between. The result carries that revision.
+func readStable(basePath string, id string) (*model.Collection, Layout, string, bool, error) {
+	for attempt := 1; ; attempt++ {
+		before, err := revision(basePath, id)
+		if err != nil {
+			return nil, "", "", false, err
+		}
+		col, layout, format, migrated, readErr := read(basePath, id)
+		after, err := revision(basePath, id)
+		if err != nil {
+			return nil, "", "", false, err
+		}
+		if before == after {
+			if readErr != nil {
+				return nil, "", "", false, readErr
+			}
+			col.Revision = after
+			return col, layout, format, migrated, nil
+		}
+		if attempt == maxReadAttempts {
+			return nil, "", "", false, fmt.Errorf("collection %s kept changing while it was read: %w", id, storagefunc.ErrConflict)
+		}
+	}
+}
+
+// upgradeLoaded takes the lock and upgrades col, read by readStable, unless the stored copy has
+// changed since.
+func upgradeLoaded(basePath string, id string, col *model.Collection, layout Layout, format string) error {
+	unlock, err := lock(basePath, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	resolvedPath, err := filepath.EvalSymlinks(path)
+	current, err := revision(basePath, id)
 	if err != nil {
-		resolvedPath = path
+		return err
+	}
+	if current != col.Revision {
+		return fmt.Errorf("collection %s: %w", id, storagefunc.ErrConflict)
+	}
+	if err := upgrade(basePath, id, col, layout, format); err != nil {
+		return err
+	}
+	col.Revision, err = revision(basePath, id)
+	return err
+}
+
+// upgrade backs up the stored copy of collection id and writes col, migrated from it, in the
+// current schema version. The caller holds the lock.
+func upgrade(basePath string, id string, col *model.Collection, layout Layout, format string) error {
+	if col.ID != id {
+		return fmt.Errorf("stored ID %q does not match %q", col.ID, id)
 	}
 
-	expectedDir := filepath.Join(basePath, "collections")
-	if !isPathContained(resolvedPath, expectedDir) {
-		return nil, errors.New("path traversal detected")
+	from := col.SchemaVersion
+	rel := filepath.Join("collections", id+".json")
+	if layout == LayoutDir {
+		rel = filepath.Join("collections", id)
+	}
+	if err := schema.Backup(basePath, rel, from); err != nil {
+		return fmt.Errorf("backing up: %w", err)
+	}
+
+	var err error
+	if layout == LayoutDir {
+		err = saveDir(basePath, col, format)
+	}
+	if layout != LayoutDir {
+		err = saveFile(basePath, col)
+	}
+	if err != nil {
+		col.SchemaVersion = from
+		return err
+	}
+	return nil
+}
+
+func loadFile(basePath string, id string) (*model.Collection, bool, error) {
+	resolvedPath, err := collectionFile(basePath, id)
+	if err != nil {
+		return nil, false, err
 	}
 
 	data, err := os.ReadFile(resolvedPath)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	version, err := schema.Inspect(data, schema.FormatJSON)
+	if err != nil {
+		return nil, false, err
+	}
+	data, migrated, err := schema.Migrate(data, schema.FormatJSON, schema.KindCollection, version)
+	if err != nil {
+		return nil, false, err
 	}
 
 	var col model.Collection
 	if err := json.Unmarshal(data, &col); err != nil {
-		return nil, err
+		return nil, false, err
 	}
+	col.SchemaVersion = version
 
-	return &col, nil
+	return &col, migrated, nil
 }
 
 // isPathContained ensures path is under base (prevents ".." traversal), not that path has no dots (e.g. .json).
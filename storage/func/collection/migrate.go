@@ -0,0 +1,70 @@
+package collection
+
+import (
+	"errors"
+	"os"
+)
+
+// Migrate converts collection id to layout (and format, for LayoutDir). The new copy is written
+// before the old one is removed, and Load prefers the directory when both exist, so an
+// interrupted migration loses nothing.
+func Migrate(basePath string, id string, layout Layout, format string) error {
+	if !validIDPattern.MatchString(id) {
+		return errors.New("invalid collection ID format")
+	}
+	unlock, err := lock(basePath, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, currentFormat, err := Detect(basePath, id)
+	if err != nil {
+		return err
+	}
+
+	col, err := load(basePath, id)
+	if err != nil {
+		return err
+	}
+
+	if layout == LayoutDir {
+		if format, err = ParseFormat(format); err != nil {
+			return err
+		}
+	}
+	if current == layout && (layout == LayoutFile || currentFormat == format) {
+		return nil
+	}
+
+	if current == LayoutDir && layout == LayoutFile {
+		if err := saveFile(basePath, col); err != nil {
+			return err
+		}
+		return removeDir(basePath, id, currentFormat)
+	}
+
+	// To LayoutDir, either from LayoutFile or from another format.
+	if err := saveDir(basePath, col, format); err != nil {
+		return err
+	}
+	if current == LayoutDir {
+		dir, err := collectionDir(basePath, id)
+		if err != nil {
+			return err
+		}
+		stale, err := readFiles(dir, currentFormat)
+		if err != nil {
+			return err
+		}
+		return removeFiles(dir, stale)
+	}
+	if current == LayoutFile {
+		path, err := collectionFile(basePath, id)
+		if err != nil {
+			return err
+		}
+		return os.Remove(path)
+	}
+	return nil
+}
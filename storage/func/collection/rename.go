@@ -0,0 +1,68 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Rename sets the display name of collection id and, when newID differs from id, moves it to
+// newID in the same layout and format. The copy under newID is written before the old one is
+// removed.
+func Rename(basePath string, id string, name string, newID string) error {
+	if newID == "" {
+		newID = id
+	}
+	if !validIDPattern.MatchString(id) || !validIDPattern.MatchString(newID) {
+		return errors.New("invalid collection ID format")
+	}
+	if strings.TrimSpace(name) == "" {
+		return errors.New("collection name is required")
+	}
+
+	ids := []string{id, newID}
+	sort.Strings(ids)
+	for i, lockID := range ids {
+		if i > 0 && lockID == ids[i-1] {
+			continue
+		}
+		unlock, err := lock(basePath, lockID)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	layout, format, err := Detect(basePath, id)
+	if err != nil {
+		return err
+	}
+	col, err := load(basePath, id)
+	if err != nil {
+		return err
+	}
+	col.Name = name
+	if newID == id {
+		return saveChecked(basePath, col, layout, format)
+	}
+
+	_, _, err = Detect(basePath, newID)
+	if err == nil {
+		return fmt.Errorf("collection %s already exists", newID)
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	col.ID = newID
+	col.Revision = ""
+	for _, req := range col.AllRequests() {
+		req.CollectionID = newID
+	}
+	if err := saveChecked(basePath, col, layout, format); err != nil {
+		return err
+	}
+	return remove(basePath, id)
+}
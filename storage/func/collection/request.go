@@ -0,0 +1,249 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"raco/model"
+	"raco/util"
+	"sort"
+	"strings"
+)
+
+// Destination is where MoveRequest and CopyRequest put a request: a collection, an optional
+// slash-separated folder path and a 1-based position, 0 for the end.
+type Destination struct {
+	Collection string
+	Folder     string
+	Position   int
+	// Name renames the request; empty keeps its name, except that a copy placed next to its
+	// original becomes "<name> copy".
+	Name string
+}
+
+// ErrHasDependents is returned when a request that others depend on would be removed from
+// their collection.
+var ErrHasDependents = errors.New("other requests depend on it")
+
+// UpdateRequest applies edit to request ref of collection id, validates the result and saves
+// the collection, all under the collection lock.
+func UpdateRequest(basePath string, id string, ref string, edit func(req *model.Request) error) (*model.Request, error) {
+	var updated *model.Request
+	err := modify(basePath, id, func(col *model.Collection) error {
+		req, err := col.FindRequest(ref)
+		if err != nil {
+			return err
+		}
+		draft := req.Clone()
+		if err := edit(draft); err != nil {
+			return err
+		}
+		if err := ValidateRequest(draft); err != nil {
+			return err
+		}
+		*req = *draft
+		updated = req
+		return nil
+	})
+	return updated, err
+}
+
+// DeleteRequest removes request ref from collection id. Unless force is set it fails with
+// ErrHasDependents while other requests list it in depends_on.
+func DeleteRequest(basePath string, id string, ref string, force bool) (*model.Request, error) {
+	var removed *model.Request
+	err := modify(basePath, id, func(col *model.Collection) error {
+		req, err := col.FindRequest(ref)
+		if err != nil {
+			return err
+		}
+		if err := checkDependents(col, req, force); err != nil {
+			return err
+		}
+		col.RemoveRequest(req)
+		removed = req
+		return nil
+	})
+	return removed, err
+}
+
+// MoveRequest moves request ref of collection id to dest, within the collection or into
+// another one. A request keeps its ID unless the target collection already uses it.
+func MoveRequest(basePath string, id string, ref string, dest Destination, force bool) (*model.Request, error) {
+	return transfer(basePath, id, ref, dest, force, true)
+}
+
+// CopyRequest copies request ref of collection id to dest under a new ID.
+func CopyRequest(basePath string, id string, ref string, dest Destination) (*model.Request, error) {
+	return transfer(basePath, id, ref, dest, false, false)
+}
+
+func transfer(basePath string, id string, ref string, dest Destination, force bool, move bool) (*model.Request, error) {
+	if dest.Collection == "" {
+		dest.Collection = id
+	}
+	if !validIDPattern.MatchString(id) || !validIDPattern.MatchString(dest.Collection) {
+		return nil, errors.New("invalid collection ID format")
+	}
+
+	ids := []string{id, dest.Collection}
+	sort.Strings(ids)
+	for i, lockID := range ids {
+		if i > 0 && lockID == ids[i-1] {
+			continue
+		}
+		unlock, err := lock(basePath, lockID)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	source, err := load(basePath, id)
+	if err != nil {
+		return nil, err
+	}
+	target := source
+	if dest.Collection != id {
+		if target, err = load(basePath, dest.Collection); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := source.FindRequest(ref)
+	if err != nil {
+		return nil, err
+	}
+	var folder *model.Folder
+	if dest.Folder != "" {
+		if folder, err = target.FindFolder(dest.Folder); err != nil {
+			return nil, err
+		}
+	}
+
+	placed := req
+	if move {
+		if target != source {
+			if err := checkDependents(source, req, force); err != nil {
+				return nil, err
+			}
+		}
+		source.RemoveRequest(req)
+	}
+	if !move {
+		placed = req.Clone()
+		placed.ID = util.GenerateID()
+		if dest.Name == "" && target == source && sameFolder(source, req, folder) {
+			placed.Name = copyName(source, req, folder)
+		}
+	}
+	if dest.Name != "" {
+		placed.Name = dest.Name
+	}
+	if target != source && requestIDTaken(target, placed.ID) {
+		placed.ID = util.GenerateID()
+	}
+	placed.CollectionID = target.ID
+
+	if err := ValidateRequest(placed); err != nil {
+		return nil, err
+	}
+	siblings := target.Requests
+	if folder != nil {
+		siblings = folder.Requests
+	}
+	for _, other := range siblings {
+		if other != placed && other.Name == placed.Name {
+			return nil, fmt.Errorf("a request named %q already exists there; pick another name", placed.Name)
+		}
+	}
+	target.InsertRequest(placed, folder, dest.Position)
+
+	// The target is written first, so an interrupted move leaves the request in both
+	// collections rather than in neither.
+	if err := saveLoaded(basePath, target); err != nil {
+		return nil, err
+	}
+	if move && target != source {
+		if err := saveLoaded(basePath, source); err != nil {
+			return nil, fmt.Errorf("request copied to %s but not removed from %s: %w", target.ID, source.ID, err)
+		}
+	}
+	return placed, nil
+}
+
+// modify loads collection id, applies fn and saves the result, holding the lock throughout.
+func modify(basePath string, id string, fn func(col *model.Collection) error) error {
+	if !validIDPattern.MatchString(id) {
+		return errors.New("invalid collection ID format")
+	}
+	unlock, err := lock(basePath, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	col, err := load(basePath, id)
+	if err != nil {
+		return err
+	}
+	if err := fn(col); err != nil {
+		return err
+	}
+	return saveLoaded(basePath, col)
+}
+
+// saveLoaded writes col back in the layout and format it was loaded from. The caller holds the
+// lock.
+func saveLoaded(basePath string, col *model.Collection) error {
+	layout, format, err := Detect(basePath, col.ID)
+	if err != nil {
+		return err
+	}
+	return saveChecked(basePath, col, layout, format)
+}
+
+func checkDependents(col *model.Collection, req *model.Request, force bool) error {
+	dependents := col.Dependents(req)
+	if force || len(dependents) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(dependents))
+	for _, d := range dependents {
+		names = append(names, d.Name)
+	}
+	return fmt.Errorf("request %q: %w: %s", req.Name, ErrHasDependents, strings.Join(names, ", "))
+}
+
+// copyName is the first of "<name> copy", "<name> copy 2", ... unused next to req.
+func copyName(col *model.Collection, req *model.Request, folder *model.Folder) string {
+	siblings := col.Requests
+	if folder != nil {
+		siblings = folder.Requests
+	}
+	taken := make(map[string]bool, len(siblings))
+	for _, other := range siblings {
+		taken[other.Name] = true
+	}
+	name := req.Name + " copy"
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("%s copy %d", req.Name, n)
+	}
+	return name
+}
+
+func sameFolder(col *model.Collection, req *model.Request, folder *model.Folder) bool {
+	path := col.FolderPath(req)
+	if len(path) == 0 {
+		return folder == nil
+	}
+	return path[len(path)-1] == folder
+}
+
+func requestIDTaken(col *model.Collection, id string) bool {
+	for _, req := range col.AllRequests() {
+		if req.ID == id {
+			return true
+		}
+	}
+	return false
+}
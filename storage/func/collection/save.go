@@ -3,12 +3,16 @@ package collection
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"raco/model"
 	"raco/storage/func"
+	"raco/storage/func/schema"
 )
 
+// Save writes col in the layout it is already stored in; new collections use LayoutFile. It fails
+// with storagefunc.ErrConflict when col was loaded and the stored copy has changed since.
 func Save(basePath string, col *model.Collection) error {
 	if col == nil {
 		return errors.New("collection is nil")
@@ -18,22 +22,123 @@ func Save(basePath string, col *model.Collection) error {
 		return errors.New("invalid collection ID format")
 	}
 
-	if err := storagefunc.EnsureDir(filepath.Join(basePath, "collections")); err != nil {
+	if err := storagefunc.EnsureDir(basePath); err != nil {
 		return err
 	}
+	unlock, err := lock(basePath, col.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	layout, format, err := Detect(basePath, col.ID)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		layout = LayoutFile
+	}
+	return saveChecked(basePath, col, layout, format)
+}
+
+// SaveAs writes col in the given layout; format applies to LayoutDir. It does not remove a copy
+// stored in the other layout; see Migrate. Like Save, it checks col's revision first.
+func SaveAs(basePath string, col *model.Collection, layout Layout, format string) error {
+	if col == nil {
+		return errors.New("collection is nil")
+	}
+
+	if !validIDPattern.MatchString(col.ID) {
+		return errors.New("invalid collection ID format")
+	}
+
+	if err := storagefunc.EnsureDir(basePath); err != nil {
+		return err
+	}
+	unlock, err := lock(basePath, col.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	path := filepath.Join(basePath, "collections", col.ID+".json")
+	return saveChecked(basePath, col, layout, format)
+}
+
+// saveChecked writes col after comparing its revision with the stored copy, and records the new
+// revision. The caller holds the lock.
+func saveChecked(basePath string, col *model.Collection, layout Layout, format string) error {
+	if col.Revision != "" {
+		current, err := revision(basePath, col.ID)
+		if err != nil {
+			return err
+		}
+		if current != col.Revision {
+			return fmt.Errorf("collection %s: %w", col.ID, storagefunc.ErrConflict)
+		}
+	}
+
+	if layout == LayoutDir {
+		format, err := ParseFormat(format)
+		if err != nil {
+			return err
+		}
+		if err := saveDir(basePath, col, format); err != nil {
+			return err
+		}
+	}
+	if layout != LayoutDir {
+		if err := saveFile(basePath, col); err != nil {
+			return err
+		}
+	}
 
-	resolvedPath, err := filepath.EvalSymlinks(path)
+	current, err := revision(basePath, col.ID)
 	if err != nil {
-		resolvedPath = path
+		return err
 	}
+	col.Revision = current
+	return nil
+}
 
-	expectedDir := filepath.Join(basePath, "collections")
-	if !isPathContained(resolvedPath, expectedDir) {
-		return errors.New("path traversal detected")
+// revision identifies the stored copy of collection id, or is "" when there is none.
+func revision(basePath string, id string) (string, error) {
+	layout, _, err := Detect(basePath, id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if layout == LayoutDir {
+		dir, err := collectionDir(basePath, id)
+		if err != nil {
+			return "", err
+		}
+		return storagefunc.HashTree(dir)
+	}
+	path, err := collectionFile(basePath, id)
+	if err != nil {
+		return "", err
+	}
+	return storagefunc.HashFile(path)
+}
+
+// lock takes the lock that guards every file of collection id, in either layout.
+func lock(basePath string, id string) (func(), error) {
+	path, err := contained(basePath, filepath.Join(basePath, "collections", id+".lock"))
+	if err != nil {
+		return nil, err
+	}
+	return storagefunc.Lock(path)
+}
+
+func saveFile(basePath string, col *model.Collection) error {
+	resolvedPath, err := collectionFile(basePath, col.ID)
+	if err != nil {
+		return err
 	}
 
+	col.SchemaVersion = schema.Current
 	tempPath := resolvedPath + ".tmp"
 	data, err := json.MarshalIndent(col, "", "  ")
 	if err != nil {
@@ -0,0 +1,128 @@
+package collection
+
+import (
+	"fmt"
+	"raco/model"
+	"raco/util"
+	"regexp"
+	"strings"
+)
+
+// maxPatternLen matches the limit assertions and extractors apply when they run.
+const maxPatternLen = 4096
+
+var (
+	valueAssertions = map[model.AssertionType]bool{
+		model.AssertStatusCode: true,
+		model.AssertJSONPath:   true,
+		model.AssertHeader:     true,
+		model.AssertXPath:      true,
+		model.AssertCSS:        true,
+	}
+	operators = map[string]bool{
+		model.OpEquals:      true,
+		model.OpNotEquals:   true,
+		model.OpContains:    true,
+		model.OpNotContains: true,
+		model.OpMatches:     true,
+		model.OpGreater:     true,
+		model.OpGreaterEq:   true,
+		model.OpLess:        true,
+		model.OpLessEq:      true,
+		model.OpExists:      true,
+		model.OpNotExists:   true,
+	}
+)
+
+// ValidateRequest checks what a request edited outside the TUI must get right before it is
+// saved: a name, a known method, a URL, assertions and extractors a run can evaluate and a
+// known stage. raco lint covers the rest, such as variables and dependencies.
+func ValidateRequest(req *model.Request) error {
+	if req == nil {
+		return fmt.Errorf("request is nil")
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("request name is required")
+	}
+	if !util.ValidateMethod(req.Method) {
+		return fmt.Errorf("unknown HTTP method %q", req.Method)
+	}
+	if strings.TrimSpace(req.URL) == "" {
+		return fmt.Errorf("request URL is required")
+	}
+	for i, a := range req.Assertions {
+		if err := ValidateAssertion(a); err != nil {
+			return fmt.Errorf("assertion %d: %w", i+1, err)
+		}
+	}
+	for i, e := range req.Extractors {
+		if err := ValidateExtractor(e); err != nil {
+			return fmt.Errorf("extractor %d: %w", i+1, err)
+		}
+	}
+	if req.Stage != "" && req.Stage != model.StageSetup && req.Stage != model.StageTeardown {
+		return fmt.Errorf("unknown stage %q", req.Stage)
+	}
+	return nil
+}
+
+// ValidateAssertion checks the type, operator and field of an assertion and compiles its
+// regular expression.
+func ValidateAssertion(a model.Assertion) error {
+	if a.Type == model.AssertRegex {
+		if a.Operator != model.OpMatches {
+			return fmt.Errorf("regex assertions only support the %s operator", model.OpMatches)
+		}
+		return validatePattern(a.Value)
+	}
+	if !valueAssertions[a.Type] {
+		return fmt.Errorf("unknown assertion type %q", a.Type)
+	}
+	if !operators[a.Operator] {
+		return fmt.Errorf("unknown operator %q", a.Operator)
+	}
+	if a.Type != model.AssertStatusCode && strings.TrimSpace(a.Field) == "" {
+		return fmt.Errorf("%s assertions need a field", a.Type)
+	}
+	if a.Operator == model.OpMatches {
+		return validatePattern(a.Value)
+	}
+	return nil
+}
+
+// ValidateExtractor checks that an extractor has a known type, a target and a source, or for
+// regex a pattern with a capture group.
+func ValidateExtractor(e model.Extractor) error {
+	if strings.TrimSpace(e.Target) == "" {
+		return fmt.Errorf("extractor target is required")
+	}
+	switch e.Type {
+	case model.ExtractRegex:
+		if err := validatePattern(e.Pattern); err != nil {
+			return err
+		}
+		if regexp.MustCompile(e.Pattern).NumSubexp() == 0 {
+			return fmt.Errorf("regex pattern %q has no capture group", e.Pattern)
+		}
+		return nil
+	case model.ExtractJSONPath, model.ExtractHeader, model.ExtractXPath, model.ExtractCSS:
+		if strings.TrimSpace(e.Source) == "" {
+			return fmt.Errorf("%s extractors need a source", e.Type)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown extractor type %q", e.Type)
+}
+
+func validatePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("regex pattern is empty")
+	}
+	if len(pattern) > maxPatternLen {
+		return fmt.Errorf("regex pattern too long (max 4KB)")
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("invalid regex %q: %w", pattern, err)
+	}
+	return nil
+}
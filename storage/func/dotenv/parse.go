@@ -0,0 +1,139 @@
+// Package dotenv reads and writes .env files.
+package dotenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Variable is one assignment of a .env file, in file order.
+type Variable struct {
+	Key   string
+	Value string
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// Parse reads .env content: KEY=value lines with an optional "export " prefix, # comments,
+// single-quoted values taken literally, double-quoted values with \n, \t, \r, \", \\ and \$
+// escapes, and unquoted values ending at a " #" comment. Quoted values may span lines. A key
+// assigned twice keeps its last value in its first position.
+func Parse(content string) ([]Variable, error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	var vars []Variable
+	index := make(map[string]int)
+	lines := strings.Split(content, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "export"); ok && len(rest) > 0 && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=value", lineNo)
+		}
+		if !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNo, key)
+		}
+		raw = strings.TrimLeft(raw, " \t")
+
+		var value string
+		if raw != "" && (raw[0] == '"' || raw[0] == '\'') {
+			quote := raw[0]
+			body := raw[1:]
+			// Keep reading lines until the closing quote; quoted values may span lines.
+			end := closingQuote(body, quote)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				body += "\n" + lines[i]
+				end = closingQuote(body, quote)
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated %c quote", lineNo, quote)
+			}
+			trailing := strings.TrimSpace(body[end+1:])
+			if trailing != "" && !strings.HasPrefix(trailing, "#") {
+				return nil, fmt.Errorf("line %d: unexpected text after closing quote", lineNo)
+			}
+			value = body[:end]
+			if quote == '"' {
+				value = unescape(value)
+			}
+		}
+		if raw == "" || (raw[0] != '"' && raw[0] != '\'') {
+			value = stripComment(raw)
+		}
+
+		if pos, seen := index[key]; seen {
+			vars[pos].Value = value
+			continue
+		}
+		index[key] = len(vars)
+		vars = append(vars, Variable{Key: key, Value: value})
+	}
+	return vars, nil
+}
+
+// closingQuote returns the index of the quote that ends s, skipping backslash escapes inside
+// double quotes, or -1.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\', '$':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// stripComment drops a trailing " # comment" from an unquoted value.
+func stripComment(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+			s = s[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(s)
+}
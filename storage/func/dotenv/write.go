@@ -0,0 +1,54 @@
+package dotenv
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var shellNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Write renders vars as a .env file that Parse reads back unchanged. Values are double-quoted
+// when they contain anything besides plain characters.
+func Write(w io.Writer, vars []Variable) error {
+	for _, v := range vars {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", v.Key, quote(v.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteShell renders vars as POSIX shell export statements with single-quoted values, suitable
+// for eval or source. Names that are not valid shell identifiers are rejected.
+func WriteShell(w io.Writer, vars []Variable) error {
+	for _, v := range vars {
+		if !shellNamePattern.MatchString(v.Key) {
+			return fmt.Errorf("%q is not a valid shell variable name", v.Key)
+		}
+	}
+	for _, v := range vars {
+		value := "'" + strings.ReplaceAll(v.Value, "'", `'\''`) + "'"
+		if _, err := fmt.Fprintf(w, "export %s=%s\n", v.Key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quote(value string) string {
+	plain := value != ""
+	for _, r := range value {
+		if r <= ' ' || r == '"' || r == '\'' || r == '#' || r == '\\' || r == '$' || r == '`' {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -2,9 +2,12 @@ package environment
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"raco/model"
+	"raco/storage/func"
+	"raco/storage/func/schema"
 	"regexp"
 	"strings"
 
@@ -13,9 +16,74 @@ import (
 
 var validEnvNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$`)
 
+// Load reads environment name and records its revision. Like a collection, an environment stored
+// in an older schema version is backed up and written back upgraded, but only when a migration
+// step changed it; otherwise the file is left as it is.
 func Load(basePath string, name string) (*model.Environment, error) {
+	env, path, migrated, err := read(basePath, name)
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		// As for collections, a failed upgrade is retried on the next load.
+		upgrade(basePath, name, path, env)
+	}
+	return env, nil
+}
+
+// Inspect reads environment name and migrates it in memory without writing anything. It returns
+// the schema version of the file, or the error that keeps it from loading.
+func Inspect(basePath string, name string) (int, error) {
+	env, _, _, err := read(basePath, name)
+	if err != nil {
+		return 0, err
+	}
+	return env.SchemaVersion, nil
+}
+
+// Upgrade writes environment name in the current schema version when it is stored in an older
+// one, like Load, but reports a failure to do so. It returns the version the file was stored in.
+func Upgrade(basePath string, name string) (int, error) {
+	env, path, _, err := read(basePath, name)
+	if err != nil {
+		return 0, err
+	}
+	from := env.SchemaVersion
+	if from < schema.Current {
+		if err := upgrade(basePath, name, path, env); err != nil {
+			return from, err
+		}
+	}
+	return from, nil
+}
+
+// Names returns the names of the stored environments, without local override files.
+func Names(basePath string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(basePath, "environments"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".yaml")
+		if entry.IsDir() || !ok || !validEnvNamePattern.MatchString(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// read loads environment name, migrated in memory to the current schema version, and returns the
+// path of its file and whether a migration step changed it. Its SchemaVersion is that of the
+// file and its Revision that of the content read.
+func read(basePath string, name string) (*model.Environment, string, bool, error) {
 	if !validEnvNamePattern.MatchString(name) {
-		return nil, errors.New("invalid environment name format")
+		return nil, "", false, errors.New("invalid environment name format")
 	}
 
 	path := filepath.Join(basePath, "environments", name+".yaml")
@@ -27,20 +95,59 @@ func Load(basePath string, name string) (*model.Environment, error) {
 
 	expectedDir := filepath.Join(basePath, "environments")
 	if !isPathContained(resolvedPath, expectedDir) {
-		return nil, errors.New("path traversal detected")
+		return nil, "", false, errors.New("path traversal detected")
 	}
 
 	data, err := os.ReadFile(resolvedPath)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
+	}
+
+	version, err := schema.Inspect(data, schema.FormatYAML)
+	if err != nil {
+		return nil, "", false, err
+	}
+	migrated, changed, err := schema.Migrate(data, schema.FormatYAML, schema.KindEnvironment, version)
+	if err != nil {
+		return nil, "", false, err
 	}
 
 	var env model.Environment
-	if err := yaml.Unmarshal(data, &env); err != nil {
-		return nil, err
+	if err := yaml.Unmarshal(migrated, &env); err != nil {
+		return nil, "", false, err
+	}
+	env.SchemaVersion = version
+	env.Revision = storagefunc.HashBytes(data)
+
+	return &env, resolvedPath, changed, nil
+}
+
+// upgrade backs up the file of environment name and writes env, migrated from it, in the current
+// schema version. It fails with storagefunc.ErrConflict when the file changed since env was read.
+func upgrade(basePath string, name string, path string, env *model.Environment) error {
+	unlock, err := storagefunc.Lock(filepath.Join(basePath, "environments", name+".lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := storagefunc.HashFile(path)
+	if err != nil {
+		return err
+	}
+	if current != env.Revision {
+		return fmt.Errorf("environment %s: %w", name, storagefunc.ErrConflict)
+	}
+	if err := schema.Backup(basePath, filepath.Join("environments", name+".yaml"), env.SchemaVersion); err != nil {
+		return fmt.Errorf("backing up: %w", err)
 	}
 
-	return &env, nil
+	revision, err := write(path, env)
+	if err != nil {
+		return err
+	}
+	env.Revision = revision
+	return nil
 }
 
 // isPathContained ensures path is under base (prevents ".." traversal), not that path has no dots (e.g. .yaml).
@@ -0,0 +1,30 @@
+package environment
+
+import (
+	"os"
+	"regexp"
+)
+
+// referencePattern matches ${env:NAME}, a reference to a variable of the raco process.
+var referencePattern = regexp.MustCompile(`\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Reference returns the ${env:NAME} reference to the process variable name.
+func Reference(name string) string {
+	return "${env:" + name + "}"
+}
+
+// ResolveReferences replaces every ${env:NAME} in value with the process variable NAME. An unset
+// one becomes the placeholder {{env:NAME}}, which no variable defines, so a request that uses it
+// fails as referencing an undefined variable.
+func ResolveReferences(value string) string {
+	if !referencePattern.MatchString(value) {
+		return value
+	}
+	return referencePattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := referencePattern.FindStringSubmatch(ref)[1]
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		}
+		return "{{env:" + name + "}}"
+	})
+}
@@ -0,0 +1,128 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"raco/secret"
+	"raco/storage/func"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RekeyResult counts what Rekey re-encrypted.
+type RekeyResult struct {
+	Files   int
+	Secrets int
+}
+
+// Rekey re-encrypts the secrets of every environment file, local overrides included, from one
+// key to another. Every value is decrypted before anything is written, so a wrong current key
+// leaves all files untouched.
+func Rekey(basePath string, from, to *secret.Box) (RekeyResult, error) {
+	dir := filepath.Join(basePath, "environments")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return RekeyResult{}, nil
+		}
+		return RekeyResult{}, err
+	}
+
+	type pending struct {
+		path     string
+		revision string
+		doc      yaml.Node
+	}
+	var files []pending
+	result := RekeyResult{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return RekeyResult{}, err
+		}
+
+		// Editing the node tree keeps the rest of the file (order, comments) as it was.
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return RekeyResult{}, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		secrets := secretsNode(&doc)
+		if secrets == nil || len(secrets.Content) == 0 {
+			continue
+		}
+
+		for i := 0; i+1 < len(secrets.Content); i += 2 {
+			name, value := secrets.Content[i].Value, secrets.Content[i+1]
+			plaintext, err := from.Open(value.Value)
+			if err != nil {
+				return RekeyResult{}, fmt.Errorf("%s: cannot decrypt secret %s: %w", entry.Name(), name, err)
+			}
+			sealed, err := to.Seal(plaintext)
+			if err != nil {
+				return RekeyResult{}, err
+			}
+			value.Value = sealed
+			result.Secrets++
+		}
+		files = append(files, pending{path: path, revision: storagefunc.HashBytes(data), doc: doc})
+	}
+
+	// Lock every file and check it is unchanged before writing any, so a concurrent edit cannot
+	// leave some files sealed with the old key.
+	for _, file := range files {
+		unlock, err := storagefunc.Lock(strings.TrimSuffix(file.path, ".yaml") + ".lock")
+		if err != nil {
+			return RekeyResult{}, err
+		}
+		defer unlock()
+
+		current, err := storagefunc.HashFile(file.path)
+		if err != nil {
+			return RekeyResult{}, err
+		}
+		if current != file.revision {
+			return RekeyResult{}, fmt.Errorf("%s: %w", filepath.Base(file.path), storagefunc.ErrConflict)
+		}
+	}
+
+	for _, file := range files {
+		data, err := yaml.Marshal(&file.doc)
+		if err != nil {
+			return result, err
+		}
+		tempPath := file.path + ".tmp"
+		if err := os.WriteFile(tempPath, data, 0600); err != nil {
+			os.Remove(tempPath)
+			return result, err
+		}
+		if err := os.Rename(tempPath, file.path); err != nil {
+			return result, err
+		}
+		result.Files++
+	}
+	return result, nil
+}
+
+// secretsNode returns the mapping under the top-level "secrets" key of doc, if any.
+func secretsNode(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "secrets" && root.Content[i+1].Kind == yaml.MappingNode {
+			return root.Content[i+1]
+		}
+	}
+	return nil
+}
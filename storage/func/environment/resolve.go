@@ -0,0 +1,177 @@
+package environment
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"raco/model"
+	"raco/secret"
+	"raco/util"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxExtendsDepth bounds how many environments one inheritance chain may contain.
+const maxExtendsDepth = 16
+
+// LocalSuffix names the per-environment override file, e.g. staging.local.yaml, which holds
+// personal values and is kept out of version control.
+const LocalSuffix = ".local"
+
+// Resolve loads name and merges, from lowest to highest precedence, the environments it extends
+// (outermost first), the environment itself and its local override file. sources maps every
+// variable to the environment (or "<name>.local") that supplied its final value.
+//
+// Each environment's secrets are decrypted and layered above its own variables; the resolved
+// environment keeps the sealed values in Secrets so callers can tell which variables are secret.
+// Decrypted values are registered with util.RegisterSecret so reports and logs mask them.
+// ${env:NAME} references are replaced with the variables of the raco process; see ResolveReferences.
+func Resolve(basePath string, name string) (*model.Environment, map[string]string, error) {
+	resolved, sources, err := merge(basePath, name, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range resolved.Variables {
+		resolved.Variables[k] = ResolveReferences(v)
+	}
+	return resolved, sources, nil
+}
+
+// Merge layers name like Resolve but leaves secrets sealed (their Variables entry holds the
+// encrypted value) and ${env:NAME} references as written. It needs no secret key, which suits
+// displaying and exporting an environment.
+func Merge(basePath string, name string) (*model.Environment, map[string]string, error) {
+	return merge(basePath, name, false)
+}
+
+func merge(basePath string, name string, decrypt bool) (*model.Environment, map[string]string, error) {
+	chain := make([]*model.Environment, 0, 2)
+	seen := make(map[string]bool)
+	trail := make([]string, 0, 2)
+
+	for current := name; current != ""; {
+		trail = append(trail, current)
+		if seen[current] {
+			return nil, nil, fmt.Errorf("environment inheritance cycle: %s", strings.Join(trail, " -> "))
+		}
+		if len(chain) >= maxExtendsDepth {
+			return nil, nil, fmt.Errorf("environment %s extends more than %d levels", name, maxExtendsDepth)
+		}
+		seen[current] = true
+
+		env, err := Load(basePath, current)
+		if err != nil {
+			if current != name && os.IsNotExist(err) {
+				return nil, nil, fmt.Errorf("environment %s extends unknown environment %s", trail[len(trail)-2], current)
+			}
+			return nil, nil, err
+		}
+		chain = append(chain, env)
+		current = env.Extends
+	}
+
+	local, err := LoadLocal(basePath, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved := &model.Environment{
+		Name:      name,
+		Extends:   chain[0].Extends,
+		Variables: make(map[string]string),
+	}
+	sources := make(map[string]string)
+	opener := &secretOpener{basePath: basePath}
+
+	layer := func(env *model.Environment, source string) error {
+		for k, v := range env.Variables {
+			resolved.Variables[k] = v
+			delete(resolved.Secrets, k)
+			sources[k] = source
+		}
+		for k, sealed := range env.Secrets {
+			value := sealed
+			if decrypt {
+				plaintext, err := opener.open(sealed)
+				if err != nil {
+					return fmt.Errorf("environment %s: cannot decrypt secret %s: %w", source, k, err)
+				}
+				util.RegisterSecret(plaintext)
+				value = plaintext
+			}
+			resolved.Variables[k] = value
+			if resolved.Secrets == nil {
+				resolved.Secrets = make(map[string]string)
+			}
+			resolved.Secrets[k] = sealed
+			sources[k] = source + " (secret)"
+		}
+		return nil
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		if err := layer(chain[i], trail[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if local != nil {
+		if err := layer(local, name+LocalSuffix); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return resolved, sources, nil
+}
+
+// secretOpener loads the secret key on first use, so environments without secrets never need one.
+type secretOpener struct {
+	basePath string
+	box      *secret.Box
+}
+
+func (o *secretOpener) open(sealed string) (string, error) {
+	if o.box == nil {
+		box, _, err := secret.Open(o.basePath)
+		if err != nil {
+			return "", err
+		}
+		o.box = box
+	}
+	return o.box.Open(sealed)
+}
+
+// LoadLocal reads the local override file of name, or returns nil when there is none. Only its
+// variables are used.
+func LoadLocal(basePath string, name string) (*model.Environment, error) {
+	if !validEnvNamePattern.MatchString(name) {
+		return nil, errors.New("invalid environment name format")
+	}
+
+	path := filepath.Join(basePath, "environments", name+LocalSuffix+".yaml")
+
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolvedPath = path
+	}
+
+	expectedDir := filepath.Join(basePath, "environments")
+	if !isPathContained(resolvedPath, expectedDir) {
+		return nil, errors.New("path traversal detected")
+	}
+
+	data, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var env model.Environment
+	if err := yaml.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &env, nil
+}
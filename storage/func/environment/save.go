@@ -2,14 +2,19 @@ package environment
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"raco/model"
 	"raco/storage/func"
+	"raco/storage/func/schema"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Save writes env under the lock of its file. It fails with storagefunc.ErrConflict when env was
+// loaded and the file has changed since.
 func Save(basePath string, env *model.Environment) error {
 	if env == nil {
 		return errors.New("environment is nil")
@@ -19,7 +24,7 @@ func Save(basePath string, env *model.Environment) error {
 		return errors.New("invalid environment name format")
 	}
 
-	if err := storagefunc.EnsureDir(filepath.Join(basePath, "environments")); err != nil {
+	if err := storagefunc.EnsureDir(basePath); err != nil {
 		return err
 	}
 
@@ -35,16 +40,138 @@ func Save(basePath string, env *model.Environment) error {
 		return errors.New("path traversal detected")
 	}
 
-	tempPath := resolvedPath + ".tmp"
-	data, err := yaml.Marshal(env)
+	unlock, err := storagefunc.Lock(filepath.Join(expectedDir, env.Name+".lock"))
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
+	if env.Revision != "" {
+		current, err := storagefunc.HashFile(resolvedPath)
+		if err != nil {
+			return err
+		}
+		if current != env.Revision {
+			return fmt.Errorf("environment %s: %w", env.Name, storagefunc.ErrConflict)
+		}
+	}
+
+	revision, err := write(resolvedPath, env)
+	if err != nil {
+		return err
+	}
+	env.Revision = revision
+	return nil
+}
+
+// write replaces the file at path with env in the current schema version and returns the new
+// revision. The caller holds the lock.
+func write(path string, env *model.Environment) (string, error) {
+	stored := *env
+	stored.SchemaVersion = schema.Current
+	data, err := yaml.Marshal(&stored)
+	if err != nil {
+		return "", err
+	}
+
+	if err := replaceFile(path, data); err != nil {
+		return "", err
+	}
+	env.SchemaVersion = schema.Current
+	return storagefunc.HashBytes(data), nil
+}
+
+// replaceFile writes data to path through a temporary file, so readers never see part of it.
+func replaceFile(path string, data []byte) error {
+	tempPath := path + ".tmp"
 	if err := os.WriteFile(tempPath, data, 0600); err != nil {
 		os.Remove(tempPath)
 		return err
 	}
+	return os.Rename(tempPath, path)
+}
+
+// SaveLocal writes env to the local override file of its name under the lock of that file.
+// Creating the first local override file also adds them to the .gitignore of the environments
+// directory, so personal values stay untracked when the storage directory is under version
+// control; ignored reports whether it did.
+func SaveLocal(basePath string, env *model.Environment) (ignored bool, err error) {
+	if env == nil {
+		return false, errors.New("environment is nil")
+	}
+
+	if !validEnvNamePattern.MatchString(env.Name) {
+		return false, errors.New("invalid environment name format")
+	}
+
+	if err := storagefunc.EnsureDir(basePath); err != nil {
+		return false, err
+	}
 
-	return os.Rename(tempPath, resolvedPath)
+	expectedDir := filepath.Join(basePath, "environments")
+	path := filepath.Join(expectedDir, env.Name+LocalSuffix+".yaml")
+
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolvedPath = path
+	}
+
+	if !isPathContained(resolvedPath, expectedDir) {
+		return false, errors.New("path traversal detected")
+	}
+
+	unlock, err := storagefunc.Lock(filepath.Join(expectedDir, env.Name+LocalSuffix+".lock"))
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	existing, _ := filepath.Glob(filepath.Join(expectedDir, "*"+LocalSuffix+".yaml"))
+	if len(existing) == 0 {
+		ignored, err = ensureLocalIgnored(expectedDir)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Only the variables and secrets of a local override file are used, and it is not versioned.
+	data, err := yaml.Marshal(&localFile{Variables: env.Variables, Secrets: env.Secrets})
+	if err != nil {
+		return false, err
+	}
+	if err := replaceFile(resolvedPath, data); err != nil {
+		return false, err
+	}
+	return ignored, nil
+}
+
+// localFile is the stored form of a local override file.
+type localFile struct {
+	Variables map[string]string `yaml:"variables,omitempty"`
+	Secrets   map[string]string `yaml:"secrets,omitempty"`
+}
+
+// ensureLocalIgnored adds the pattern of local override files to the .gitignore in dir unless
+// the file already lists it, and reports whether it did.
+func ensureLocalIgnored(dir string) (bool, error) {
+	pattern := "*" + LocalSuffix + ".yaml"
+	path := filepath.Join(dir, ".gitignore")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return false, nil
+		}
+	}
+
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	data = append(data, pattern+"\n"...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return false, err
+	}
+	return true, nil
 }
@@ -0,0 +1,32 @@
+package storagefunc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// Fingerprint summarises the name, size and modification time of every file below the
+// collections and environments directories of basePath. It reads no file contents, so it is
+// cheap enough to poll.
+func Fingerprint(basePath string) string {
+	hash := sha256.New()
+	for _, dir := range []string{"collections", "environments"} {
+		root := filepath.Join(basePath, dir)
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || IsScratchFile(d.Name()) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(basePath, path)
+			fmt.Fprintf(hash, "%s\x00%d\x00%d\n", filepath.ToSlash(rel), info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
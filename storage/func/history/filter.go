@@ -0,0 +1,140 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"raco/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects history entries; zero fields match everything.
+type Filter struct {
+	Method   string
+	Protocol string
+	// Host matches a substring of the URL's host, case-insensitively.
+	Host string
+	// Status is an exact code ("404"), a class ("5xx") or "error" for entries without a response.
+	Status string
+	Since  time.Time
+	Until  time.Time
+	// Text matches a substring of the URL, headers, body or error, case-insensitively.
+	Text string
+}
+
+// Validate reports a Status that Match cannot interpret.
+func (f Filter) Validate() error {
+	if f.Status == "" || f.Status == "error" {
+		return nil
+	}
+	if len(f.Status) == 3 && strings.HasSuffix(strings.ToLower(f.Status), "xx") && f.Status[0] >= '1' && f.Status[0] <= '5' {
+		return nil
+	}
+	if code, err := strconv.Atoi(f.Status); err == nil && code >= 100 && code <= 599 {
+		return nil
+	}
+	return fmt.Errorf("invalid status %q (use e.g. 200, 4xx or error)", f.Status)
+}
+
+func (f Filter) Match(e *model.HistoryEntry) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, e.Method) {
+		return false
+	}
+	if f.Protocol != "" && !strings.EqualFold(f.Protocol, e.Protocol) {
+		return false
+	}
+	if f.Host != "" && !strings.Contains(strings.ToLower(host(e.URL)), strings.ToLower(f.Host)) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Timestamp.After(f.Until) {
+		return false
+	}
+	if f.Status != "" && !matchStatus(f.Status, e) {
+		return false
+	}
+	if f.Text != "" && !matchText(strings.ToLower(f.Text), e) {
+		return false
+	}
+	return true
+}
+
+func matchStatus(status string, e *model.HistoryEntry) bool {
+	if status == "error" {
+		return e.StatusCode == 0
+	}
+	if strings.HasSuffix(strings.ToLower(status), "xx") {
+		return e.StatusCode/100 == int(status[0]-'0')
+	}
+	return strconv.Itoa(e.StatusCode) == status
+}
+
+func matchText(text string, e *model.HistoryEntry) bool {
+	if strings.Contains(strings.ToLower(e.URL), text) ||
+		strings.Contains(strings.ToLower(e.Body), text) ||
+		strings.Contains(strings.ToLower(e.Error), text) {
+		return true
+	}
+	for k, v := range e.Headers {
+		if strings.Contains(strings.ToLower(k+": "+v), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// host returns the host of rawURL, which may lack a scheme (e.g. a gRPC address).
+func host(rawURL string) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "//" + rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.Host
+}
+
+// ParseTime reads a --since/--until value: a duration before now ("24h", "30m"), a day
+// ("2026-10-01") or an RFC 3339 timestamp.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if strings.HasSuffix(s, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use e.g. 24h, 7d, 2026-10-01 or RFC 3339)", s)
+}
+
+// Find returns the entry whose ID is id or starts with it; a prefix must be unique.
+func Find(entries []*model.HistoryEntry, id string) (*model.HistoryEntry, error) {
+	var found *model.HistoryEntry
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+		if id != "" && strings.HasPrefix(e.ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("history ID %q is ambiguous", id)
+			}
+			found = e
+		}
+	}
+	if found == nil {
+		return nil, errors.New("no history entry " + strconv.Quote(id))
+	}
+	return found, nil
+}
@@ -0,0 +1,108 @@
+// Package history keeps an append-only log of executed requests in <store>/history as JSON
+// lines, rotating the file when it grows past MaxFileSize.
+package history
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"raco/model"
+	"raco/storage/func/jsonl"
+	"raco/util"
+	"strings"
+)
+
+const (
+	// MaxFileSize is the size at which the current file is rotated.
+	MaxFileSize = 1 << 20
+	// MaxRotated is how many rotated files are kept besides the current one.
+	MaxRotated = 4
+	// MaxBodySize bounds the request body stored with an entry.
+	MaxBodySize = 16 << 10
+
+	dirName = "history"
+)
+
+// log returns the history log of the store at basePath: history/history.jsonl and its rotated
+// files.
+func log(basePath string) jsonl.Log[*model.HistoryEntry] {
+	return jsonl.Log[*model.HistoryEntry]{
+		Dir:         filepath.Join(basePath, dirName),
+		Name:        "history",
+		MaxFileSize: MaxFileSize,
+		MaxRotated:  MaxRotated,
+	}
+}
+
+// Append redacts entry and adds it to the log. Sensitive headers and query parameters,
+// sensitive JSON fields and registered secrets are masked before anything is written.
+func Append(basePath string, entry *model.HistoryEntry) error {
+	if entry == nil {
+		return nil
+	}
+	return log(basePath).Append(Redact(entry))
+}
+
+// Redact returns a copy of entry that is safe to store.
+func Redact(entry *model.HistoryEntry) *model.HistoryEntry {
+	redacted := *entry
+	redacted.URL = util.RedactSecrets(redactQuery(entry.URL))
+
+	redacted.Headers = util.RedactHeaders(entry.Headers)
+	for k, v := range redacted.Headers {
+		redacted.Headers[k] = util.RedactSecrets(v)
+	}
+
+	body := entry.Body
+	if len(body) > MaxBodySize {
+		body = body[:MaxBodySize] + fmt.Sprintf("... [truncated, %d bytes]", len(entry.Body))
+	}
+	redacted.Body = util.RedactSecrets(util.RedactJSON(body))
+	redacted.Error = util.RedactSecrets(entry.Error)
+	return &redacted
+}
+
+// redactQuery masks the values of sensitive query parameters such as api_key or token.
+func redactQuery(rawURL string) string {
+	base, query, ok := strings.Cut(rawURL, "?")
+	if !ok {
+		return rawURL
+	}
+	fragment := ""
+	if i := strings.Index(query, "#"); i >= 0 {
+		query, fragment = query[:i], query[i:]
+	}
+
+	parts := strings.Split(query, "&")
+	for i, part := range parts {
+		key, _, hasValue := strings.Cut(part, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if hasValue && util.IsSensitiveKey(name) {
+			parts[i] = key + "=[REDACTED]"
+		}
+	}
+	return base + "?" + strings.Join(parts, "&") + fragment
+}
+
+// Load returns every stored entry, oldest first. Lines that cannot be parsed are skipped.
+func Load(basePath string) ([]*model.HistoryEntry, error) {
+	loaded, err := log(basePath).Load()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*model.HistoryEntry, 0, len(loaded))
+	for _, entry := range loaded {
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Clear removes the current and all rotated history files.
+func Clear(basePath string) error {
+	return log(basePath).Clear()
+}
@@ -0,0 +1,145 @@
+// Package jsonl keeps an append-only log of records as JSON lines in one directory, rotating the
+// current file into numbered ones when it grows past a size. The history and the request
+// metrics of a store are such logs.
+package jsonl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"raco/storage/func"
+)
+
+// maxLineSize bounds one record when reading; longer lines are skipped.
+const maxLineSize = 1 << 20
+
+// Log is a log of records of type T: <Dir>/<Name>.jsonl is the current file and <Name>.1.jsonl
+// up to <Name>.<MaxRotated>.jsonl hold older records, the highest number the oldest.
+type Log[T any] struct {
+	Dir  string
+	Name string
+	// MaxFileSize is the size at which the current file is rotated.
+	MaxFileSize int64
+	// MaxRotated is how many rotated files are kept besides the current one.
+	MaxRotated int
+}
+
+// Append adds record to the current file, rotating it first when the record would take it past
+// MaxFileSize. It holds <Name>.jsonl.lock meanwhile, so processes that append to the same log at
+// once never rotate it twice or write to a file that is being renamed.
+func (l Log[T]) Append(record T) error {
+	if err := os.MkdirAll(l.Dir, 0700); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	unlock, err := storagefunc.Lock(l.path(0) + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := l.path(0)
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) > l.MaxFileSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// rotate shifts <Name>.jsonl to <Name>.1.jsonl, <Name>.1.jsonl to <Name>.2.jsonl and so on,
+// dropping the oldest file.
+func (l Log[T]) rotate() error {
+	os.Remove(l.path(l.MaxRotated))
+	for i := l.MaxRotated - 1; i >= 1; i-- {
+		err := os.Rename(l.path(i), l.path(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.path(0), l.path(1))
+}
+
+// path returns the current file for n == 0 and the n-th rotated file otherwise.
+func (l Log[T]) path(n int) string {
+	if n == 0 {
+		return filepath.Join(l.Dir, l.Name+".jsonl")
+	}
+	return filepath.Join(l.Dir, fmt.Sprintf("%s.%d.jsonl", l.Name, n))
+}
+
+// Load returns every stored record, oldest first. Lines that cannot be parsed are skipped.
+func (l Log[T]) Load() ([]T, error) {
+	records := make([]T, 0)
+	for i := l.MaxRotated; i >= 0; i-- {
+		loaded, err := loadFile[T](l.path(i))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		records = append(records, loaded...)
+	}
+	return records, nil
+}
+
+func loadFile[T any](path string) ([]T, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []T
+	reader := bufio.NewReaderSize(file, 64<<10)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && len(line) <= maxLineSize {
+			var record T
+			if json.Unmarshal(line, &record) == nil {
+				records = append(records, record)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return records, nil
+}
+
+// Clear removes the current and all rotated files, holding the lock Append takes.
+func (l Log[T]) Clear() error {
+	unlock, err := storagefunc.Lock(l.path(0) + ".lock")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer unlock()
+
+	for i := 0; i <= l.MaxRotated; i++ {
+		if err := os.Remove(l.path(i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
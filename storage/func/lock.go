@@ -0,0 +1,74 @@
+package storagefunc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned when another process still holds a lock after LockTimeout.
+var ErrLocked = errors.New("locked by another raco process")
+
+const (
+	// LockTimeout is how long Lock waits for a lock held by another process.
+	LockTimeout = 5 * time.Second
+	// staleLockAge is the age after which a lock is assumed to belong to a process that died
+	// while holding it. Writes hold locks for milliseconds.
+	staleLockAge = 30 * time.Second
+	lockPoll     = 25 * time.Millisecond
+)
+
+// Lock takes the advisory lock file at path, waiting up to LockTimeout, and returns the function
+// that releases it. Every raco process takes the same lock before writing the files it guards, so
+// two writes never interleave. Reads take no lock; they compare the revision before and after
+// reading instead, see HashFile and HashTree.
+func Lock(path string) (func(), error) {
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if breakStale(path) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), ErrLocked)
+		}
+		time.Sleep(lockPoll)
+	}
+}
+
+// breakStale removes the lock at path when it is older than staleLockAge and reports whether it
+// did. The lock is first renamed to a name of this process's own, which only one waiter can do,
+// and is checked for staleness again under that name: when another waiter broke it and took a
+// fresh lock in between, that fresh lock is what was moved, and it is put back.
+func breakStale(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) <= staleLockAge {
+		return false
+	}
+
+	aside := fmt.Sprintf("%s.%d.%d.stale", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, aside); err != nil {
+		return false
+	}
+	defer os.Remove(aside)
+
+	info, err = os.Stat(aside)
+	if err == nil && time.Since(info.ModTime()) <= staleLockAge {
+		// Link never replaces a lock that was taken since.
+		os.Link(aside, path)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package storagefunc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrConflict is returned by a save when the stored copy changed after it was loaded.
+var ErrConflict = errors.New("changed on disk since it was loaded")
+
+// HashBytes returns the revision of a file with the given content.
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// HashFile returns the revision of the file at path, or "" when it does not exist.
+func HashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return HashBytes(data), nil
+}
+
+// HashTree returns the revision of every regular file below dir, by relative path and content,
+// or "" when dir does not exist. Temporary and lock files are ignored.
+func HashTree(dir string) (string, error) {
+	paths := make([]string, 0)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && !IsScratchFile(d.Name()) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		hash.Write([]byte(filepath.ToSlash(rel)))
+		hash.Write([]byte{0})
+		hash.Write([]byte(HashBytes(data)))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16]), nil
+}
+
+// IsScratchFile reports whether name is a temporary or lock file written while saving.
+func IsScratchFile(name string) bool {
+	return strings.HasSuffix(name, ".tmp") || strings.HasSuffix(name, ".lock")
+}
@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"raco/storage/func"
+	"strconv"
+)
+
+// BackupDir is the directory of basePath that keeps the originals of files upgraded from
+// version from, in the same layout as the store.
+func BackupDir(basePath string, from int) string {
+	return filepath.Join(basePath, "backups", "schema-v"+strconv.Itoa(from))
+}
+
+// Backup copies rel, a file or a LayoutDir collection relative to basePath, into BackupDir
+// before it is upgraded. An earlier backup of the same path is replaced. Temporary and lock files
+// are not copied.
+func Backup(basePath string, rel string, from int) error {
+	if !filepath.IsLocal(rel) {
+		return errors.New("backup path is outside the store")
+	}
+	path := filepath.Join(basePath, rel)
+	target := filepath.Join(BackupDir(basePath, from), rel)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return copyFile(path, target)
+	}
+
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || storagefunc.IsScratchFile(d.Name()) {
+			return nil
+		}
+		fileRel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		return copyFile(p, filepath.Join(target, fileRel))
+	})
+}
+
+func copyFile(from, to string) error {
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(to, data, 0600)
+}
@@ -0,0 +1,56 @@
+package schema
+
+import "strings"
+
+// Migration upgrades documents from version From to From+1. Each step edits one object of a
+// document in place and is nil when the version does not change that kind. Requests and folders
+// nested in a collection file are passed to Request and Folder one by one, so a step is written
+// once and applies to both collection layouts.
+type Migration struct {
+	From        int
+	Description string
+	Collection  func(obj Object) error
+	Folder      func(obj Object) error
+	Request     func(obj Object) error
+	Environment func(obj Object) error
+}
+
+func (m Migration) step(kind Kind) func(obj Object) error {
+	switch kind {
+	case KindCollection:
+		return m.Collection
+	case KindFolder:
+		return m.Folder
+	case KindRequest:
+		return m.Request
+	case KindEnvironment:
+		return m.Environment
+	}
+	return nil
+}
+
+// migrations lists every upgrade in order; the last one has From Current-1. A change to the
+// stored format bumps Current and adds its migration here.
+var migrations = []Migration{
+	{
+		From:        0,
+		Description: "Add schema_version; upper-case request methods",
+		Request: func(obj Object) error {
+			if method, ok := obj.String("method"); ok {
+				obj.SetString("method", strings.ToUpper(strings.TrimSpace(method)))
+			}
+			return nil
+		},
+	},
+}
+
+// Describe returns the descriptions of the migrations that upgrade a file from version from.
+func Describe(from int) []string {
+	var steps []string
+	for _, m := range migrations {
+		if m.From >= from {
+			steps = append(steps, m.Description)
+		}
+	}
+	return steps
+}
@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Object is one mapping of a stored document as a migration step sees it. Steps read and replace
+// single string values, so a YAML document keeps the exact text of every value they do not
+// touch, such as 1.10, 0x1F or 2024-01-02.
+type Object interface {
+	// String returns the value of key when it is a string.
+	String(key string) (string, bool)
+	// SetString replaces the value of key with a string; an unchanged value is not a change.
+	SetString(key string, value string)
+
+	objects(key string) []Object
+}
+
+// document is a parsed stored document that remembers whether a step changed it.
+type document struct {
+	format  string
+	node    *yaml.Node
+	values  map[string]interface{}
+	changed bool
+}
+
+func parse(data []byte, format string) (*document, error) {
+	doc := &document{format: format}
+	if format == FormatJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&doc.values); err != nil {
+			return nil, err
+		}
+		if doc.values == nil {
+			doc.values = make(map[string]interface{})
+		}
+		return doc, nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	doc.node = &node
+	return doc, nil
+}
+
+// root returns the top-level mapping; a YAML document that is empty or not a mapping has no keys.
+func (d *document) root() Object {
+	if d.format == FormatJSON {
+		return jsonObject{doc: d, values: d.values}
+	}
+	node := d.node
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		node = &yaml.Node{Kind: yaml.MappingNode}
+	}
+	return yamlObject{doc: d, node: node}
+}
+
+func (d *document) encode() ([]byte, error) {
+	if d.format == FormatJSON {
+		return json.Marshal(d.values)
+	}
+	return yaml.Marshal(d.node)
+}
+
+type yamlObject struct {
+	doc  *document
+	node *yaml.Node
+}
+
+func (o yamlObject) value(key string) *yaml.Node {
+	for i := 0; i+1 < len(o.node.Content); i += 2 {
+		if o.node.Content[i].Value == key {
+			return o.node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func (o yamlObject) String(key string) (string, bool) {
+	v := o.value(key)
+	if v == nil || v.Kind != yaml.ScalarNode || v.Tag != "!!str" {
+		return "", false
+	}
+	return v.Value, true
+}
+
+func (o yamlObject) SetString(key string, value string) {
+	v := o.value(key)
+	if v != nil && v.Kind == yaml.ScalarNode && v.Value == value && v.Tag == "!!str" {
+		return
+	}
+	if v == nil {
+		v = &yaml.Node{}
+		o.node.Content = append(o.node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+	}
+	*v = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Line: v.Line, Column: v.Column}
+	o.doc.changed = true
+}
+
+func (o yamlObject) objects(key string) []Object {
+	v := o.value(key)
+	if v == nil || v.Kind != yaml.SequenceNode {
+		return nil
+	}
+	out := make([]Object, 0, len(v.Content))
+	for _, item := range v.Content {
+		if item.Kind == yaml.MappingNode {
+			out = append(out, yamlObject{doc: o.doc, node: item})
+		}
+	}
+	return out
+}
+
+type jsonObject struct {
+	doc    *document
+	values map[string]interface{}
+}
+
+func (o jsonObject) String(key string) (string, bool) {
+	v, ok := o.values[key].(string)
+	return v, ok
+}
+
+func (o jsonObject) SetString(key string, value string) {
+	if current, ok := o.values[key].(string); ok && current == value {
+		return
+	}
+	o.values[key] = value
+	o.doc.changed = true
+}
+
+func (o jsonObject) objects(key string) []Object {
+	items, _ := o.values[key].([]interface{})
+	out := make([]Object, 0, len(items))
+	for _, item := range items {
+		if values, ok := item.(map[string]interface{}); ok {
+			out = append(out, jsonObject{doc: o.doc, values: values})
+		}
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+// Package schema versions the stored formats of collections and environments and upgrades
+// documents written by older versions of raco.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Current is the schema version this version of raco writes. Files without a schema_version
+// predate versioning and are version 0.
+const Current = 1
+
+// Field is the key that holds the version in collection files, collection manifests and
+// environment files. Requests and folders of a LayoutDir collection have the version of its
+// manifest.
+const Field = "schema_version"
+
+// Formats of stored documents.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// ErrTooNew is returned for a file written by a newer version of raco.
+var ErrTooNew = errors.New("written by a newer version of raco")
+
+// Kind is the type of a stored document, which selects the migration step applied to it.
+type Kind int
+
+const (
+	// KindCollection is a collection file or the manifest of a LayoutDir collection.
+	KindCollection Kind = iota
+	// KindFolder is a folder manifest or a folder inside a collection file.
+	KindFolder
+	// KindRequest is a request file or a request inside a collection or folder.
+	KindRequest
+	// KindEnvironment is an environment file.
+	KindEnvironment
+)
+
+// maxDepth bounds recursion into the folders of hand-edited collection files.
+const maxDepth = 32
+
+// Inspect returns the schema version of a document without decoding it into a model.
+func Inspect(data []byte, format string) (int, error) {
+	doc, err := decode(data, format)
+	if err != nil {
+		return 0, err
+	}
+	return version(doc)
+}
+
+// Check fails for versions this raco cannot read.
+func Check(v int) error {
+	if v < 0 {
+		return fmt.Errorf("invalid %s %d", Field, v)
+	}
+	if v > Current {
+		return fmt.Errorf("%s %d, this raco reads up to %d: %w", Field, v, Current, ErrTooNew)
+	}
+	return nil
+}
+
+// Migrate upgrades a document of the given kind from version from to Current. Steps edit single
+// values in place, so everything they leave alone keeps its exact text, and migrated reports
+// whether any of them changed the document. Data that no step changed, including data already at
+// Current, is returned as it was. The version field itself is left alone; it is set when the
+// upgraded model is saved.
+func Migrate(data []byte, format string, kind Kind, from int) (out []byte, migrated bool, err error) {
+	if err := Check(from); err != nil {
+		return nil, false, err
+	}
+	if from == Current {
+		return data, false, nil
+	}
+
+	doc, err := parse(data, format)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, m := range migrations {
+		if m.From < from {
+			continue
+		}
+		if err := apply(m, kind, doc.root(), 0); err != nil {
+			return nil, false, fmt.Errorf("migrating to %s %d: %w", Field, m.From+1, err)
+		}
+	}
+	if !doc.changed {
+		return data, false, nil
+	}
+	out, err = doc.encode()
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+// apply runs the step of m for kind on obj and, for collections and folders, on the requests
+// and folders nested in it. Entries that are not objects, such as the file names listed by a
+// LayoutDir manifest, are skipped.
+func apply(m Migration, kind Kind, obj Object, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("folders nested more than %d levels", maxDepth)
+	}
+
+	step := m.step(kind)
+	if step != nil {
+		if err := step(obj); err != nil {
+			return err
+		}
+	}
+	if kind != KindCollection && kind != KindFolder {
+		return nil
+	}
+
+	for _, req := range obj.objects("requests") {
+		if err := apply(m, KindRequest, req, depth); err != nil {
+			return err
+		}
+	}
+	for _, folder := range obj.objects("folders") {
+		if err := apply(m, KindFolder, folder, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func version(doc map[string]interface{}) (int, error) {
+	raw, ok := doc[Field]
+	if !ok || raw == nil {
+		return 0, nil
+	}
+
+	var v int
+	var err error
+	switch n := raw.(type) {
+	case int:
+		v = n
+	case json.Number:
+		v, err = strconv.Atoi(n.String())
+	default:
+		err = errors.New("not a whole number")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s %v: %w", Field, raw, err)
+	}
+	return v, nil
+}
+
+func decode(data []byte, format string) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if format == FormatJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, err
+		}
+	}
+	if format != FormatJSON {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+	if doc == nil {
+		doc = make(map[string]interface{})
+	}
+	return doc, nil
+}
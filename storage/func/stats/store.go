@@ -0,0 +1,53 @@
+// Package stats keeps the request metrics of a store in <store>/metrics as JSON lines, so
+// raco stats can summarise requests made by the TUI, raco req and raco run.
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"raco/metrics"
+	"raco/storage/func/jsonl"
+)
+
+const (
+	// MaxFileSize is the size at which the current file is rotated; about 20,000 metrics.
+	MaxFileSize = 4 << 20
+	// MaxRotated is how many rotated files are kept besides the current one.
+	MaxRotated = 2
+
+	dirName = "metrics"
+	// LegacyFile is the summary file read by earlier versions of raco stats.
+	LegacyFile = "stats.json"
+)
+
+// log returns the metrics log of the store at basePath: metrics/metrics.jsonl and its rotated
+// files.
+func log(basePath string) jsonl.Log[metrics.RequestMetric] {
+	return jsonl.Log[metrics.RequestMetric]{
+		Dir:         filepath.Join(basePath, dirName),
+		Name:        "metrics",
+		MaxFileSize: MaxFileSize,
+		MaxRotated:  MaxRotated,
+	}
+}
+
+// Append adds metric to the store.
+func Append(basePath string, metric metrics.RequestMetric) error {
+	return log(basePath).Append(metric)
+}
+
+// Load returns every stored metric, oldest first. Lines that cannot be parsed are skipped.
+func Load(basePath string) ([]metrics.RequestMetric, error) {
+	return log(basePath).Load()
+}
+
+// Clear removes the stored metrics and the legacy summary file.
+func Clear(basePath string) error {
+	if err := log(basePath).Clear(); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(basePath, LegacyFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"raco/model"
+	"raco/storage/func/history"
+	"time"
+)
+
+// HistoryFilter selects history entries; see history.Filter.
+type HistoryFilter = history.Filter
+
+// AppendHistory adds entry to the workspace's request history after redacting it.
+func (s *Storage) AppendHistory(entry *model.HistoryEntry) error {
+	return history.Append(s.basePath, entry)
+}
+
+// LoadHistory returns the stored history, oldest first.
+func (s *Storage) LoadHistory() ([]*model.HistoryEntry, error) {
+	return history.Load(s.basePath)
+}
+
+func (s *Storage) ClearHistory() error {
+	return history.Clear(s.basePath)
+}
+
+// FindHistory returns the entry with the given ID or unique ID prefix.
+func FindHistory(entries []*model.HistoryEntry, id string) (*model.HistoryEntry, error) {
+	return history.Find(entries, id)
+}
+
+// ParseHistoryTime reads a --since/--until value; see history.ParseTime.
+func ParseHistoryTime(s string, now time.Time) (time.Time, error) {
+	return history.ParseTime(s, now)
+}
@@ -85,31 +85,46 @@ func ImportPostmanCollection(filePath string) (*model.Collection, error) {
 		Requests: make([]*model.Request, 0),
 	}
 
-	extractRequests(postman.Item, collection, 0)
+	collection.Requests, collection.Folders, collection.Order = extractItems(postman.Item, 0)
 
 	return collection, nil
 }
 
 const maxPostmanDepth = 10
 
-func extractRequests(items []PostmanItem, collection *model.Collection, depth int) {
+// extractItems converts Postman items into requests and folders, keeping the folder structure.
+// order lists their IDs as the items were ordered, so requests and folders stay interleaved.
+func extractItems(items []PostmanItem, depth int) ([]*model.Request, []*model.Folder, []string) {
+	requests := make([]*model.Request, 0)
+	folders := make([]*model.Folder, 0)
+	order := make([]string, 0, len(items))
+
 	// Guard against deeply nested Postman collections causing stack exhaustion.
 	if depth > maxPostmanDepth {
-		return
+		return requests, folders, order
 	}
 
 	for _, item := range items {
 		if item.Request != nil {
 			req := convertPostmanRequest(item.Name, item.Request)
 			if req != nil {
-				collection.Requests = append(collection.Requests, req)
+				requests = append(requests, req)
+				order = append(order, req.ID)
 			}
 		}
 
-		if len(item.Item) > 0 {
-			extractRequests(item.Item, collection, depth+1)
+		if item.Request == nil && item.Item != nil {
+			folder := &model.Folder{
+				ID:   util.GenerateID(),
+				Name: item.Name,
+			}
+			folder.Requests, folder.Folders, folder.Order = extractItems(item.Item, depth+1)
+			folders = append(folders, folder)
+			order = append(order, folder.ID)
 		}
 	}
+
+	return requests, folders, order
 }
 
 func convertPostmanRequest(name string, pr *PostmanRequest) *model.Request {
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"raco/model"
+	"raco/storage/func/collection"
+)
+
+// RequestDestination is where MoveRequest and CopyRequest put a request; see
+// collection.Destination.
+type RequestDestination = collection.Destination
+
+// ErrHasDependents is returned when removing a request that others list in depends_on.
+var ErrHasDependents = collection.ErrHasDependents
+
+// UpdateRequest applies edit to request ref (an ID or unique name) of collection id and saves
+// the collection when the edited request is valid.
+func (s *Storage) UpdateRequest(id string, ref string, edit func(req *model.Request) error) (*model.Request, error) {
+	return collection.UpdateRequest(s.basePath, id, ref, edit)
+}
+
+// DeleteRequest removes request ref from collection id; see collection.DeleteRequest.
+func (s *Storage) DeleteRequest(id string, ref string, force bool) (*model.Request, error) {
+	return collection.DeleteRequest(s.basePath, id, ref, force)
+}
+
+// MoveRequest moves request ref of collection id to dest; see collection.MoveRequest.
+func (s *Storage) MoveRequest(id string, ref string, dest RequestDestination, force bool) (*model.Request, error) {
+	return collection.MoveRequest(s.basePath, id, ref, dest, force)
+}
+
+// CopyRequest copies request ref of collection id to dest under a new ID.
+func (s *Storage) CopyRequest(id string, ref string, dest RequestDestination) (*model.Request, error) {
+	return collection.CopyRequest(s.basePath, id, ref, dest)
+}
+
+// ValidateRequest checks a request before it is saved; see collection.ValidateRequest.
+func ValidateRequest(req *model.Request) error {
+	return collection.ValidateRequest(req)
+}
+
+// ValidateAssertion checks an assertion before it is added to a request.
+func ValidateAssertion(a model.Assertion) error {
+	return collection.ValidateAssertion(a)
+}
+
+// ValidateExtractor checks an extractor before it is added to a request.
+func ValidateExtractor(e model.Extractor) error {
+	return collection.ValidateExtractor(e)
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"raco/storage/func/collection"
+	"raco/storage/func/environment"
+	"raco/storage/func/schema"
+)
+
+// SchemaVersion is the version of the collection and environment formats this raco writes.
+const SchemaVersion = schema.Current
+
+// ErrSchemaTooNew is returned for files written by a newer version of raco.
+var ErrSchemaTooNew = schema.ErrTooNew
+
+// Kinds of SchemaFile.
+const (
+	SchemaCollection  = "collection"
+	SchemaEnvironment = "environment"
+)
+
+// SchemaFile reports the schema version of one stored collection or environment.
+type SchemaFile struct {
+	Kind string
+	// Name is the collection ID or the environment name.
+	Name string
+	// Version is the version the file was stored in, before any upgrade.
+	Version int
+	// Upgraded is set when UpgradeSchema wrote the file in SchemaVersion.
+	Upgraded bool
+	// Err is why the file cannot be read, migrated or, for UpgradeSchema, written.
+	Err error
+}
+
+// CheckSchema reads every collection and environment and migrates it in memory, writing nothing.
+func (s *Storage) CheckSchema() ([]SchemaFile, error) {
+	return s.schemaFiles(collection.Inspect, environment.Inspect, false)
+}
+
+// UpgradeSchema writes every collection and environment stored in an older schema version in
+// SchemaVersion, keeping the originals in SchemaBackupDir.
+func (s *Storage) UpgradeSchema() ([]SchemaFile, error) {
+	return s.schemaFiles(collection.Upgrade, environment.Upgrade, true)
+}
+
+// SchemaBackupDir is where the originals of files upgraded from version from are kept.
+func (s *Storage) SchemaBackupDir(from int) string {
+	return schema.BackupDir(s.basePath, from)
+}
+
+// SchemaChanges describes the migrations that upgrade a file from version from.
+func SchemaChanges(from int) []string {
+	return schema.Describe(from)
+}
+
+func (s *Storage) schemaFiles(collectionFn, environmentFn func(basePath, name string) (int, error), upgrade bool) ([]SchemaFile, error) {
+	ids, err := collection.IDs(s.basePath)
+	if err != nil {
+		return nil, err
+	}
+	names, err := environment.Names(s.basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]SchemaFile, 0, len(ids)+len(names))
+	check := func(kind string, name string, fn func(basePath, name string) (int, error)) {
+		version, err := fn(s.basePath, name)
+		file := SchemaFile{Kind: kind, Name: name, Version: version, Err: err}
+		file.Upgraded = upgrade && err == nil && version < SchemaVersion
+		files = append(files, file)
+	}
+	for _, id := range ids {
+		check(SchemaCollection, id, collectionFn)
+	}
+	for _, name := range names {
+		check(SchemaEnvironment, name, environmentFn)
+	}
+	return files, nil
+}
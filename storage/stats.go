@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"raco/metrics"
+	"raco/storage/func/stats"
+)
+
+// RecordMetric adds metric to the workspace's request metrics.
+func (s *Storage) RecordMetric(metric metrics.RequestMetric) error {
+	return stats.Append(s.basePath, metric)
+}
+
+// LoadMetrics returns the stored request metrics, oldest first.
+func (s *Storage) LoadMetrics() ([]metrics.RequestMetric, error) {
+	return stats.Load(s.basePath)
+}
+
+func (s *Storage) ClearMetrics() error {
+	return stats.Clear(s.basePath)
+}
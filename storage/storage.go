@@ -1,5 +1,17 @@
 package storage
 
+import (
+	"raco/storage/func"
+)
+
+var (
+	// ErrConflict is returned when saving a collection or environment that changed on disk
+	// after it was loaded; reload it and apply the change again.
+	ErrConflict = storagefunc.ErrConflict
+	// ErrLocked is returned when another raco process holds a file for longer than expected.
+	ErrLocked = storagefunc.ErrLocked
+)
+
 type Storage struct {
 	basePath string
 }
@@ -7,3 +19,9 @@ type Storage struct {
 func NewStorage(basePath string) *Storage {
 	return &Storage{basePath: basePath}
 }
+
+// Fingerprint changes whenever a file of the collections or environments changes, so a caller
+// can poll it to notice edits made by other processes.
+func (s *Storage) Fingerprint() string {
+	return storagefunc.Fingerprint(s.basePath)
+}
@@ -4,10 +4,13 @@
 package ui
 
 import (
+	"errors"
+	"fmt"
 	"raco/http"
 	"raco/metrics"
 	"raco/model"
 	protocol2 "raco/protocol"
+	"raco/search"
 	"raco/storage"
 	"raco/ui/func/command"
 	"raco/ui/func/helper"
@@ -15,6 +18,9 @@ import (
 	"raco/ui/func/render/modal"
 	"raco/ui/notification"
 	"raco/util"
+	"raco/workspace"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textarea"
@@ -56,9 +62,19 @@ type Model struct {
 	currentResponse  *model.Response
 	httpClient       *http.Client
 	storage          *storage.Storage
+	// globalStorage is the home store when a project workspace is in use, else nil.
+	globalStorage    *storage.Storage
+	// storeFingerprint is the state of the stores last loaded or written by the TUI; see command.Watch.
+	storeFingerprint string
+	// conflict is a save refused because the collection changed on disk; see saveCollection.
+	conflict         *saveConflict
+	globalCollections map[*model.Collection]bool
 	activeEnv        *model.Environment
+	globals          map[string]string
+	runtimeVars      map[string]string
 	selectedIndex    int
 	expandedIndex    int
+	expandedFolders  map[*model.Folder]bool
 	headers          map[string]string
 	headerKeys       []string
 	selectedHeader   int
@@ -80,23 +96,26 @@ type Model struct {
 	requestNameInput textinput.Model
 	showCreateCollection bool
 	showSaveRequest bool
+	collectionSettings collectionSettings
 	metricsCollector *metrics.Collector
 	streamClient        protocol2.StreamHandler
 	streamMessages      []model.StreamMessage
 	streamActive        bool
 	streamInput         textinput.Model
 	commandPaletteInput textinput.Model
-	commandPaletteItems []string
+	commandPaletteItems []paletteEntry
 	commandPaletteIndex int
 	assertionResults    []model.AssertionResult
 	history            []*model.HistoryEntry
 	historyExpanded    bool
+	// environments are searched by the command palette; picking one makes it activeEnv.
+	environments       []*model.Environment
 	sidebarVisible     bool
 	// prevKey stores last key for "gg" (go to top): first "g" sets it, second "g" within same session jumps to index 0.
 	prevKey            string
 }
 
-func NewModel(storagePath string) Model {
+func NewModel(ws workspace.Workspace) Model {
 	methodInput := textinput.New()
 	methodInput.Placeholder = "GET"
 	methodInput.SetValue("GET")
@@ -143,14 +162,22 @@ func NewModel(storagePath string) Model {
 	streamInput.Width = 60
 
 	commandPaletteInput := textinput.New()
-	commandPaletteInput.Placeholder = "Search requests..."
+	commandPaletteInput.Placeholder = "Search requests, environments and history (method:POST host:api)..."
 	commandPaletteInput.Width = 60
 
+	var globalStorage *storage.Storage
+	if ws.IsProject() {
+		globalStorage = storage.NewStorage(ws.Global)
+	}
+
 	return Model{
 		mode:             viewSidebar,
 		httpClient:       http.NewClient(),
-		storage:          storage.NewStorage(storagePath),
+		storage:          storage.NewStorage(ws.Path),
+		globalStorage:    globalStorage,
+		globalCollections: make(map[*model.Collection]bool),
 		collections:      make([]*model.Collection, 0),
+		runtimeVars:      make(map[string]string),
 		headers:          make(map[string]string),
 		headerKeys:       make([]string, 0),
 		selectedHeader:   -1,
@@ -169,6 +196,7 @@ func NewModel(storagePath string) Model {
 		notification:     notification.New(),
 		selectedIndex:    0,
 		expandedIndex:    -1,
+		expandedFolders:  make(map[*model.Folder]bool),
 		sidebarScroll:    0,
 		collectionInput:  collectionInput,
 		requestNameInput: requestNameInput,
@@ -179,7 +207,7 @@ func NewModel(storagePath string) Model {
 		streamActive:     false,
 		streamInput:      streamInput,
 		commandPaletteInput: commandPaletteInput,
-		commandPaletteItems: make([]string, 0),
+		commandPaletteItems: make([]paletteEntry, 0),
 		commandPaletteIndex: 0,
 		assertionResults:    make([]model.AssertionResult, 0),
 		history:            make([]*model.HistoryEntry, 0),
@@ -189,7 +217,8 @@ func NewModel(storagePath string) Model {
 }
 
 func (m *Model) Init() tea.Cmd {
-	return command.Load(m.storage)
+	m.storeFingerprint = command.Fingerprint(m.storage, m.globalStorage)
+	return tea.Batch(command.Load(m.storage, m.globalStorage), command.Watch(m.storage, m.globalStorage))
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -200,23 +229,37 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updateDimensions()
 		return m, nil
 
+	case command.StoreCheckedMsg:
+		watch := command.Watch(m.storage, m.globalStorage)
+		if msg.Fingerprint != m.storeFingerprint {
+			m.storeFingerprint = msg.Fingerprint
+			return m, tea.Batch(command.Reload(m.storage, m.globalStorage), watch)
+		}
+		return m, watch
+
 	case command.CollectionsLoadedMsg:
+		if msg.Reloaded {
+			m.applyReload(msg)
+			return m, nil
+		}
 		m.collections = msg.Collections
+		m.history = msg.History
+		m.environments = msg.Environments
+		m.globals = msg.Globals
+		m.globalCollections = msg.Global
+		m.expandedFolders = make(map[*model.Folder]bool)
 		if len(m.collections) > 0 {
 			m.expandedIndex = 0
 		}
 		return m, nil
 
 	case command.RequestExecutedMsg:
+		if msg.Runtime != nil {
+			m.runtimeVars = msg.Runtime
+		}
 		if msg.Error != "" {
-			m.metricsCollector.Record(metrics.RequestMetric{
-				Timestamp:  time.Now(),
-				Duration:   0,
-				StatusCode: 0,
-				Success:    false,
-				Protocol:   "HTTP",
-			})
-			m.addHistoryEntry()
+			m.recordMetric(msg)
+			m.addHistoryEntry(nil, msg.Error, msg.URL)
 			return m, notification.ShowCmd("Request failed: " + msg.Error)
 		}
 
@@ -225,17 +268,16 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.mode = viewResponse
 
 		if msg.Response != nil {
-			isSuccess := msg.Response.StatusCode >= 200 && msg.Response.StatusCode < 300
-			m.metricsCollector.Record(metrics.RequestMetric{
-				Timestamp:  msg.Response.Timestamp,
-				Duration:   msg.Response.Duration,
-				StatusCode: msg.Response.StatusCode,
-				Success:    isSuccess,
-				Protocol:   "HTTP",
-			})
+			m.recordMetric(msg)
 		}
 
-		m.addHistoryEntry()
+		m.addHistoryEntry(msg.Response, "", msg.URL)
+		if msg.Warning != "" {
+			return m, notification.ShowCmd("Warning: " + msg.Warning)
+		}
+		if msg.Attempts > 1 {
+			return m, notification.ShowCmd(fmt.Sprintf("Polling finished after %d attempts", msg.Attempts))
+		}
 		return m, nil
 
 	case command.StreamConnectedMsg:
@@ -319,12 +361,18 @@ func (m *Model) View() string {
 	}
 
 	statusMode := m.statusMode()
+	if m.activeEnv != nil {
+		statusMode += " · " + m.activeEnv.Name
+	}
 	statusBar := render.StatusBar(m.width, statusMode)
+	if m.conflict != nil {
+		statusBar = render.ConflictBar(m.width, m.conflict.col.Name)
+	}
 	contentHeight := m.height - 2
 
 	var sidebarView string
 	if m.sidebarVisible {
-		sidebarView = render.Sidebar(sidebarWidth, contentHeight, m.mode == viewSidebar, m.collections, m.selectedIndex, m.expandedIndex, m.history, m.historyExpanded)
+		sidebarView = render.Sidebar(sidebarWidth, contentHeight, m.mode == viewSidebar, m.collections, m.selectedIndex, m.expandedIndex, m.expandedFolders, m.globalCollections, m.history, m.historyExpanded)
 	}
 	
 	var mainView string
@@ -346,6 +394,9 @@ func (m *Model) View() string {
 			AvgDuration:    stats.AverageDuration.String(),
 			MinDuration:    stats.MinDuration.String(),
 			MaxDuration:    stats.MaxDuration.String(),
+			P50Duration:    stats.P50Duration.String(),
+			P95Duration:    stats.P95Duration.String(),
+			P99Duration:    stats.P99Duration.String(),
 			Sparkline:      render.Sparkline(durations, mainWidth-20),
 			SuccessRateBar: render.SuccessRateBar(stats.SuccessCount, stats.TotalRequests, mainWidth-20),
 		}
@@ -380,7 +431,7 @@ func (m *Model) View() string {
 	}
 
 	if m.mode == viewCommandPalette {
-		mainView = render.CommandPalette(mainWidth, contentHeight, m.commandPaletteInput, m.commandPaletteItems, m.commandPaletteIndex)
+		mainView = render.CommandPalette(mainWidth, contentHeight, m.commandPaletteInput, m.paletteRenderItems(), m.commandPaletteIndex)
 	}
 	
 	if m.mode != viewResponse && m.mode != viewDashboard && m.mode != viewStream && m.mode != viewCommandPalette {
@@ -396,6 +447,7 @@ func (m *Model) View() string {
 			SelectedHeader:   m.selectedHeader,
 			FileKeys:         m.fileKeys,
 			SelectedFile:     m.selectedFile,
+			Variables:        m.activeVariables(),
 		}
 		mainView = render.Panel(mainWidth, contentHeight, m.mode == viewPanel, m.headers, panelInputs)
 	}
@@ -412,6 +464,11 @@ func (m *Model) View() string {
 		baseView += modal.Request(m.requestNameInput, m.collections, m.expandedIndex)
 	}
 
+	if m.collectionSettings.visible {
+		settings := m.collectionSettings
+		baseView += modal.CollectionSettings(settings.target.Name, settingLabels, settings.inputs, settings.focus)
+	}
+
 	return baseView
 }
 
@@ -444,6 +501,10 @@ func (m *Model) updateDimensions() {
 }
 
 func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.conflict != nil {
+		return m.handleConflictInput(msg)
+	}
+
 	if m.showCreateCollection {
 		return m.handleCreateCollectionInput(msg)
 	}
@@ -452,6 +513,10 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleSaveRequestInput(msg)
 	}
 
+	if m.collectionSettings.visible {
+		return m.handleCollectionSettingsInput(msg)
+	}
+
 	if m.mode == viewCommandPalette {
 		return m.handleCommandPaletteInput(msg)
 	}
@@ -474,7 +539,7 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m.handleFileDelete()
 		}
 
-		if key == "ctrl+c" || key == "tab" || key == "shift+tab" || key == "esc" || key == "ctrl+r" || key == "ctrl+s" || key == "ctrl+d" {
+		if key == "ctrl+c" || key == "tab" || key == "shift+tab" || key == "esc" || key == "ctrl+r" || key == "ctrl+s" || key == "ctrl+d" || key == "ctrl+o" {
 			return m.handleGlobalKeys(msg)
 		}
 
@@ -609,6 +674,10 @@ func (m *Model) handleGlobalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.requestNameInput.Focus()
 		return m, nil
 
+	case "ctrl+o":
+		m.prevKey = ""
+		return m.openCollectionSettings()
+
 	case "ctrl+q":
 		m.prevKey = ""
 		if m.streamActive && m.streamClient != nil {
@@ -641,7 +710,7 @@ func (m *Model) handleGlobalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleGoLast moves sidebar selection to the last visible item (vim G).
 func (m *Model) handleGoLast() *Model {
-	total := helper.TotalSidebarItems(m.collections, m.expandedIndex, m.history, m.historyExpanded)
+	total := helper.TotalSidebarItems(m.collections, m.expandedIndex, m.expandedFolders, m.history, m.historyExpanded)
 	if total > 0 {
 		m.selectedIndex = total - 1
 	}
@@ -705,7 +774,7 @@ func (m *Model) handleTabNavigation() *Model {
 
 func (m *Model) handleDownNavigation() *Model {
 	if m.mode == viewSidebar {
-		totalItems := helper.TotalSidebarItems(m.collections, m.expandedIndex, m.history, m.historyExpanded)
+		totalItems := helper.TotalSidebarItems(m.collections, m.expandedIndex, m.expandedFolders, m.history, m.historyExpanded)
 		if m.selectedIndex < totalItems-1 {
 			m.selectedIndex++
 		}
@@ -918,34 +987,27 @@ func (m *Model) handleFileDeleteWithCmd(cmd tea.Cmd) (*Model, tea.Cmd) {
 
 func (m *Model) handleSidebarSelection() {
 	currentIdx := 0
-	for colIdx, col := range m.collections {
-		if col == nil {
+	for _, row := range helper.CollectionRows(m.collections, m.expandedIndex, m.expandedFolders) {
+		if currentIdx != m.selectedIndex {
+			currentIdx++
 			continue
 		}
-		if currentIdx == m.selectedIndex {
-			if m.expandedIndex == colIdx {
-				m.expandedIndex = -1
-			}
-			if m.expandedIndex != colIdx {
-				m.expandedIndex = colIdx
-			}
+		if row.Request != nil {
+			m.loadRequest(row.Request)
+			m.mode = viewPanel
 			return
 		}
-		currentIdx++
-
-		if m.expandedIndex == colIdx {
-			for _, req := range col.Requests {
-				if req == nil {
-					continue
-				}
-				if currentIdx == m.selectedIndex {
-					m.loadRequest(req)
-					m.mode = viewPanel
-					return
-				}
-				currentIdx++
-			}
+		if row.Folder != nil {
+			m.expandedFolders[row.Folder] = !m.expandedFolders[row.Folder]
+			return
+		}
+		if m.expandedIndex == row.CollectionIndex {
+			m.expandedIndex = -1
 		}
+		if m.expandedIndex != row.CollectionIndex {
+			m.expandedIndex = row.CollectionIndex
+		}
+		return
 	}
 
 	if currentIdx == m.selectedIndex {
@@ -1010,9 +1072,9 @@ func (m *Model) executeCurrentRequest() tea.Cmd {
 		if m.streamClient != nil {
 			m.streamClient.Close()
 		}
-		wsClient := protocol2.NewWebSocketClient(url)
+		wsClient := protocol2.NewWebSocketClient(http.ReplaceEnvVars(url, m.activeEnvironment()))
 		if setHeaders, ok := wsClient.(interface{ SetHeaders(map[string]string) }); ok && len(m.headers) > 0 {
-			setHeaders.SetHeaders(m.headers)
+			setHeaders.SetHeaders(http.ReplaceEnvVarsInMap(m.headers, m.activeEnvironment()))
 		}
 		m.streamClient = wsClient
 		m.streamMessages = make([]model.StreamMessage, 0)
@@ -1025,7 +1087,7 @@ func (m *Model) executeCurrentRequest() tea.Cmd {
 		if m.streamClient != nil {
 			m.streamClient.Close()
 		}
-		m.streamClient = protocol2.NewGRPCClient(url)
+		m.streamClient = protocol2.NewGRPCClient(http.ReplaceEnvVars(url, m.activeEnvironment()))
 		m.streamMessages = make([]model.StreamMessage, 0)
 		m.mode = viewStream
 		m.addHistoryEntryWithProtocol("GRPC")
@@ -1051,8 +1113,15 @@ func (m *Model) executeCurrentRequest() tea.Cmd {
 	}
 
 	if m.currentRequest != nil {
+		req.ID = m.currentRequest.ID
+		req.Name = m.currentRequest.Name
 		req.Assertions = m.currentRequest.Assertions
 		req.Extractors = m.currentRequest.Extractors
+		req.PreRequestScript = m.currentRequest.PreRequestScript
+		req.PostResponseScript = m.currentRequest.PostResponseScript
+		req.Variables = m.currentRequest.Variables
+		req.Poll = m.currentRequest.Poll
+		req.Retry = m.currentRequest.Retry
 		if len(m.currentRequest.Query) > 0 {
 			req.Query = m.currentRequest.Query
 		}
@@ -1061,11 +1130,63 @@ func (m *Model) executeCurrentRequest() tea.Cmd {
 		}
 	}
 
-	if !util.ValidateURL(req.URL) {
+	col := m.currentCollection()
+	fullURL := req.URL
+	if col != nil && col.Defaults != nil {
+		fullURL = model.JoinBaseURL(col.Defaults.BaseURL, fullURL)
+	}
+	if !util.ValidateURL(util.RenderTemplate(fullURL, m.activeVariables())) {
 		return notification.ShowCmd("Invalid URL")
 	}
 
-	return command.Execute(m.httpClient, req, m.activeEnv)
+	return command.Execute(m.httpClient, col, col.FolderPath(m.currentRequest), req, m.variableScope())
+}
+
+// variableScope layers globals, the current collection, the active environment and the values
+// extracted earlier in this session. The runtime layer is copied so commands never share it.
+func (m *Model) variableScope() *model.Scope {
+	var collectionVars, envVars map[string]string
+	if col := m.currentCollection(); col != nil {
+		collectionVars = col.Variables
+	}
+	if m.activeEnv != nil {
+		envVars = m.activeEnv.Variables
+	}
+	scope := model.NewScope(m.globals, collectionVars, envVars)
+	scope.Runtime = model.CloneVariables(m.runtimeVars)
+	return scope
+}
+
+// activeVariables returns the variables placeholders are resolved against, for highlighting undefined ones.
+func (m *Model) activeVariables() map[string]string {
+	var overrides map[string]string
+	if m.currentRequest != nil {
+		overrides = m.currentRequest.Variables
+	}
+	return m.variableScope().Resolve(overrides)
+}
+
+// activeEnvironment wraps activeVariables for substitution in WebSocket and gRPC inputs.
+func (m *Model) activeEnvironment() *model.Environment {
+	return &model.Environment{Variables: m.activeVariables()}
+}
+
+// currentCollection returns the collection that owns the loaded request, so its hooks run too.
+func (m *Model) currentCollection() *model.Collection {
+	if m.currentRequest == nil {
+		return nil
+	}
+	for _, col := range m.collections {
+		if col == nil {
+			continue
+		}
+		for _, req := range col.AllRequests() {
+			if req == m.currentRequest {
+				return col
+			}
+		}
+	}
+	return nil
 }
 
 func (m *Model) handleStreamInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -1084,7 +1205,7 @@ func (m *Model) handleStreamInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		message := m.streamInput.Value()
 		if message != "" {
 			m.streamInput.SetValue("")
-			return m, command.SendStreamMessage(m.streamClient, message)
+			return m, command.SendStreamMessage(m.streamClient, http.ReplaceEnvVars(message, m.activeEnvironment()))
 		}
 		return m, nil
 	}
@@ -1146,7 +1267,7 @@ func (m *Model) handleMouseEvent(msg tea.MouseMsg) (*Model, tea.Cmd) {
 		m.mode = viewSidebar
 		m.unfocusAllInputs()
 		itemIdx := msg.Y - 3
-		if itemIdx >= 0 && itemIdx < helper.TotalSidebarItems(m.collections, m.expandedIndex, m.history, m.historyExpanded) {
+		if itemIdx >= 0 && itemIdx < helper.TotalSidebarItems(m.collections, m.expandedIndex, m.expandedFolders, m.history, m.historyExpanded) {
 			oldIndex := m.selectedIndex
 			m.selectedIndex = itemIdx
 			if oldIndex == itemIdx {
@@ -1372,10 +1493,13 @@ func (m *Model) createCollection(name string) (*Model, tea.Cmd) {
 		Requests: make([]*model.Request, 0),
 	}
 
-	if err := m.storage.SaveCollection(col); err != nil {
+	if _, err := m.saveCollection(col, func(*model.Collection) {}); err != nil {
 		m.showCreateCollection = false
 		m.collectionInput.SetValue("")
 		m.collectionInput.Blur()
+		if errors.Is(err, storage.ErrConflict) {
+			return m, nil
+		}
 		return m, notification.ShowCmd("Failed to create collection")
 	}
 
@@ -1419,6 +1543,16 @@ func (m *Model) saveRequestToCollection(name string) (*Model, tea.Cmd) {
 	if m.currentRequest != nil {
 		req.Assertions = m.currentRequest.Assertions
 		req.Extractors = m.currentRequest.Extractors
+		req.PreRequestScript = m.currentRequest.PreRequestScript
+		req.PostResponseScript = m.currentRequest.PostResponseScript
+		req.Variables = m.currentRequest.Variables
+		req.Sequential = m.currentRequest.Sequential
+		req.DependsOn = m.currentRequest.DependsOn
+		req.RunIf = m.currentRequest.RunIf
+		req.SkipIf = m.currentRequest.SkipIf
+		req.Poll = m.currentRequest.Poll
+		req.Retry = m.currentRequest.Retry
+		req.Stage = m.currentRequest.Stage
 	}
 
 	targetColIdx := 0
@@ -1427,18 +1561,26 @@ func (m *Model) saveRequestToCollection(name string) (*Model, tea.Cmd) {
 	}
 
 	targetCol := m.collections[targetColIdx]
-	targetCol.Requests = append(targetCol.Requests, req)
-
-	if err := m.storage.SaveCollection(targetCol); err != nil {
+	savedCol, err := m.saveCollection(targetCol, func(col *model.Collection) {
+		col.Requests = append(col.Requests, req)
+	})
+	if err != nil {
 		m.showSaveRequest = false
 		m.requestNameInput.SetValue("")
 		m.requestNameInput.Blur()
+		if errors.Is(err, storage.ErrLocked) {
+			return m, notification.ShowCmd("Failed to save request: " + targetCol.Name + " is locked by another raco process")
+		}
+		if errors.Is(err, storage.ErrConflict) {
+			return m, nil
+		}
 		return m, notification.ShowCmd("Failed to save request")
 	}
 
-	reloadedCol, err := m.storage.LoadCollection(targetCol.ID)
+	store := m.storeFor(savedCol)
+	reloadedCol, err := store.LoadCollection(savedCol.ID)
 	if err == nil {
-		m.collections[targetColIdx] = reloadedCol
+		m.replaceCollection(savedCol, reloadedCol)
 	}
 
 	m.showSaveRequest = false
@@ -1447,6 +1589,15 @@ func (m *Model) saveRequestToCollection(name string) (*Model, tea.Cmd) {
 	return m, notification.ShowCmd("Request saved to " + targetCol.Name)
 }
 
+// storeFor returns the store col was loaded from: the global store for global collections in a
+// project workspace, otherwise the workspace store.
+func (m *Model) storeFor(col *model.Collection) *storage.Storage {
+	if m.globalStorage != nil && m.globalCollections[col] {
+		return m.globalStorage
+	}
+	return m.storage
+}
+
 func convertStreamMessages(msgs []model.StreamMessage) []render.StreamMessage {
 	result := make([]render.StreamMessage, len(msgs))
 	for i, msg := range msgs {
@@ -1461,22 +1612,143 @@ func convertStreamMessages(msgs []model.StreamMessage) []render.StreamMessage {
 }
 
 func (m *Model) buildCommandPaletteItems() {
-	m.commandPaletteItems = make([]string, 0)
+	m.commandPaletteItems = m.paletteEntries()
+}
 
+// paletteEntry is one command palette item: a saved request, an environment or, when history is
+// set, a past request. field, context and highlights show where a search matched it.
+type paletteEntry struct {
+	label       string
+	request     *model.Request
+	environment *model.Environment
+	history     *model.HistoryEntry
+	field       string
+	context     string
+	highlights  []search.Span
+}
+
+// paletteEntries lists the requests of every collection, the environments, then the history,
+// newest first.
+func (m *Model) paletteEntries() []paletteEntry {
+	entries := make([]paletteEntry, 0, len(m.history)+len(m.environments))
 	for _, col := range m.collections {
 		if col == nil {
 			continue
 		}
+		for _, req := range col.AllRequests() {
+			entries = append(entries, paletteEntry{label: paletteItem(col, req), request: req})
+		}
+	}
+	for _, env := range m.environments {
+		entries = append(entries, paletteEntry{label: environmentPaletteItem(env, m.activeEnv), environment: env})
+	}
+	for i := len(m.history) - 1; i >= 0; i-- {
+		entries = append(entries, paletteEntry{label: historyPaletteItem(m.history[i]), history: m.history[i]})
+	}
+	return entries
+}
 
-		for _, req := range col.Requests {
-			if req == nil {
-				continue
-			}
+// searchPalette ranks the palette entries against query with the same search as raco search,
+// keeping the best hit of each as its context. A query that does not parse yet, such as a
+// filter still being typed, matches nothing.
+func (m *Model) searchPalette(query string) []paletteEntry {
+	matched := make([]paletteEntry, 0)
+	q, err := search.Parse(query)
+	if err != nil {
+		return matched
+	}
 
-			item := col.Name + " → " + req.Name + " (" + req.Method + " " + req.URL + ")"
-			m.commandPaletteItems = append(m.commandPaletteItems, item)
+	byRequest := make(map[*model.Request]paletteEntry)
+	byEnvironment := make(map[*model.Environment]paletteEntry)
+	byHistory := make(map[*model.HistoryEntry]paletteEntry)
+	for _, entry := range m.paletteEntries() {
+		if entry.request != nil {
+			byRequest[entry.request] = entry
+		}
+		if entry.environment != nil {
+			byEnvironment[entry.environment] = entry
+		}
+		if entry.history != nil {
+			byHistory[entry.history] = entry
 		}
 	}
+
+	vars := make(map[string]string)
+	for k, v := range m.globals {
+		vars[k] = v
+	}
+	if m.activeEnv != nil {
+		for k, v := range m.activeEnv.Variables {
+			vars[k] = v
+		}
+	}
+
+	docs := search.Documents(m.collections, m.environments, m.history, vars)
+	for _, result := range search.Rank(docs, q) {
+		var entry paletteEntry
+		var ok bool
+		switch {
+		case result.Request != nil:
+			entry, ok = byRequest[result.Request]
+		case result.Environment != nil:
+			entry, ok = byEnvironment[result.Environment]
+		case result.History != nil:
+			entry, ok = byHistory[result.History]
+		}
+		if !ok {
+			continue
+		}
+		if len(result.Hits) > 0 {
+			hit := result.Hits[0]
+			entry.field, entry.context, entry.highlights = hit.Field, hit.Snippet, hit.Spans
+		}
+		matched = append(matched, entry)
+	}
+	return matched
+}
+
+// paletteRenderItems converts the palette entries for render.CommandPalette.
+func (m *Model) paletteRenderItems() []render.PaletteItem {
+	items := make([]render.PaletteItem, 0, len(m.commandPaletteItems))
+	for _, entry := range m.commandPaletteItems {
+		item := render.PaletteItem{Label: entry.label, Field: entry.field, Context: entry.context}
+		for _, span := range entry.highlights {
+			item.Highlights = append(item.Highlights, [2]int{span.Start, span.End})
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// paletteItem labels req with its collection and folder path, e.g. "api → users/admin/list (GET /admin)".
+func paletteItem(col *model.Collection, req *model.Request) string {
+	name := req.Name
+	folders := col.FolderPath(req)
+	for i := len(folders) - 1; i >= 0; i-- {
+		name = folders[i].Name + "/" + name
+	}
+	return col.Name + " → " + name + " (" + req.Method + " " + req.URL + ")"
+}
+
+// environmentPaletteItem labels an environment, e.g. "env → staging (12 variables, active)".
+func environmentPaletteItem(env *model.Environment, active *model.Environment) string {
+	label := fmt.Sprintf("env → %s (%d variables", env.Name, len(env.Variables)+len(env.Secrets))
+	if active != nil && active.Name == env.Name {
+		label += ", active"
+	}
+	return label + ")"
+}
+
+// historyPaletteItem labels a history entry, e.g. "history → GET /users 200 (2026-10-19 14:03)".
+func historyPaletteItem(entry *model.HistoryEntry) string {
+	label := "history → " + entry.Method + " " + entry.URL
+	if entry.StatusCode > 0 {
+		label += " " + strconv.Itoa(entry.StatusCode)
+	}
+	if entry.StatusCode == 0 && entry.Error != "" {
+		label += " error"
+	}
+	return label + " (" + entry.Timestamp.Local().Format("2006-01-02 15:04") + ")"
 }
 
 func (m *Model) handleCommandPaletteInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -1489,16 +1761,17 @@ func (m *Model) handleCommandPaletteInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 
 	if msg.String() == "enter" {
+		var cmd tea.Cmd
 		if len(m.commandPaletteItems) > 0 {
 			if m.commandPaletteIndex >= 0 && m.commandPaletteIndex < len(m.commandPaletteItems) {
-				m.selectCommandPaletteItem(m.commandPaletteIndex)
+				cmd = m.selectCommandPaletteItem(m.commandPaletteIndex)
 			}
 		}
 		m.mode = viewPanel
 		m.commandPaletteInput.SetValue("")
 		m.commandPaletteInput.Blur()
 		m.commandPaletteIndex = 0
-		return m, nil
+		return m, cmd
 	}
 
 	if msg.String() == "down" || msg.String() == "j" {
@@ -1519,29 +1792,11 @@ func (m *Model) handleCommandPaletteInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	m.commandPaletteInput, cmd = m.commandPaletteInput.Update(msg)
 
 	query := m.commandPaletteInput.Value()
-	if query != "" {
-		allItems := make([]string, 0)
-		for _, col := range m.collections {
-			if col == nil {
-				continue
-			}
-			for _, req := range col.Requests {
-				if req == nil {
-					continue
-				}
-				item := col.Name + " → " + req.Name + " (" + req.Method + " " + req.URL + ")"
-				allItems = append(allItems, item)
-			}
-		}
-
-		matches := util.FuzzyMatch(query, allItems)
-		m.commandPaletteItems = make([]string, 0)
-		for _, match := range matches {
-			m.commandPaletteItems = append(m.commandPaletteItems, match.Text)
-		}
+	if strings.TrimSpace(query) != "" {
+		m.commandPaletteItems = m.searchPalette(query)
 		m.commandPaletteIndex = 0
 	}
-	if query == "" {
+	if strings.TrimSpace(query) == "" {
 		m.buildCommandPaletteItems()
 		m.commandPaletteIndex = 0
 	}
@@ -1549,34 +1804,71 @@ func (m *Model) handleCommandPaletteInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *Model) selectCommandPaletteItem(index int) {
+// selectCommandPaletteItem loads the request or history entry at index, or makes the
+// environment there the active one.
+func (m *Model) selectCommandPaletteItem(index int) tea.Cmd {
 	if index < 0 || index >= len(m.commandPaletteItems) {
-		return
+		return nil
 	}
 
-	selectedIdx := 0
-	for _, col := range m.collections {
-		if col == nil {
-			continue
-		}
-		for _, req := range col.Requests {
-			if req == nil {
-				continue
-			}
-			if selectedIdx == index {
-				m.loadRequest(req)
-				return
-			}
-			selectedIdx++
+	entry := m.commandPaletteItems[index]
+	if entry.request != nil {
+		m.loadRequest(entry.request)
+	}
+	if entry.history != nil {
+		m.loadHistoryEntry(entry.history)
+	}
+	if entry.environment != nil {
+		env, _, err := m.storage.ResolveEnvironment(entry.environment.Name)
+		if err != nil {
+			return notification.ShowCmd("Environment " + entry.environment.Name + ": " + err.Error())
 		}
+		m.activeEnv = env
+		return notification.ShowCmd("Environment " + env.Name + " active")
+	}
+	return nil
+}
+
+// recordMetric adds the outcome of an executed request to the dashboard and to the workspace
+// metrics read by raco stats. A failed write is ignored, like one of the history.
+func (m *Model) recordMetric(msg command.RequestExecutedMsg) {
+	rawURL := msg.URL
+	if rawURL == "" {
+		rawURL = m.urlInput.Value()
+	}
+
+	metric := metrics.NewMetric("HTTP", m.methodInput.Value(), rawURL, msg.Response, msg.Error)
+	metric.Source = "tui"
+	metrics.CountAssertions(&metric, msg.AssertionResults)
+	if m.currentRequest != nil {
+		metric.RequestID = m.currentRequest.ID
+		metric.RequestName = m.currentRequest.Name
 	}
+	if col := m.currentCollection(); col != nil {
+		metric.Collection = col.ID
+	}
+
+	m.metricsCollector.Record(metric)
+	m.storage.RecordMetric(metric)
 }
 
-func (m *Model) addHistoryEntry() {
-	m.addHistoryEntryWithProtocol("")
+// addHistoryEntry records the HTTP request in the editor with its response or error. url is the
+// URL it was sent to, with variables resolved; the URL as typed is kept when it is empty.
+func (m *Model) addHistoryEntry(resp *model.Response, errMsg string, url string) {
+	entry := m.newHistoryEntry("")
+	if url != "" {
+		entry.URL = url
+	}
+	entry.SetResponse(resp)
+	entry.Error = errMsg
+	m.appendHistory(entry)
 }
 
 func (m *Model) addHistoryEntryWithProtocol(protocol string) {
+	m.appendHistory(m.newHistoryEntry(protocol))
+}
+
+func (m *Model) newHistoryEntry(protocol string) *model.HistoryEntry {
 	method := m.methodInput.Value()
 	url := m.urlInput.Value()
 	body := m.bodyInput.Value()
@@ -1599,6 +1891,17 @@ func (m *Model) addHistoryEntryWithProtocol(protocol string) {
 	}
 
 	entry := model.NewHistoryEntry(method, url, headersCopy, body, proto, filesCopy)
+	entry.Source = "tui"
+	if m.activeEnv != nil {
+		entry.Environment = m.activeEnv.Name
+	}
+	return entry
+}
+
+// appendHistory adds entry to the sidebar and to the workspace history file. A failed write is
+// ignored: the entry is still shown for this session.
+func (m *Model) appendHistory(entry *model.HistoryEntry) {
+	m.storage.AppendHistory(entry)
 	m.history = append(m.history, entry)
 
 	maxHistory := 100
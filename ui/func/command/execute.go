@@ -3,62 +3,102 @@ package command
 import (
 	"raco/http"
 	"raco/model"
+	"raco/script"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-func copyAndReplaceHeaders(headers map[string]string, env *model.Environment) map[string]string {
-	if headers == nil {
-		return nil
-	}
-	out := make(map[string]string, len(headers))
-	for k, v := range headers {
-		out[k] = http.ReplaceEnvVars(v, env)
-	}
-	return out
-}
-
 type RequestExecutedMsg struct {
 	Response         *model.Response
 	Error            string
+	Warning          string
 	AssertionResults []model.AssertionResult
+	// Attempts is how often a polling request was sent; zero without polling.
+	Attempts int
+	// Runtime is the run-scoped variable layer after extractors and scripts ran.
+	Runtime map[string]string
+	// URL is the resolved URL the request was sent to; empty when it failed before sending.
+	URL string
 }
 
-func Execute(client *http.Client, req *model.Request, env *model.Environment) tea.Cmd {
+// Execute runs req with variables resolved from scope. folders are the folders containing req,
+// outermost first; their settings and then col's defaults apply to it. scope must be owned by
+// the command; the updated runtime layer is handed back in RequestExecutedMsg.Runtime.
+func Execute(client *http.Client, col *model.Collection, folders []*model.Folder, req *model.Request, scope *model.Scope) tea.Cmd {
 	return func() tea.Msg {
 		if req == nil {
 			return RequestExecutedMsg{Response: nil}
 		}
 
-		processedReq := *req
-		processedReq.URL = http.ReplaceEnvVars(req.URL, env)
-		processedReq.Body = http.ReplaceEnvVars(req.Body, env)
-		processedReq.Headers = copyAndReplaceHeaders(req.Headers, env)
-		if len(req.Query) > 0 {
-			processedReq.Query = http.ReplaceEnvVarsInMap(req.Query, env)
+		prepared := col.ApplyDefaults(model.ApplyFolders(folders, req))
+		vars := scope.Resolve(prepared.Variables)
+		before := model.CloneVariables(vars)
+
+		results := make([]model.AssertionResult, 0, len(req.Assertions))
+
+		pre, err := script.RunPreRequest(col, folders, prepared, vars)
+		if pre != nil {
+			results = append(results, pre.Tests...)
+		}
+		scope.Commit(before, vars)
+		if err != nil {
+			return RequestExecutedMsg{Response: nil, Error: err.Error(), AssertionResults: results, Runtime: scope.Snapshot()}
 		}
-		processedReq.Assertions = req.Assertions
-		processedReq.Extractors = req.Extractors
 
-		resp, err := client.Execute(&processedReq)
+		warning := ""
+		processedReq, err := http.ResolveRequest(prepared, &model.Environment{Variables: vars})
 		if err != nil {
-			return RequestExecutedMsg{Response: nil, Error: err.Error()}
+			if col == nil || !col.LenientVariables {
+				return RequestExecutedMsg{Response: nil, Error: err.Error(), AssertionResults: results, Runtime: scope.Snapshot()}
+			}
+			warning = err.Error()
+		}
+
+		var resp *model.Response
+		attempts := 0
+		if req.Poll == nil {
+			resp, err = client.Execute(processedReq)
+		}
+		if req.Poll != nil {
+			var polled *http.PollResult
+			polled, err = client.Poll(processedReq, req.Poll, req.Assertions)
+			attempts = polled.Attempts
+			resp = polled.Response
+			if err == nil && !polled.Met {
+				results = append(results, model.PollFailure(polled.Attempts, polled.TimedOut, polled.Failed))
+			}
+		}
+		if err != nil {
+			return RequestExecutedMsg{Response: nil, Error: err.Error(), AssertionResults: results, Attempts: attempts, Runtime: scope.Snapshot(), URL: processedReq.URL}
 		}
 
-		results := make([]model.AssertionResult, 0, len(req.Assertions))
 		for _, assertion := range req.Assertions {
 			result := model.ValidateAssertion(assertion, resp)
 			results = append(results, result)
 		}
 
-		if len(req.Extractors) > 0 {
-			if env != nil {
-				for _, extractor := range req.Extractors {
-					_ = model.ExtractValue(extractor, resp, env)
-				}
+		for _, extractor := range req.Extractors {
+			extracted := &model.Environment{Variables: make(map[string]string)}
+			if err := model.ExtractValue(extractor, resp, extracted); err == nil {
+				scope.Set(extractor.Target, extracted.Variables[extractor.Target])
+				vars[extractor.Target] = extracted.Variables[extractor.Target]
 			}
 		}
 
-		return RequestExecutedMsg{Response: resp, AssertionResults: results}
+		before = model.CloneVariables(vars)
+		post, err := script.RunPostResponse(col, folders, processedReq, resp, vars)
+		if post != nil {
+			results = append(results, post.Tests...)
+		}
+		scope.Commit(before, vars)
+		if err != nil {
+			results = append(results, model.AssertionResult{
+				Assertion: model.Assertion{Type: model.AssertScript, Field: "post-response"},
+				Passed:    false,
+				Message:   err.Error(),
+			})
+		}
+
+		return RequestExecutedMsg{Response: resp, Warning: warning, AssertionResults: results, Attempts: attempts, Runtime: scope.Snapshot(), URL: processedReq.URL}
 	}
 }
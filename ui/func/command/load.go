@@ -9,14 +9,78 @@ import (
 
 type CollectionsLoadedMsg struct {
 	Collections []*model.Collection
+	Globals     map[string]string
+	// Global marks the collections that come from the global store rather than the project.
+	Global map[*model.Collection]bool
+	// History holds the most recent entries of the persisted history, oldest first.
+	History []*model.HistoryEntry
+	// Environments are the environments of storage, without globals, as stored; the palette
+	// searches their variable names.
+	Environments []*model.Environment
+	// Reloaded is set when the load follows a change on disk; the view keeps its selection.
+	Reloaded bool
 }
 
-func Load(storage *storage.Storage) tea.Cmd {
+// maxLoadedHistory matches the number of entries the sidebar keeps.
+const maxLoadedHistory = 100
+
+// Load lists the collections of storage and, when it is set, of global after them. Globals of
+// the project store override those of the global store.
+func Load(storage *storage.Storage, global *storage.Storage) tea.Cmd {
 	return func() tea.Msg {
 		collections, err := storage.ListCollections()
 		if err != nil {
 			collections = []*model.Collection{}
 		}
-		return CollectionsLoadedMsg{Collections: collections}
+		globals := make(map[string]string)
+		fromGlobal := make(map[*model.Collection]bool)
+
+		if global != nil {
+			if globalCollections, err := global.ListCollections(); err == nil {
+				for _, col := range globalCollections {
+					fromGlobal[col] = true
+				}
+				collections = append(collections, globalCollections...)
+			}
+			if env, err := global.LoadGlobals(); err == nil {
+				for k, v := range env.Variables {
+					globals[k] = v
+				}
+			}
+		}
+		if env, err := storage.LoadGlobals(); err == nil {
+			for k, v := range env.Variables {
+				globals[k] = v
+			}
+		}
+		history, err := storage.LoadHistory()
+		if err != nil {
+			history = nil
+		}
+		if len(history) > maxLoadedHistory {
+			history = history[len(history)-maxLoadedHistory:]
+		}
+		environments := make([]*model.Environment, 0)
+		if names, err := storage.EnvironmentNames(); err == nil {
+			for _, name := range names {
+				if name == model.GlobalsEnvironment {
+					continue
+				}
+				if env, err := storage.LoadEnvironment(name); err == nil {
+					environments = append(environments, env)
+				}
+			}
+		}
+		return CollectionsLoadedMsg{Collections: collections, Globals: globals, Global: fromGlobal, History: history, Environments: environments}
+	}
+}
+
+// Reload is Load for a store that changed on disk while the TUI was running.
+func Reload(storage *storage.Storage, global *storage.Storage) tea.Cmd {
+	load := Load(storage, global)
+	return func() tea.Msg {
+		msg := load().(CollectionsLoadedMsg)
+		msg.Reloaded = true
+		return msg
 	}
 }
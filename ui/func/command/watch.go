@@ -0,0 +1,33 @@
+package command
+
+import (
+	"raco/storage"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// watchInterval is how often the stores are checked for changes made outside the TUI.
+const watchInterval = 2 * time.Second
+
+// StoreCheckedMsg carries the fingerprint of the stores after a Watch poll.
+type StoreCheckedMsg struct {
+	Fingerprint string
+}
+
+// Watch reports the fingerprint of storage and, when set, global after watchInterval. The
+// model compares it with the last one it saw and reloads on a difference.
+func Watch(storage *storage.Storage, global *storage.Storage) tea.Cmd {
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		return StoreCheckedMsg{Fingerprint: Fingerprint(storage, global)}
+	})
+}
+
+// Fingerprint combines the fingerprints of storage and, when set, global.
+func Fingerprint(storage *storage.Storage, global *storage.Storage) string {
+	fingerprint := storage.Fingerprint()
+	if global != nil {
+		fingerprint += global.Fingerprint()
+	}
+	return fingerprint
+}
@@ -0,0 +1,45 @@
+package helper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FormatPairs renders m as "key<sep>value; key<sep>value" with keys sorted, for editing a map in
+// a single-line input.
+func FormatPairs(m map[string]string, sep string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+sep+m[k])
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ParsePairs is the inverse of FormatPairs. Surrounding spaces are trimmed and empty entries are
+// skipped; it returns nil for an empty input.
+func ParsePairs(s, sep string) (map[string]string, error) {
+	var out map[string]string
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, sep)
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid entry %q (want key%svalue)", part, strings.TrimSpace(sep))
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[key] = strings.TrimSpace(value)
+	}
+	return out, nil
+}
@@ -2,26 +2,55 @@ package helper
 
 import "raco/model"
 
-func TotalSidebarItems(collections []*model.Collection, expandedIndex int, history []*model.HistoryEntry, historyExpanded bool) int {
-	if collections == nil {
-		collections = []*model.Collection{}
-	}
-	if history == nil {
-		history = []*model.HistoryEntry{}
-	}
+// SidebarRow is one visible line of the collection tree. Exactly one of Folder and Request is
+// set for rows inside a collection; both are nil for the collection row itself.
+type SidebarRow struct {
+	CollectionIndex int
+	Folder          *model.Folder
+	Request         *model.Request
+	Depth           int
+}
 
-	total := 0
-	for _, col := range collections {
+// maxTreeDepth bounds how deep the sidebar follows nested folders.
+const maxTreeDepth = 32
+
+// CollectionRows lists the visible collection tree: every collection, and for the expanded one
+// its requests and folders in entry order, descending into the folders in expandedFolders.
+func CollectionRows(collections []*model.Collection, expandedIndex int, expandedFolders map[*model.Folder]bool) []SidebarRow {
+	rows := make([]SidebarRow, 0, len(collections))
+	for colIdx, col := range collections {
 		if col == nil {
 			continue
 		}
-		total++
-		if expandedIndex >= 0 && expandedIndex < len(collections) {
-			if collections[expandedIndex] != nil && collections[expandedIndex].ID == col.ID {
-				total += len(col.Requests)
-			}
+		rows = append(rows, SidebarRow{CollectionIndex: colIdx})
+		if colIdx == expandedIndex {
+			rows = appendTreeRows(rows, colIdx, col.Entries(), expandedFolders, 1)
+		}
+	}
+	return rows
+}
+
+func appendTreeRows(rows []SidebarRow, colIdx int, entries []model.Entry, expandedFolders map[*model.Folder]bool, depth int) []SidebarRow {
+	for _, entry := range entries {
+		if entry.Request != nil {
+			rows = append(rows, SidebarRow{CollectionIndex: colIdx, Request: entry.Request, Depth: depth})
+			continue
+		}
+		folder := entry.Folder
+		rows = append(rows, SidebarRow{CollectionIndex: colIdx, Folder: folder, Depth: depth})
+		if expandedFolders[folder] && depth < maxTreeDepth {
+			rows = appendTreeRows(rows, colIdx, folder.Entries(), expandedFolders, depth+1)
 		}
 	}
+	return rows
+}
+
+func TotalSidebarItems(collections []*model.Collection, expandedIndex int, expandedFolders map[*model.Folder]bool, history []*model.HistoryEntry, historyExpanded bool) int {
+	if history == nil {
+		history = []*model.HistoryEntry{}
+	}
+
+	total := len(CollectionRows(collections, expandedIndex, expandedFolders))
 
 	total++
 	if historyExpanded {
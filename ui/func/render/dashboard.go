@@ -44,6 +44,9 @@ type DashboardStats struct {
 	AvgDuration     string
 	MinDuration     string
 	MaxDuration     string
+	P50Duration     string
+	P95Duration     string
+	P99Duration     string
 	Sparkline       string
 	SuccessRateBar  string
 }
@@ -78,6 +81,13 @@ func Dashboard(width, height int, stats DashboardStats) string {
 	content.WriteString(dashboardValueStyle.Render(stats.MinDuration))
 	content.WriteString(dashboardLabelStyle.Render(" | Max: "))
 	content.WriteString(dashboardValueStyle.Render(stats.MaxDuration))
+	content.WriteString("\n")
+	content.WriteString(dashboardLabelStyle.Render("  p50: "))
+	content.WriteString(dashboardValueStyle.Render(stats.P50Duration))
+	content.WriteString(dashboardLabelStyle.Render(" | p95: "))
+	content.WriteString(dashboardValueStyle.Render(stats.P95Duration))
+	content.WriteString(dashboardLabelStyle.Render(" | p99: "))
+	content.WriteString(dashboardValueStyle.Render(stats.P99Duration))
 	content.WriteString("\n\n")
 
 	content.WriteString(dashboardLabelStyle.Render("Response Time Trend"))
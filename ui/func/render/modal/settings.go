@@ -0,0 +1,53 @@
+package modal
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// CollectionSettings renders the collection defaults form; labels and inputs are parallel and
+// focus is the index of the active input.
+func CollectionSettings(collectionName string, labels []string, inputs []textinput.Model, focus int) string {
+	modalStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("255")).
+		Padding(1, 2).
+		Width(70).
+		Background(lipgloss.Color("235"))
+
+	title := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("255")).
+		Bold(true).
+		Render("Collection Settings: " + collectionName)
+
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Width(16)
+	activeLabelStyle := labelStyle.Foreground(lipgloss.Color("255")).Bold(true)
+
+	rows := ""
+	for i, input := range inputs {
+		style := labelStyle
+		if i == focus {
+			style = activeLabelStyle
+		}
+		label := ""
+		if i < len(labels) {
+			label = labels[i]
+		}
+		rows += fmt.Sprintf("%s %s\n", style.Render(label), input.View())
+	}
+
+	hint := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("245")).
+		Render("Headers as Key: Value; query and variables as key=value, separated by ;")
+
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Italic(true).
+		Render("Tab/Shift+Tab: Field • Enter: Save • Esc: Cancel")
+
+	content := title + "\n\n" + rows + "\n" + hint + "\n\n" + help
+
+	return "\n" + modalStyle.Render(content)
+}
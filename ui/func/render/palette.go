@@ -28,9 +28,25 @@ var (
 				Foreground(lipgloss.Color("255")).
 				Bold(true).
 				PaddingLeft(2)
+
+	paletteContextStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("245"))
+
+	paletteMatchStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("220")).
+				Bold(true)
 )
 
-func CommandPalette(width, height int, input textinput.Model, items []string, selectedIndex int) string {
+// PaletteItem is one command palette row. Search results also carry the field that matched,
+// a one-line Context from it and the byte ranges of Context to highlight.
+type PaletteItem struct {
+	Label      string
+	Field      string
+	Context    string
+	Highlights [][2]int
+}
+
+func CommandPalette(width, height int, input textinput.Model, items []PaletteItem, selectedIndex int) string {
 	var content strings.Builder
 
 	content.WriteString(paletteTitleStyle.Render("Command Palette"))
@@ -50,16 +66,20 @@ func CommandPalette(width, height int, input textinput.Model, items []string, se
 
 	for i, item := range items {
 		if i == selectedIndex {
-			content.WriteString(paletteSelectedStyle.Render(fmt.Sprintf("▶ %s", item)))
+			content.WriteString(paletteSelectedStyle.Render(fmt.Sprintf("▶ %s", item.Label)))
 		}
 		if i != selectedIndex {
-			content.WriteString(paletteItemStyle.Render(fmt.Sprintf("  %s", item)))
+			content.WriteString(paletteItemStyle.Render(fmt.Sprintf("  %s", item.Label)))
 		}
 		content.WriteString("\n")
+		if item.Context != "" {
+			content.WriteString(paletteItemStyle.Render("    " + paletteContextStyle.Render(item.Field+": ") + highlightMatches(item.Context, item.Highlights)))
+			content.WriteString("\n")
+		}
 	}
 
 	content.WriteString("\n")
-	help := "↑/↓: Navigate • Enter: Select • Esc: Close"
+	help := "↑/↓: Navigate • Enter: Select • Esc: Close • Filters: method:POST host:api col:shop in:env"
 	content.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(help))
 
 	return paletteStyle.
@@ -67,3 +87,19 @@ func CommandPalette(width, height int, input textinput.Model, items []string, se
 		Height(height - 4).
 		Render(content.String())
 }
+
+// highlightMatches renders text in the context style with the byte ranges of highlights marked.
+func highlightMatches(text string, highlights [][2]int) string {
+	var b strings.Builder
+	last := 0
+	for _, h := range highlights {
+		if h[0] < last || h[1] > len(text) || h[0] >= h[1] {
+			continue
+		}
+		b.WriteString(paletteContextStyle.Render(text[last:h[0]]))
+		b.WriteString(paletteMatchStyle.Render(text[h[0]:h[1]]))
+		last = h[1]
+	}
+	b.WriteString(paletteContextStyle.Render(text[last:]))
+	return b.String()
+}
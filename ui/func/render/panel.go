@@ -5,18 +5,22 @@ import (
 	"strings"
 
 	"raco/ui/theme"
+	"raco/util"
 
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
 )
 
-// panelSelectedStyle highlights the current header or file row in the request panel.
+// panelSelectedStyle highlights the current header or file row in the request panel;
+// panelUndefinedStyle marks labels and rows that reference undefined variables.
 var (
 	panelSelectedStyle = lipgloss.NewStyle().
 				Foreground(theme.Text).
 				Background(theme.BgPanel).
 				PaddingLeft(1)
+	panelUndefinedStyle = lipgloss.NewStyle().
+				Foreground(theme.Warning)
 )
 
 // PanelInputs holds the bubbletea input models and selection state for the request builder.
@@ -32,6 +36,7 @@ type PanelInputs struct {
 	SelectedHeader   int
 	FileKeys         []string
 	SelectedFile     int
+	Variables        map[string]string
 }
 
 // Panel renders the main request builder: method, URL, headers list + add row, files list + add row, body.
@@ -52,6 +57,7 @@ func Panel(width, height int, isActive bool, headers map[string]string, inputs P
 	b.WriteString("\n\n")
 
 	b.WriteString(theme.Label().Render("URL"))
+	b.WriteString(undefinedHint(inputs.URLInput.Value(), inputs.Variables))
 	b.WriteString("\n")
 	b.WriteString(inputs.URLInput.View())
 	b.WriteString("\n\n")
@@ -67,10 +73,17 @@ func Panel(width, height int, isActive bool, headers map[string]string, inputs P
 	for i, key := range inputs.HeaderKeys {
 		value := headers[key]
 		line := fmt.Sprintf("  %s: %s", key, value)
+		undefined := len(util.UndefinedVariables(value, inputs.Variables)) > 0
+		if undefined {
+			line += " ⚠"
+		}
 		if i == inputs.SelectedHeader {
 			b.WriteString(panelSelectedStyle.Render("▸ "+line) + "\n")
 		}
-		if i != inputs.SelectedHeader {
+		if i != inputs.SelectedHeader && undefined {
+			b.WriteString(panelUndefinedStyle.PaddingLeft(2).Render(line) + "\n")
+		}
+		if i != inputs.SelectedHeader && !undefined {
 			b.WriteString(theme.Muted().PaddingLeft(2).Render(line) + "\n")
 		}
 	}
@@ -99,12 +112,22 @@ func Panel(width, height int, isActive bool, headers map[string]string, inputs P
 	b.WriteString("\n\n")
 
 	b.WriteString(theme.Label().Render("Body"))
+	b.WriteString(undefinedHint(inputs.BodyInput.Value(), inputs.Variables))
 	b.WriteString("\n")
 	b.WriteString(inputs.BodyInput.View())
 
 	return style.Render(b.String())
 }
 
+// undefinedHint renders " ⚠ undefined: a, b" for placeholders in text that vars cannot resolve.
+func undefinedHint(text string, vars map[string]string) string {
+	names := util.UndefinedVariables(text, vars)
+	if len(names) == 0 {
+		return ""
+	}
+	return panelUndefinedStyle.Render(" ⚠ unresolved: " + strings.Join(names, ", "))
+}
+
 // GetPanelHelp returns the one-line shortcut hint for the request panel (Tab, e, w, Ctrl+S/D/F/X).
 func GetPanelHelp() string {
 	return "Tab next  Shift+Tab prev  e send  w save  h/l method  Ctrl+S/D header  Ctrl+F/X file"
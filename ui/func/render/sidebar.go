@@ -3,13 +3,17 @@ package render
 import (
 	"fmt"
 	"raco/model"
+	"raco/ui/func/helper"
 	"raco/ui/theme"
+	"strings"
 )
 
-// Sidebar renders the left panel: collections (expandable), their requests, and history.
-// selectedIndex is the linear index over all visible items; expandedIndex is which collection is open.
-// Help text at the bottom reflects vim keys (j/k, gg/G, Enter, h/l, e, w).
-func Sidebar(width, height int, isActive bool, collections []*model.Collection, selectedIndex, expandedIndex int, history []*model.HistoryEntry, historyExpanded bool) string {
+// Sidebar renders the left panel: collections (expandable), their folders and requests as a tree,
+// and history. selectedIndex is the linear index over all visible items; expandedIndex is which
+// collection is open and expandedFolders which of its folders are. Collections in global come from
+// the home store while a project workspace is open and are marked as such.
+// Help text at the bottom reflects vim keys (j/k, gg/G, h/l, e, w).
+func Sidebar(width, height int, isActive bool, collections []*model.Collection, selectedIndex, expandedIndex int, expandedFolders map[*model.Folder]bool, global map[*model.Collection]bool, history []*model.HistoryEntry, historyExpanded bool) string {
 	if collections == nil {
 		collections = []*model.Collection{}
 	}
@@ -26,19 +30,42 @@ func Sidebar(width, height int, isActive bool, collections []*model.Collection,
 	}
 
 	currentIdx := 0
-	for colIdx, col := range collections {
-		if col == nil {
-			continue
-		}
+	for _, row := range helper.CollectionRows(collections, expandedIndex, expandedFolders) {
 		isSelected := currentIdx == selectedIndex && isActive
-		isExpanded := expandedIndex == colIdx
+		indent := strings.Repeat("  ", row.Depth)
 
-		icon := "›"
-		if isExpanded {
-			icon = "∨"
+		if row.Request != nil {
+			reqLine := fmt.Sprintf(" %s %s %s", indent, GetMethodIcon(row.Request.Method), row.Request.Name)
+			if isSelected {
+				content += theme.Selected().Render(reqLine)
+			}
+			if !isSelected {
+				content += theme.Muted().PaddingLeft(1).Render(reqLine)
+			}
+			content += "\n"
+			currentIdx++
+			continue
 		}
 
-		line := fmt.Sprintf(" %s %s (%d)", icon, col.Name, len(col.Requests))
+		var line string
+		if row.Folder != nil {
+			icon := "›"
+			if expandedFolders[row.Folder] {
+				icon = "∨"
+			}
+			line = fmt.Sprintf(" %s%s %s/ (%d)", indent, icon, row.Folder.Name, len(row.Folder.AllRequests()))
+		}
+		if row.Folder == nil {
+			col := collections[row.CollectionIndex]
+			icon := "›"
+			if expandedIndex == row.CollectionIndex {
+				icon = "∨"
+			}
+			line = fmt.Sprintf(" %s %s (%d)", icon, col.Name, len(col.AllRequests()))
+			if global[col] {
+				line += " · global"
+			}
+		}
 		if isSelected {
 			content += theme.Selected().Render(line)
 		}
@@ -47,21 +74,6 @@ func Sidebar(width, height int, isActive bool, collections []*model.Collection,
 		}
 		content += "\n"
 		currentIdx++
-
-		if isExpanded {
-			for _, req := range col.Requests {
-				reqSelected := currentIdx == selectedIndex && isActive
-				reqLine := fmt.Sprintf("   %s %s", GetMethodIcon(req.Method), req.Name)
-				if reqSelected {
-					content += theme.Selected().Render(reqLine)
-				}
-				if !reqSelected {
-					content += theme.Muted().PaddingLeft(1).Render(reqLine)
-				}
-				content += "\n"
-				currentIdx++
-			}
-		}
 	}
 
 	content += "\n" + theme.Title().Render("History") + "\n"
@@ -130,7 +142,7 @@ func GetMethodIcon(method string) string {
 // GetSidebarHelp returns one-line hint: vim keys when sidebar is focused, focus panel hint otherwise.
 func GetSidebarHelp(isActive bool) string {
 	if isActive {
-		return "j/k nav  gg/G top/bot  Enter open  h/l focus  e send  w save"
+		return "j/k nav  gg/G top/bot  Enter open/expand  h/l focus  e send  w save"
 	}
 	return "Tab or l → focus panel"
 }
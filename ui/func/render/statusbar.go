@@ -39,3 +39,28 @@ func StatusBar(width int, mode string) string {
 	content := lipgloss.JoinHorizontal(lipgloss.Top, left, " ", right)
 	return theme.StatusBar().Width(width).Render(content)
 }
+
+// ConflictBar replaces the status bar while a save is blocked because collection name changed on
+// disk, offering to reload it or to overwrite it with the unsaved version.
+func ConflictBar(width int, name string) string {
+	left := lipgloss.NewStyle().Foreground(theme.Error).Bold(true).Render(name + " changed on disk; not saved")
+
+	hints := []struct{ key, desc string }{
+		{"r", "reload"},
+		{"o", "overwrite"},
+	}
+
+	var rightParts []string
+	for _, h := range hints {
+		rightParts = append(rightParts, theme.KeyHint().Render(" "+h.key)+theme.Muted().Render(" "+h.desc))
+	}
+	rightStr := lipgloss.JoinHorizontal(lipgloss.Top, rightParts...)
+	w := width - lipgloss.Width(left) - 2
+	if w < 10 {
+		w = 10
+	}
+	right := lipgloss.NewStyle().Width(w).Align(lipgloss.Right).Render(rightStr)
+
+	content := lipgloss.JoinHorizontal(lipgloss.Top, left, " ", right)
+	return theme.StatusBar().Width(width).Render(content)
+}
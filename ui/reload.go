@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"errors"
+	"raco/model"
+	"raco/storage"
+	"raco/ui/func/command"
+	"raco/ui/func/helper"
+	"raco/ui/notification"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// applyReload takes collections that were reloaded after a change on disk, keeping the expanded
+// collection and folders and the loaded request where they still exist. The editor is left as
+// it is, so unsaved edits survive.
+func (m *Model) applyReload(msg command.CollectionsLoadedMsg) {
+	expandedID := ""
+	if m.expandedIndex >= 0 && m.expandedIndex < len(m.collections) {
+		expandedID = m.collections[m.expandedIndex].ID
+	}
+	expandedFolders := make(map[string]bool)
+	for folder, open := range m.expandedFolders {
+		if open {
+			expandedFolders[folder.ID] = true
+		}
+	}
+
+	m.collections = msg.Collections
+	m.globals = msg.Globals
+	m.globalCollections = msg.Global
+	m.history = msg.History
+	m.environments = msg.Environments
+	if m.activeEnv != nil {
+		if env, _, err := m.storage.ResolveEnvironment(m.activeEnv.Name); err == nil {
+			m.activeEnv = env
+		}
+	}
+
+	m.expandedIndex = -1
+	m.expandedFolders = make(map[*model.Folder]bool)
+	for i, col := range m.collections {
+		if col.ID == expandedID {
+			m.expandedIndex = i
+		}
+		walkFolders(col.Folders, func(folder *model.Folder) {
+			if expandedFolders[folder.ID] {
+				m.expandedFolders[folder] = true
+			}
+		})
+	}
+
+	if m.currentRequest != nil {
+		if req := m.findRequest(m.currentRequest.ID); req != nil {
+			m.currentRequest = req
+		}
+	}
+
+	total := helper.TotalSidebarItems(m.collections, m.expandedIndex, m.expandedFolders, m.history, m.historyExpanded)
+	if m.selectedIndex >= total {
+		m.selectedIndex = total - 1
+	}
+	if m.selectedIndex < 0 {
+		m.selectedIndex = 0
+	}
+}
+
+// findRequest returns the request with id in any loaded collection, or nil.
+func (m *Model) findRequest(id string) *model.Request {
+	if id == "" {
+		return nil
+	}
+	for _, col := range m.collections {
+		for _, req := range col.AllRequests() {
+			if req.ID == id {
+				return req
+			}
+		}
+	}
+	return nil
+}
+
+func walkFolders(folders []*model.Folder, fn func(*model.Folder)) {
+	for _, folder := range folders {
+		fn(folder)
+		walkFolders(folder.Folders, fn)
+	}
+}
+
+// saveConflict is a save that was refused because the stored copy of col changed since it was
+// loaded. col keeps the unsaved change until the user reloads or overwrites it.
+type saveConflict struct {
+	col   *model.Collection
+	store *storage.Storage
+}
+
+// saveCollection applies change to col and saves it. When the stored copy changed since col was
+// loaded, nothing is written: the conflict is kept for the status bar, which asks whether to
+// reload the collection or overwrite it, and storage.ErrConflict is returned.
+func (m *Model) saveCollection(col *model.Collection, change func(*model.Collection)) (*model.Collection, error) {
+	store := m.storeFor(col)
+	change(col)
+	err := store.SaveCollection(col)
+	if errors.Is(err, storage.ErrConflict) {
+		m.conflict = &saveConflict{col: col, store: store}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.storeFingerprint = command.Fingerprint(m.storage, m.globalStorage)
+	return col, nil
+}
+
+// handleConflictInput answers the status bar prompt of a pending save conflict: r drops the
+// unsaved change and reloads the stores, o writes the unsaved version over the stored copy.
+func (m *Model) handleConflictInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	conflict := m.conflict
+	switch msg.String() {
+	case "r":
+		m.conflict = nil
+		return m, tea.Batch(command.Reload(m.storage, m.globalStorage), notification.ShowCmd("Reloaded "+conflict.col.Name+"; the unsaved change was dropped"))
+	case "o":
+		m.conflict = nil
+		// An empty revision skips the check, so the unsaved version replaces the stored copy.
+		conflict.col.Revision = ""
+		if err := conflict.store.SaveCollection(conflict.col); err != nil {
+			return m, notification.ShowCmd("Failed to overwrite " + conflict.col.Name + ": " + err.Error())
+		}
+		m.storeFingerprint = command.Fingerprint(m.storage, m.globalStorage)
+		return m, tea.Batch(command.Reload(m.storage, m.globalStorage), notification.ShowCmd("Overwrote "+conflict.col.Name+" on disk"))
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// replaceCollection puts updated where old was, keeping it marked as global when old was.
+func (m *Model) replaceCollection(old *model.Collection, updated *model.Collection) {
+	for i, col := range m.collections {
+		if col == old {
+			m.collections[i] = updated
+		}
+	}
+	if m.globalCollections[old] {
+		delete(m.globalCollections, old)
+		m.globalCollections[updated] = true
+	}
+}
@@ -0,0 +1,197 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"raco/model"
+	"raco/storage"
+	"raco/ui/func/helper"
+	"raco/ui/notification"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Fields of the collection settings form, in display order.
+const (
+	settingBaseURL = iota
+	settingHeaders
+	settingQuery
+	settingTimeout
+	settingRetries
+	settingRetryDelay
+	settingVariables
+	settingCount
+)
+
+var settingLabels = []string{"Base URL", "Headers", "Query", "Timeout (s)", "Retries", "Retry delay (ms)", "Variables"}
+
+// collectionSettings is the state of the Ctrl+O form that edits a collection's defaults and
+// variables.
+type collectionSettings struct {
+	visible bool
+	target  *model.Collection
+	inputs  []textinput.Model
+	focus   int
+}
+
+// settingsTarget is the collection of the loaded request, else the expanded one, else the first.
+func (m *Model) settingsTarget() *model.Collection {
+	if col := m.currentCollection(); col != nil {
+		return col
+	}
+	if m.expandedIndex >= 0 && m.expandedIndex < len(m.collections) {
+		return m.collections[m.expandedIndex]
+	}
+	if len(m.collections) > 0 {
+		return m.collections[0]
+	}
+	return nil
+}
+
+func (m *Model) openCollectionSettings() (tea.Model, tea.Cmd) {
+	col := m.settingsTarget()
+	if col == nil {
+		return m, notification.ShowCmd("No collection available. Create one first (Ctrl+N)")
+	}
+
+	defaults := col.Defaults
+	if defaults == nil {
+		defaults = &model.Defaults{}
+	}
+
+	values := make([]string, settingCount)
+	values[settingBaseURL] = defaults.BaseURL
+	values[settingHeaders] = helper.FormatPairs(defaults.Headers, ": ")
+	values[settingQuery] = helper.FormatPairs(defaults.Query, "=")
+	if defaults.TimeoutSeconds > 0 {
+		values[settingTimeout] = strconv.Itoa(defaults.TimeoutSeconds)
+	}
+	if defaults.Retry != nil {
+		values[settingRetries] = strconv.Itoa(defaults.Retry.MaxRetries)
+		if defaults.Retry.DelayMs > 0 {
+			values[settingRetryDelay] = strconv.Itoa(defaults.Retry.DelayMs)
+		}
+	}
+	values[settingVariables] = helper.FormatPairs(col.Variables, "=")
+
+	placeholders := []string{"https://api.example.com/v1", "Accept: application/json; User-Agent: raco", "api_version=2", "30", "3 (empty: default, 0: off)", "1000", "tenant=acme"}
+
+	inputs := make([]textinput.Model, settingCount)
+	for i := range inputs {
+		input := textinput.New()
+		input.Placeholder = placeholders[i]
+		input.Width = 48
+		input.SetValue(values[i])
+		inputs[i] = input
+	}
+	inputs[0].Focus()
+
+	m.collectionSettings = collectionSettings{visible: true, target: col, inputs: inputs}
+	return m, nil
+}
+
+func (m *Model) closeCollectionSettings() {
+	m.collectionSettings = collectionSettings{}
+}
+
+func (m *Model) handleCollectionSettingsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := &m.collectionSettings
+
+	switch msg.String() {
+	case "esc":
+		m.closeCollectionSettings()
+		return m, nil
+
+	case "enter":
+		return m.saveCollectionSettings()
+
+	case "tab", "down":
+		s.inputs[s.focus].Blur()
+		s.focus = (s.focus + 1) % len(s.inputs)
+		s.inputs[s.focus].Focus()
+		return m, nil
+
+	case "shift+tab", "up":
+		s.inputs[s.focus].Blur()
+		s.focus = (s.focus - 1 + len(s.inputs)) % len(s.inputs)
+		s.inputs[s.focus].Focus()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	s.inputs[s.focus], cmd = s.inputs[s.focus].Update(msg)
+	return m, cmd
+}
+
+func (m *Model) saveCollectionSettings() (tea.Model, tea.Cmd) {
+	s := &m.collectionSettings
+	value := func(field int) string {
+		return strings.TrimSpace(s.inputs[field].Value())
+	}
+
+	defaults := &model.Defaults{BaseURL: value(settingBaseURL)}
+
+	var err error
+	if defaults.Headers, err = helper.ParsePairs(value(settingHeaders), ":"); err != nil {
+		return m, notification.ShowCmd("Headers: " + err.Error())
+	}
+	if defaults.Query, err = helper.ParsePairs(value(settingQuery), "="); err != nil {
+		return m, notification.ShowCmd("Query: " + err.Error())
+	}
+	variables, err := helper.ParsePairs(value(settingVariables), "=")
+	if err != nil {
+		return m, notification.ShowCmd("Variables: " + err.Error())
+	}
+
+	if defaults.TimeoutSeconds, err = parseSetting(value(settingTimeout)); err != nil {
+		return m, notification.ShowCmd("Timeout: " + err.Error())
+	}
+	if value(settingRetries) != "" {
+		retries, err := parseSetting(value(settingRetries))
+		if err != nil {
+			return m, notification.ShowCmd("Retries: " + err.Error())
+		}
+		delay, err := parseSetting(value(settingRetryDelay))
+		if err != nil {
+			return m, notification.ShowCmd("Retry delay: " + err.Error())
+		}
+		defaults.Retry = &model.RetryPolicy{MaxRetries: retries, DelayMs: delay}
+	}
+
+	if defaults.BaseURL == "" && defaults.Headers == nil && defaults.Query == nil && defaults.TimeoutSeconds == 0 && defaults.Retry == nil {
+		defaults = nil
+	}
+
+	col := s.target
+	previousDefaults, previousVariables := col.Defaults, col.Variables
+	_, err = m.saveCollection(col, func(c *model.Collection) {
+		c.Defaults = defaults
+		c.Variables = variables
+	})
+	if errors.Is(err, storage.ErrConflict) {
+		m.closeCollectionSettings()
+		return m, nil
+	}
+	if err != nil {
+		col.Defaults, col.Variables = previousDefaults, previousVariables
+		return m, notification.ShowCmd("Failed to save collection settings")
+	}
+
+	m.closeCollectionSettings()
+	return m, notification.ShowCmd("Settings saved for " + col.Name)
+}
+
+// parseSetting reads an optional non-negative number; empty means zero.
+func parseSetting(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a non-negative number", s)
+	}
+	return n, nil
+}
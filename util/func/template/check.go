@@ -0,0 +1,107 @@
+package template
+
+import (
+	"sort"
+	"strings"
+)
+
+// UnresolvedError lists the placeholders still present after rendering.
+type UnresolvedError struct {
+	Undefined []string
+	Circular  []string
+	Failed    []string
+}
+
+func (e *UnresolvedError) Error() string {
+	parts := make([]string, 0, 3)
+	if len(e.Undefined) > 0 {
+		parts = append(parts, "undefined variables: "+strings.Join(e.Undefined, ", "))
+	}
+	if len(e.Circular) > 0 {
+		parts = append(parts, "circular variable references: "+strings.Join(e.Circular, ", "))
+	}
+	if len(e.Failed) > 0 {
+		parts = append(parts, "failed template functions: "+strings.Join(e.Failed, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Names returns every unresolved placeholder, sorted.
+func (e *UnresolvedError) Names() []string {
+	names := make([]string, 0, len(e.Undefined)+len(e.Circular)+len(e.Failed))
+	names = append(names, e.Undefined...)
+	names = append(names, e.Circular...)
+	names = append(names, e.Failed...)
+	sort.Strings(names)
+	return names
+}
+
+// Check inspects already rendered text for placeholders that Render left behind. It returns
+// an *UnresolvedError classifying them against vars, or nil when everything was resolved.
+func Check(vars map[string]string, rendered ...string) error {
+	seen := make(map[string]bool)
+	err := &UnresolvedError{}
+
+	for _, text := range rendered {
+		for _, name := range Unresolved(text) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			if strings.HasPrefix(name, "$") {
+				err.Failed = append(err.Failed, name)
+				continue
+			}
+			if _, ok := vars[name]; ok {
+				err.Circular = append(err.Circular, name)
+				continue
+			}
+			err.Undefined = append(err.Undefined, name)
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+	sort.Strings(err.Undefined)
+	sort.Strings(err.Circular)
+	sort.Strings(err.Failed)
+	return err
+}
+
+// Unresolved returns the innermost placeholder expressions found in text, in order of appearance.
+func Unresolved(text string) []string {
+	names := make([]string, 0)
+	if !strings.Contains(text, openDelim) {
+		return names
+	}
+
+	i := 0
+	for i < len(text) {
+		start := strings.Index(text[i:], openDelim)
+		if start < 0 {
+			break
+		}
+		start += i
+
+		end := matchingClose(text, start)
+		if end < 0 {
+			break
+		}
+
+		inner := text[start+len(openDelim) : end]
+		if strings.Contains(inner, openDelim) {
+			names = append(names, Unresolved(inner)...)
+		}
+		if !strings.Contains(inner, openDelim) {
+			if name := strings.TrimSpace(inner); name != "" {
+				names = append(names, name)
+			}
+		}
+
+		i = end + len(closeDelim)
+	}
+
+	return names
+}
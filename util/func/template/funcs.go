@@ -0,0 +1,305 @@
+package template
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type function func(args []string) (string, error)
+
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"uuid":         fnUUID,
+		"guid":         fnUUID,
+		"timestamp":    fnTimestamp,
+		"timestampMs":  fnTimestampMs,
+		"isoTimestamp": fnISOTimestamp,
+		"date":         fnDate,
+		"randomInt":    fnRandomInt,
+		"randomString": fnRandomString,
+		"randomEmail":  fnRandomEmail,
+		"base64":       fnBase64,
+		"base64Encode": fnBase64,
+		"base64Decode": fnBase64Decode,
+		"urlEncode":    fnURLEncode,
+		"urlDecode":    fnURLDecode,
+		"sha256":       fnSHA256,
+		"hmacSha256":   fnHMACSHA256,
+		"processEnv":   fnProcessEnv,
+	}
+}
+
+// Call runs the built-in template function name with args, as used by {{$name args}}.
+func Call(name string, args []string) (string, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return "", fmt.Errorf("unknown template function $%s", name)
+	}
+	return fn(args)
+}
+
+// Has reports whether name is a built-in template function.
+func Has(name string) bool {
+	_, ok := functions[name]
+	return ok
+}
+
+// Names lists the built-in template functions in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func joined(args []string) string {
+	return strings.Join(args, " ")
+}
+
+func fnUUID(args []string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+func offsetNow(args []string) (time.Time, error) {
+	now := time.Now()
+	if len(args) == 0 {
+		return now, nil
+	}
+	offset, err := ParseOffset(args[0])
+	if err != nil {
+		return now, err
+	}
+	return now.Add(offset), nil
+}
+
+func fnTimestamp(args []string) (string, error) {
+	t, err := offsetNow(args)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(t.Unix(), 10), nil
+}
+
+func fnTimestampMs(args []string) (string, error) {
+	t, err := offsetNow(args)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10), nil
+}
+
+func fnISOTimestamp(args []string) (string, error) {
+	t, err := offsetNow(args)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// fnDate formats the current time: {{$date}}, {{$date 2006-01-02T15:04}}, {{$date 2006-01-02 -1w}}, {{$date +3d}}.
+func fnDate(args []string) (string, error) {
+	layout := "2006-01-02"
+	rest := args
+	if len(rest) > 0 && !isSignedOffset(rest[0]) {
+		layout = rest[0]
+		rest = rest[1:]
+	}
+	t, err := offsetNow(rest)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(layout), nil
+}
+
+func isSignedOffset(s string) bool {
+	if !strings.HasPrefix(s, "+") && !strings.HasPrefix(s, "-") {
+		return false
+	}
+	_, err := ParseOffset(s)
+	return err == nil
+}
+
+// ParseOffset parses date arithmetic offsets such as +1d, -2h, +30m, 1w or any time.ParseDuration value.
+func ParseOffset(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty offset")
+	}
+
+	sign := time.Duration(1)
+	body := s
+	if body[0] == '+' || body[0] == '-' {
+		if body[0] == '-' {
+			sign = -1
+		}
+		body = body[1:]
+	}
+
+	if len(body) > 1 {
+		unit := body[len(body)-1]
+		n, err := strconv.Atoi(body[:len(body)-1])
+		if err == nil {
+			switch unit {
+			case 'd':
+				return sign * time.Duration(n) * 24 * time.Hour, nil
+			case 'w':
+				return sign * time.Duration(n) * 7 * 24 * time.Hour, nil
+			case 'y':
+				return sign * time.Duration(n) * 365 * 24 * time.Hour, nil
+			}
+		}
+	}
+
+	d, err := time.ParseDuration(body)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q", s)
+	}
+	return sign * d, nil
+}
+
+func randomInRange(lo, hi int64) (int64, error) {
+	if hi < lo {
+		return 0, errors.New("min must not exceed max")
+	}
+	// The span overflows int64 when the range covers more than half of it.
+	span := hi - lo + 1
+	if span <= 0 {
+		return 0, errors.New("range between min and max is too large")
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(span))
+	if err != nil {
+		return 0, err
+	}
+	return lo + n.Int64(), nil
+}
+
+func fnRandomInt(args []string) (string, error) {
+	lo, hi := int64(0), int64(1000)
+	if len(args) == 2 {
+		var err error
+		if lo, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+			return "", errors.New("min must be an integer")
+		}
+		if hi, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return "", errors.New("max must be an integer")
+		}
+	}
+	if len(args) != 0 && len(args) != 2 {
+		return "", errors.New("usage: $randomInt [min max]")
+	}
+	n, err := randomInRange(lo, hi)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+const randomAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+func randomString(length int) (string, error) {
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(randomAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = randomAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+func fnRandomString(args []string) (string, error) {
+	length := 16
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 || n > 4096 {
+			return "", errors.New("length must be between 1 and 4096")
+		}
+		length = n
+	}
+	return randomString(length)
+}
+
+func fnRandomEmail(args []string) (string, error) {
+	domain := "example.com"
+	if len(args) > 0 {
+		domain = args[0]
+	}
+	local, err := randomString(10)
+	if err != nil {
+		return "", err
+	}
+	return "user_" + local + "@" + domain, nil
+}
+
+func fnBase64(args []string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(joined(args))), nil
+}
+
+func fnBase64Decode(args []string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(joined(args))
+	if err != nil {
+		return "", errors.New("invalid base64")
+	}
+	return string(data), nil
+}
+
+func fnURLEncode(args []string) (string, error) {
+	return url.QueryEscape(joined(args)), nil
+}
+
+func fnURLDecode(args []string) (string, error) {
+	decoded, err := url.QueryUnescape(joined(args))
+	if err != nil {
+		return "", errors.New("invalid URL encoding")
+	}
+	return decoded, nil
+}
+
+func fnSHA256(args []string) (string, error) {
+	sum := sha256.Sum256([]byte(joined(args)))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func fnHMACSHA256(args []string) (string, error) {
+	if len(args) < 2 {
+		return "", errors.New("usage: $hmacSha256 key message")
+	}
+	mac := hmac.New(sha256.New, []byte(args[0]))
+	mac.Write([]byte(joined(args[1:])))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func fnProcessEnv(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("usage: $processEnv NAME")
+	}
+	value, ok := os.LookupEnv(args[0])
+	if !ok {
+		return "", fmt.Errorf("process environment variable %s is not set", args[0])
+	}
+	return value, nil
+}
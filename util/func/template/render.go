@@ -0,0 +1,188 @@
+package template
+
+import (
+	"strings"
+)
+
+const (
+	openDelim  = "{{"
+	closeDelim = "}}"
+)
+
+// maxDepth bounds how many variables may reference each other in a chain.
+const maxDepth = 32
+
+// Render replaces {{name}} placeholders with values from vars and {{$fn args}} placeholders with
+// the output of the named built-in function. Placeholders may nest, e.g. {{$base64 {{user}}:{{pass}}}};
+// inner ones are resolved first. Variable values are rendered recursively, so {{base}} may itself
+// contain {{host}}, but only for variable references: a value can come from a response or a data
+// file, so a {{$fn}} inside one is kept as text and never called. Anything that cannot be
+// resolved, including circular references, is left in place verbatim; use Check to report it.
+func Render(input string, vars map[string]string) string {
+	if !strings.Contains(input, openDelim) {
+		return input
+	}
+	r := &renderer{vars: vars, resolving: make(map[string]bool), calls: true}
+	return r.render(input)
+}
+
+type renderer struct {
+	vars      map[string]string
+	resolving map[string]bool
+	depth     int
+	// calls is false while a variable value is rendered, so only the template text runs functions.
+	calls bool
+}
+
+func (r *renderer) render(input string) string {
+	var b strings.Builder
+	b.Grow(len(input))
+
+	i := 0
+	for i < len(input) {
+		start := strings.Index(input[i:], openDelim)
+		if start < 0 {
+			b.WriteString(input[i:])
+			break
+		}
+		start += i
+		b.WriteString(input[i:start])
+
+		end := matchingClose(input, start)
+		if end < 0 {
+			b.WriteString(input[start:])
+			break
+		}
+
+		inner := r.render(input[start+len(openDelim) : end])
+		value, ok := r.evaluate(inner)
+		if ok {
+			b.WriteString(value)
+		}
+		if !ok {
+			b.WriteString(openDelim + inner + closeDelim)
+		}
+
+		i = end + len(closeDelim)
+	}
+
+	return b.String()
+}
+
+// matchingClose returns the index of the "}}" that closes the "{{" at start, honouring nesting.
+func matchingClose(input string, start int) int {
+	depth := 0
+	i := start
+	for i < len(input)-1 {
+		if input[i] == '{' && input[i+1] == '{' {
+			depth++
+			i += 2
+			continue
+		}
+		if input[i] == '}' && input[i+1] == '}' {
+			depth--
+			if depth == 0 {
+				return i
+			}
+			i += 2
+			continue
+		}
+		i++
+	}
+	return -1
+}
+
+func (r *renderer) evaluate(expr string) (string, bool) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return "", false
+	}
+
+	// An inner placeholder that could not be resolved keeps the outer one unresolved too,
+	// so functions never run on half-rendered input.
+	if strings.Contains(expr, openDelim) {
+		return "", false
+	}
+
+	if strings.HasPrefix(expr, "$") {
+		if !r.calls {
+			return "", false
+		}
+		name, args := splitCall(expr[1:])
+		value, err := Call(name, args)
+		if err != nil {
+			return "", false
+		}
+		return value, true
+	}
+
+	value, ok := r.vars[expr]
+	if !ok {
+		return "", false
+	}
+	if !strings.Contains(value, openDelim) {
+		return value, true
+	}
+	if r.resolving[expr] || r.depth >= maxDepth {
+		return "", false
+	}
+
+	r.resolving[expr] = true
+	r.depth++
+	calls := r.calls
+	r.calls = false
+	resolved := r.render(value)
+	r.calls = calls
+	r.depth--
+	delete(r.resolving, expr)
+
+	return resolved, true
+}
+
+func splitCall(expr string) (string, []string) {
+	fields := splitArgs(expr)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// splitArgs splits on whitespace, keeping "double" or 'single' quoted segments together.
+func splitArgs(s string) []string {
+	args := make([]string, 0, 4)
+	var current strings.Builder
+	var quote byte
+	inArg := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+				continue
+			}
+			current.WriteByte(c)
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			inArg = true
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\n' {
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+			continue
+		}
+		current.WriteByte(c)
+		inArg = true
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
@@ -0,0 +1,28 @@
+package template
+
+import "testing"
+
+func TestRenderKeepsFunctionsInVariableValuesLiteral(t *testing.T) {
+	t.Setenv("RACO_TEST_SECRET", "s3cret")
+	// token stands for a value extracted from a response.
+	vars := map[string]string{
+		"token": "{{$processEnv RACO_TEST_SECRET}}",
+		"host":  "api.example.org",
+		"base":  "https://{{host}}",
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Bearer {{token}}", "Bearer {{$processEnv RACO_TEST_SECRET}}"},
+		{"{{$base64 {{token}}}}", "{{$base64 {{$processEnv RACO_TEST_SECRET}}}}"},
+		{"{{base}}/v1", "https://api.example.org/v1"},
+		{"{{$processEnv RACO_TEST_SECRET}}", "s3cret"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.input, vars); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// minSecretLength keeps very short secrets from masking unrelated text.
+const minSecretLength = 4
+
+var secretValues struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+// RegisterSecret records a decrypted secret so RedactSecrets masks it wherever it appears. Its
+// JSON- and HTML-escaped forms are recorded too, so rendered reports are covered as well.
+func RegisterSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	variants := []string{value, html.EscapeString(value), jsonEscape(value, true), jsonEscape(value, false)}
+
+	secretValues.mu.Lock()
+	defer secretValues.mu.Unlock()
+	for _, variant := range variants {
+		if !containsString(secretValues.values, variant) {
+			secretValues.values = append(secretValues.values, variant)
+		}
+	}
+	// Longest first, so a secret containing another one is masked as a whole.
+	sort.Slice(secretValues.values, func(i, j int) bool {
+		return len(secretValues.values[i]) > len(secretValues.values[j])
+	})
+}
+
+// jsonEscape returns value as it appears inside a JSON string literal.
+func jsonEscape(value string, escapeHTML bool) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(escapeHTML)
+	if err := encoder.Encode(value); err != nil {
+		return value
+	}
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+	return quoted[1 : len(quoted)-1]
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSecrets replaces every registered secret in s with [REDACTED].
+func RedactSecrets(s string) string {
+	if s == "" {
+		return s
+	}
+	secretValues.mu.RLock()
+	defer secretValues.mu.RUnlock()
+	for _, v := range secretValues.values {
+		s = strings.ReplaceAll(s, v, "[REDACTED]")
+	}
+	return s
+}
+
+// RedactSecretsInMap applies RedactSecrets to every value of m and returns a new map.
+func RedactSecretsInMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = RedactSecrets(v)
+	}
+	return out
+}
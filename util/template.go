@@ -0,0 +1,37 @@
+package util
+
+import (
+	"raco/util/func/template"
+)
+
+func RenderTemplate(input string, vars map[string]string) string {
+	return template.Render(input, vars)
+}
+
+func TemplateFunctions() []string {
+	return template.Names()
+}
+
+// CheckTemplate reports placeholders left in rendered text as a *template.UnresolvedError.
+func CheckTemplate(vars map[string]string, rendered ...string) error {
+	return template.Check(vars, rendered...)
+}
+
+// UndefinedVariables renders input against vars and returns the placeholders that stay unresolved.
+func UndefinedVariables(input string, vars map[string]string) []string {
+	err := template.Check(vars, template.Render(input, vars))
+	if unresolved, ok := err.(*template.UnresolvedError); ok {
+		return unresolved.Names()
+	}
+	return nil
+}
+
+// Placeholders returns the innermost {{...}} expressions in text, in order of appearance.
+func Placeholders(text string) []string {
+	return template.Unresolved(text)
+}
+
+// IsTemplateFunction reports whether name is a built-in {{$name}} function.
+func IsTemplateFunction(name string) bool {
+	return template.Has(name)
+}
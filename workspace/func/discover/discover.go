@@ -0,0 +1,104 @@
+// Package discover finds the raco store to use for the current directory.
+package discover
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DirName is the store directory, both in the home directory and in a project.
+	DirName = ".raco"
+	// EnvHome overrides discovery with an explicit store directory.
+	EnvHome = "RACO_HOME"
+)
+
+// Source says how a store was found.
+type Source string
+
+const (
+	SourceFlag    Source = "--workspace"
+	SourceEnv     Source = "$" + EnvHome
+	SourceProject Source = "project"
+	SourceHome    Source = "home"
+)
+
+// Find returns the store for dir: explicit when set, else $RACO_HOME, else the nearest .raco
+// directory in dir or one of its parents, else ~/.raco.
+func Find(explicit string, dir string) (string, Source, error) {
+	if explicit != "" {
+		path, err := storePath(explicit)
+		return path, SourceFlag, err
+	}
+	if env := os.Getenv(EnvHome); env != "" {
+		path, err := storePath(env)
+		return path, SourceEnv, err
+	}
+
+	home, err := Home()
+	if err != nil {
+		return "", "", err
+	}
+
+	if dir != "" {
+		if found := Nearest(dir); found != "" && !samePath(found, home) {
+			return found, SourceProject, nil
+		}
+	}
+	return home, SourceHome, nil
+}
+
+// Home is the global store, ~/.raco.
+func Home() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, DirName), nil
+}
+
+// Nearest returns the .raco directory in dir or its closest parent that has one, or "".
+func Nearest(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, DirName)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// storePath accepts either a store directory or a project directory that contains .raco.
+func storePath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("empty workspace path")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if filepath.Base(abs) != DirName {
+		if info, err := os.Stat(filepath.Join(abs, DirName)); err == nil && info.IsDir() {
+			return filepath.Join(abs, DirName), nil
+		}
+	}
+	return abs, nil
+}
+
+func samePath(a, b string) bool {
+	if a == b {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,52 @@
+// Package scaffold creates a project-local raco store.
+package scaffold
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// GitIgnore keeps personal and sensitive files of a project store out of version control.
+const GitIgnore = `# Secret key and files holding personal or recorded data
+secret.key
+secret.key.*
+environments/*.local.yaml
+history/
+metrics/
+backups/
+stats.json
+*.tmp
+*.lock
+`
+
+// Init creates storePath with empty collections and environments directories and a .gitignore.
+// Existing files are kept; created lists the paths that were added.
+func Init(storePath string) (created []string, err error) {
+	for _, dir := range []string{storePath, filepath.Join(storePath, "collections"), filepath.Join(storePath, "environments")} {
+		if _, err := os.Stat(dir); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return created, err
+		}
+		created = append(created, dir)
+	}
+
+	path := filepath.Join(storePath, ".gitignore")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return created, nil
+		}
+		return created, err
+	}
+	if _, err := file.WriteString(GitIgnore); err != nil {
+		file.Close()
+		return created, err
+	}
+	if err := file.Close(); err != nil {
+		return created, err
+	}
+	return append(created, path), nil
+}
@@ -0,0 +1,80 @@
+// Package workspace decides which store raco works in: an explicit --workspace or $RACO_HOME, a
+// project-local .raco directory found by walking up from the working directory, or ~/.raco.
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"raco/workspace/func/discover"
+	"raco/workspace/func/scaffold"
+	"strings"
+)
+
+const (
+	DirName = discover.DirName
+	EnvHome = discover.EnvHome
+	// Flag is the global option that selects a workspace explicitly.
+	Flag = "--workspace"
+)
+
+type Source = discover.Source
+
+const (
+	SourceFlag    = discover.SourceFlag
+	SourceEnv     = discover.SourceEnv
+	SourceProject = discover.SourceProject
+	SourceHome    = discover.SourceHome
+)
+
+// Workspace is the store raco uses. Global is the home store, which a project workspace is
+// shown alongside in the TUI; it equals Path when no project store is in use.
+type Workspace struct {
+	Path   string
+	Global string
+	Source Source
+}
+
+// IsProject reports whether the workspace is separate from the home store.
+func (w Workspace) IsProject() bool {
+	return w.Global != "" && w.Path != w.Global
+}
+
+// Discover finds the workspace for the working directory; explicit, from --workspace, wins.
+func Discover(explicit string) (Workspace, error) {
+	cwd, _ := os.Getwd()
+	path, source, err := discover.Find(explicit, cwd)
+	if err != nil {
+		return Workspace{}, err
+	}
+	global, err := discover.Home()
+	if err != nil {
+		global = path
+	}
+	return Workspace{Path: path, Global: global, Source: source}, nil
+}
+
+// TakeFlag removes a leading "--workspace <path>" or "--workspace=<path>" from args and returns
+// its value and the remaining arguments.
+func TakeFlag(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "", args
+	}
+	if value, ok := strings.CutPrefix(args[0], Flag+"="); ok {
+		return value, args[1:]
+	}
+	if args[0] == Flag && len(args) > 1 {
+		return args[1], args[2:]
+	}
+	return "", args
+}
+
+// Init scaffolds a project store in dir/.raco and returns its path and the paths it created.
+func Init(dir string) (string, []string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	path := filepath.Join(abs, DirName)
+	created, err := scaffold.Init(path)
+	return path, created, err
+}